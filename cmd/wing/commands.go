@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"wing/internal/git"
+)
+
+// errUsage reports bad arguments; the flag set has already printed why.
+var errUsage = errors.New("usage")
+
+type statusFile struct {
+	Path       string `json:"path"`
+	Status     string `json:"status"`
+	OrigPath   string `json:"orig_path,omitempty"`
+	Index      string `json:"index,omitempty"`
+	Worktree   string `json:"worktree,omitempty"`
+	Submodule  bool   `json:"submodule,omitempty"`
+	Conflicted bool   `json:"conflicted,omitempty"`
+	Ignored    bool   `json:"ignored,omitempty"`
+}
+
+type statusBranch struct {
+	Head     string `json:"head"`
+	OID      string `json:"oid,omitempty"`
+	Upstream string `json:"upstream,omitempty"`
+	Ahead    int    `json:"ahead"`
+	Behind   int    `json:"behind"`
+	Detached bool   `json:"detached,omitempty"`
+}
+
+type statusOutput struct {
+	Branch *statusBranch `json:"branch,omitempty"`
+	Base   string        `json:"base,omitempty"`
+	Files  []statusFile  `json:"files"`
+}
+
+type diffHunk struct {
+	OldStart int      `json:"old_start"`
+	OldLines int      `json:"old_lines"`
+	NewStart int      `json:"new_start"`
+	NewLines int      `json:"new_lines"`
+	Section  string   `json:"section,omitempty"`
+	Lines    []string `json:"lines"`
+}
+
+type diffOutput struct {
+	Path     string     `json:"path"`
+	Status   string     `json:"status,omitempty"`
+	OrigPath string     `json:"orig_path,omitempty"`
+	Base     string     `json:"base,omitempty"`
+	Header   []string   `json:"header"`
+	Hunks    []diffHunk `json:"hunks"`
+	Diff     string     `json:"diff"`
+}
+
+// subcommands are the headless commands; each writes its result to stdout.
+var subcommands = map[string]func(args []string, stdout io.Writer) error{
+	"status": runStatus,
+	"diff":   runDiff,
+	"files":  runFiles,
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "Usage: wing [flags]")
+	fmt.Fprintln(out, "       wing status [--json] [-base REV]")
+	fmt.Fprintln(out, "       wing diff <path> [--json] [-base REV]")
+	fmt.Fprintln(out, "       wing files [--json] [--ignored]")
+	fmt.Fprintln(out, "       wing mcp")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Flags:")
+	flag.PrintDefaults()
+}
+
+// parseArgs parses flags that may come before or after positional
+// arguments and returns the positionals.
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	positionals := []string{}
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, errUsage
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positionals, nil
+		}
+		if args[0] == "--" {
+			return append(positionals, args[1:]...), nil
+		}
+		positionals = append(positionals, args[0])
+		args = args[1:]
+	}
+}
+
+func writeJSON(stdout io.Writer, value any) error {
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func runStatus(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("status", flag.ContinueOnError)
+	repoPath := flags.String("repo", ".", "path to the git repo")
+	base := flags.String("base", "", "compare base: a ref such as HEAD or main, or a range A..B")
+	asJSON := flags.Bool("json", false, "print JSON")
+	if _, err := parseArgs(flags, args); err != nil {
+		return err
+	}
+
+	statuses, branch, err := git.StatusWithBranch(*repoPath)
+	if err != nil {
+		return err
+	}
+	output := statusOutput{Base: *base}
+	if *base == "" {
+		output.Branch = &statusBranch{
+			Head:     branch.Head,
+			OID:      branch.OID,
+			Upstream: branch.Upstream,
+			Ahead:    branch.Ahead,
+			Behind:   branch.Behind,
+			Detached: branch.Detached,
+		}
+	} else {
+		if err := git.VerifyBase(*repoPath, *base); err != nil {
+			return err
+		}
+		if statuses, err = git.StatusAgainst(*repoPath, *base); err != nil {
+			return err
+		}
+	}
+	output.Files = make([]statusFile, 0, len(statuses))
+	for _, entry := range statuses {
+		output.Files = append(output.Files, toStatusFile(entry))
+	}
+
+	if *asJSON {
+		return writeJSON(stdout, output)
+	}
+	if output.Branch != nil {
+		fmt.Fprintf(stdout, "## %s\n", branchLine(*output.Branch))
+	}
+	for _, file := range output.Files {
+		fmt.Fprintf(stdout, "%-2s %s\n", file.Status, fileLabel(file))
+	}
+	return nil
+}
+
+func runDiff(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	repoPath := flags.String("repo", ".", "path to the git repo")
+	base := flags.String("base", "", "compare base: a ref such as HEAD or main, or a range A..B")
+	asJSON := flags.Bool("json", false, "print JSON")
+	paths, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(paths) != 1 {
+		fmt.Fprintln(flags.Output(), "wing diff takes exactly one path")
+		return errUsage
+	}
+	path := paths[0]
+
+	var statuses []git.StatusEntry
+	if *base == "" {
+		statuses, err = git.Status(*repoPath)
+	} else if err = git.VerifyBase(*repoPath, *base); err == nil {
+		statuses, err = git.StatusAgainst(*repoPath, *base)
+	}
+	if err != nil {
+		return err
+	}
+	entry := git.StatusEntry{Path: path}
+	for _, candidate := range statuses {
+		if candidate.Path == path {
+			entry = candidate
+			break
+		}
+	}
+	diff, err := git.Diff(*repoPath, entry, git.DiffOptions{Base: *base, Context: git.DefaultContext})
+	if err != nil {
+		return err
+	}
+
+	if !*asJSON {
+		_, err := io.WriteString(stdout, diff)
+		return err
+	}
+	parsed := git.ParseDiff(diff)
+	output := diffOutput{
+		Path:     path,
+		Status:   entry.Status,
+		OrigPath: entry.OrigPath,
+		Base:     *base,
+		Header:   parsed.Header,
+		Hunks:    make([]diffHunk, 0, len(parsed.Hunks)),
+		Diff:     diff,
+	}
+	if output.Header == nil {
+		output.Header = []string{}
+	}
+	for _, hunk := range parsed.Hunks {
+		output.Hunks = append(output.Hunks, diffHunk{
+			OldStart: hunk.OldStart,
+			OldLines: hunk.OldLines,
+			NewStart: hunk.NewStart,
+			NewLines: hunk.NewLines,
+			Section:  hunk.Section,
+			Lines:    hunk.Lines,
+		})
+	}
+	return writeJSON(stdout, output)
+}
+
+func runFiles(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("files", flag.ContinueOnError)
+	repoPath := flags.String("repo", ".", "path to the git repo")
+	ignored := flags.Bool("ignored", false, "include ignored files")
+	asJSON := flags.Bool("json", false, "print JSON")
+	if _, err := parseArgs(flags, args); err != nil {
+		return err
+	}
+
+	files, err := git.ListFiles(*repoPath, *ignored)
+	if err != nil {
+		return err
+	}
+	statuses, err := git.Status(*repoPath)
+	if err != nil {
+		return err
+	}
+	byPath := make(map[string]git.StatusEntry, len(statuses))
+	for _, entry := range statuses {
+		byPath[entry.Path] = entry
+	}
+	output := make([]statusFile, 0, len(files))
+	for _, entry := range files {
+		if status, ok := byPath[entry.Path]; ok && !entry.Ignored {
+			entry = status
+		}
+		output = append(output, toStatusFile(entry))
+	}
+
+	if *asJSON {
+		return writeJSON(stdout, output)
+	}
+	for _, file := range output {
+		fmt.Fprintf(stdout, "%-2s %s\n", file.Status, fileLabel(file))
+	}
+	return nil
+}
+
+func toStatusFile(entry git.StatusEntry) statusFile {
+	file := statusFile{
+		Path:       entry.Path,
+		Status:     entry.Status,
+		OrigPath:   entry.OrigPath,
+		Submodule:  entry.IsSubmodule(),
+		Conflicted: entry.Conflicted,
+		Ignored:    entry.Ignored,
+	}
+	if entry.Index != 0 {
+		file.Index = string(entry.Index)
+	}
+	if entry.Worktree != 0 {
+		file.Worktree = string(entry.Worktree)
+	}
+	return file
+}
+
+func fileLabel(file statusFile) string {
+	if file.OrigPath != "" {
+		return file.OrigPath + " -> " + file.Path
+	}
+	return file.Path
+}
+
+func branchLine(branch statusBranch) string {
+	line := branch.Head
+	if branch.Upstream != "" {
+		line += "..." + branch.Upstream
+	}
+	counts := []string{}
+	if branch.Ahead > 0 {
+		counts = append(counts, fmt.Sprintf("ahead %d", branch.Ahead))
+	}
+	if branch.Behind > 0 {
+		counts = append(counts, fmt.Sprintf("behind %d", branch.Behind))
+	}
+	if len(counts) > 0 {
+		line += " [" + strings.Join(counts, ", ") + "]"
+	}
+	return line
+}
+
+// runSubcommand runs a headless subcommand and returns the exit code.
+func runSubcommand(run func([]string, io.Writer) error, args []string) int {
+	if err := run(args, os.Stdout); err != nil {
+		if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+			return 2
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runGit(t *testing.T, repo string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=wing", "-c", "user.email=wing@example.com"}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v (%s)", args, err, out)
+	}
+}
+
+func writeFile(t *testing.T, repo, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(repo, path), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testRepo(t *testing.T) string {
+	t.Helper()
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-b", "main")
+	writeFile(t, repo, "a.txt", "one\n")
+	writeFile(t, repo, ".gitignore", "*.log\n")
+	runGit(t, repo, "add", ".")
+	runGit(t, repo, "commit", "-m", "init")
+	writeFile(t, repo, "a.txt", "one\ntwo\n")
+	writeFile(t, repo, "new.txt", "new\n")
+	writeFile(t, repo, "debug.log", "noise\n")
+	return repo
+}
+
+func TestStatusJSON(t *testing.T) {
+	repo := testRepo(t)
+	var out bytes.Buffer
+	if err := runStatus([]string{"-repo", repo, "--json"}, &out); err != nil {
+		t.Fatalf("status error: %v", err)
+	}
+	var status statusOutput
+	if err := json.Unmarshal(out.Bytes(), &status); err != nil {
+		t.Fatalf("decode: %v (%s)", err, out.String())
+	}
+	if status.Branch == nil || status.Branch.Head != "main" {
+		t.Fatalf("expected branch main, got %+v", status.Branch)
+	}
+	if len(status.Files) != 2 || status.Files[0].Path != "a.txt" || status.Files[0].Worktree != "M" || status.Files[1].Status != "??" {
+		t.Fatalf("unexpected files %+v", status.Files)
+	}
+
+	out.Reset()
+	if err := runStatus([]string{"-repo", repo}, &out); err != nil {
+		t.Fatalf("status error: %v", err)
+	}
+	if got := out.String(); got != "## main\nM  a.txt\n?? new.txt\n" {
+		t.Fatalf("unexpected text status %q", got)
+	}
+}
+
+func TestDiffJSONAcceptsFlagsAfterPath(t *testing.T) {
+	repo := testRepo(t)
+	var out bytes.Buffer
+	if err := runDiff([]string{"a.txt", "--json", "-repo", repo}, &out); err != nil {
+		t.Fatalf("diff error: %v", err)
+	}
+	var diff diffOutput
+	if err := json.Unmarshal(out.Bytes(), &diff); err != nil {
+		t.Fatalf("decode: %v (%s)", err, out.String())
+	}
+	if diff.Path != "a.txt" || len(diff.Hunks) != 1 || diff.Hunks[0].NewLines != 2 {
+		t.Fatalf("unexpected diff %+v", diff)
+	}
+	if got := strings.Join(diff.Hunks[0].Lines, "\n"); got != " one\n+two" {
+		t.Fatalf("unexpected hunk lines %q", got)
+	}
+	if err := runDiff([]string{"-repo", repo}, &out); err != errUsage {
+		t.Fatalf("expected usage error without a path, got %v", err)
+	}
+}
+
+func TestFilesIgnored(t *testing.T) {
+	repo := testRepo(t)
+	var out bytes.Buffer
+	if err := runFiles([]string{"-repo", repo}, &out); err != nil {
+		t.Fatalf("files error: %v", err)
+	}
+	if strings.Contains(out.String(), "debug.log") {
+		t.Fatalf("expected ignored files hidden, got %q", out.String())
+	}
+	out.Reset()
+	if err := runFiles([]string{"-repo", repo, "--ignored"}, &out); err != nil {
+		t.Fatalf("files error: %v", err)
+	}
+	want := "   .gitignore\nM  a.txt\n!! debug.log\n?? new.txt\n"
+	if got := out.String(); got != want {
+		t.Fatalf("unexpected files %q, want %q", got, want)
+	}
+}
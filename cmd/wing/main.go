@@ -9,14 +9,36 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 
 	"wing/internal/app"
+	"wing/internal/git"
+	"wing/internal/graphics"
+	"wing/internal/mcp"
+	"wing/internal/settings"
 )
 
 var version = "dev"
 
 func main() {
+	if len(os.Args) > 1 {
+		if os.Args[1] == "mcp" {
+			os.Exit(runMCP(os.Args[2:]))
+		}
+		if run, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(runSubcommand(run, os.Args[2:]))
+		}
+	}
+
+	flag.Usage = usage
+
 	repoPath := flag.String("repo", ".", "path to the git repo")
 	refresh := flag.Duration("refresh", 2*time.Second, "refresh interval")
 	theme := flag.String("theme", "default", "color theme (stub)")
+	base := flag.String("base", "", "compare base: a ref such as HEAD or main, or a range A..B")
+	checkpoints := flag.Bool("checkpoints", false, "snapshot the worktree to refs/wing/checkpoints after each burst of changes")
+	recent := flag.Duration("recent", 10*time.Second, "how long changed files and lines stay highlighted")
+	follow := flag.Bool("follow", false, "select the most recently changed file on every refresh")
+	agent := flag.String("agent", "", "command to run in an embedded agent pane, e.g. codex or $SHELL")
+	backendName := flag.String("backend", git.BackendExec, "git backend: exec runs the git binary, go-git reads the repo in-process")
+	graphicsName := flag.String("graphics", graphics.NameAuto, "how images are previewed: auto, kitty, sixel or blocks")
 	showVersion := flag.Bool("version", false, "print version")
 	flag.Parse()
 
@@ -25,10 +47,40 @@ func main() {
 		return
 	}
 
+	backend, err := git.NewBackend(*backendName, *repoPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	// Check the base before any refresh passes it to git.
+	if err := backend.VerifyBase(*base); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	protocol, err := graphics.Parse(*graphicsName, os.Getenv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	var prefs settings.Settings
+	if path, err := settings.DefaultPath(); err == nil {
+		if prefs, err = settings.Load(path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+
 	model := app.New(app.Config{
 		RepoPath:      *repoPath,
 		RefreshPeriod: *refresh,
 		Theme:         *theme,
+		Base:          *base,
+		Checkpoints:   *checkpoints,
+		RecentWindow:  *recent,
+		Follow:        *follow,
+		AgentCommand:  *agent,
+		Backend:       backend,
+		Graphics:      protocol,
+		Settings:      prefs,
 	})
 
 	program := tea.NewProgram(model, tea.WithAltScreen())
@@ -37,3 +89,17 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// runMCP serves the repository to coding agents over MCP on stdio.
+func runMCP(args []string) int {
+	flags := flag.NewFlagSet("mcp", flag.ExitOnError)
+	repoPath := flags.String("repo", ".", "path to the git repo")
+	flags.Parse(args)
+
+	server := mcp.Server{RepoPath: *repoPath, Version: version}
+	if err := server.Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
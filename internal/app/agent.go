@@ -0,0 +1,225 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"wing/internal/term"
+)
+
+// agentState is the command running in the agent pane. The session is kept
+// after the command exits so its last screen stays visible.
+type agentState struct {
+	session  *term.Session
+	starting bool
+	err      error
+}
+
+type agentStartedMsg struct {
+	session *term.Session
+	err     error
+}
+
+type agentOutputMsg struct {
+	session *term.Session
+}
+
+type agentExitMsg struct {
+	session *term.Session
+}
+
+func (m Model) agentVisible() bool {
+	return m.agent.session != nil
+}
+
+func (m Model) agentRunning() bool {
+	return m.agent.session != nil && !m.agent.session.Exited()
+}
+
+// agentCommand is the configured agent command, or the user's shell.
+func (m Model) agentCommand() string {
+	if command := strings.TrimSpace(m.config.AgentCommand); command != "" {
+		return command
+	}
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	return "sh"
+}
+
+// agentLayout splits the width between the files, diff and agent panes.
+func (m Model) agentLayout() (int, int, int) {
+	left := m.width / 4
+	if left < 24 {
+		left = 24
+	}
+	rest := m.width - left - 1
+	diff := rest / 2
+	agent := rest - diff - 2
+	if diff < 20 {
+		diff = 20
+	}
+	if agent < 20 {
+		agent = 20
+	}
+	return left, diff, agent
+}
+
+// agentSize is the terminal size the agent pane has room for.
+func (m Model) agentSize() (int, int) {
+	_, _, width := m.agentLayout()
+	return width - 2, contentHeight(m.paneHeight())
+}
+
+// startAgent launches the agent command unless it is already running.
+func (m *Model) startAgent() tea.Cmd {
+	if m.agent.starting || m.agentRunning() {
+		return nil
+	}
+	m.agent.starting = true
+	cols, rows := m.agentSize()
+	repoPath, command := m.config.RepoPath, m.agentCommand()
+	return func() tea.Msg {
+		session, err := term.Start(repoPath, command, cols, rows)
+		return agentStartedMsg{session: session, err: err}
+	}
+}
+
+func waitAgentCmd(session *term.Session) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case <-session.Updates():
+			return agentOutputMsg{session: session}
+		case <-session.Done():
+			return agentExitMsg{session: session}
+		}
+	}
+}
+
+func (m Model) handleAgentMsg(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case agentStartedMsg:
+		m.agent.starting = false
+		m.agent.err = msg.err
+		if msg.err != nil {
+			m.notice = fmt.Sprintf("Agent failed to start: %s", msg.err)
+			return m, nil
+		}
+		m.closeAgent()
+		m.agent.session = msg.session
+		m.focus = focusAgent
+		m.updateContentLines()
+		return m, waitAgentCmd(msg.session)
+	case agentOutputMsg:
+		if msg.session == m.agent.session {
+			return m, waitAgentCmd(msg.session)
+		}
+	case agentExitMsg:
+		if msg.session == m.agent.session {
+			if m.focus == focusAgent {
+				m.focus = focusFiles
+			}
+			m.notice = "Agent exited, ! to restart."
+			if err := msg.session.Err(); err != nil {
+				m.notice = fmt.Sprintf("Agent exited (%s), ! to restart.", err)
+			}
+		}
+	}
+	return m, nil
+}
+
+// resizeAgent keeps the agent terminal matched to its pane.
+func (m Model) resizeAgent() {
+	if m.agent.session != nil {
+		m.agent.session.Resize(m.agentSize())
+	}
+}
+
+func (m Model) closeAgent() {
+	if m.agent.session != nil {
+		m.agent.session.Close()
+	}
+}
+
+// handleAgentKey forwards keys to the agent; ctrl+] hands focus back.
+func (m Model) handleAgentKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "ctrl+]" || !m.agentRunning() {
+		m.focus = focusFiles
+		return m, nil
+	}
+	if input := keyBytes(msg); len(input) > 0 {
+		m.agent.session.Write(input)
+	}
+	return m, nil
+}
+
+// keyBytes translates a key press back into the bytes a terminal sends.
+func keyBytes(msg tea.KeyMsg) []byte {
+	var out []byte
+	switch msg.Type {
+	case tea.KeyRunes:
+		out = []byte(string(msg.Runes))
+	case tea.KeySpace:
+		out = []byte{' '}
+	case tea.KeyUp:
+		out = []byte("\x1b[A")
+	case tea.KeyDown:
+		out = []byte("\x1b[B")
+	case tea.KeyRight:
+		out = []byte("\x1b[C")
+	case tea.KeyLeft:
+		out = []byte("\x1b[D")
+	case tea.KeyHome:
+		out = []byte("\x1b[H")
+	case tea.KeyEnd:
+		out = []byte("\x1b[F")
+	case tea.KeyPgUp:
+		out = []byte("\x1b[5~")
+	case tea.KeyPgDown:
+		out = []byte("\x1b[6~")
+	case tea.KeyInsert:
+		out = []byte("\x1b[2~")
+	case tea.KeyDelete:
+		out = []byte("\x1b[3~")
+	case tea.KeyShiftTab:
+		out = []byte("\x1b[Z")
+	default:
+		if (msg.Type >= 0 && msg.Type < 32) || msg.Type == 127 {
+			out = []byte{byte(msg.Type)}
+		}
+	}
+	if msg.Alt && len(out) > 0 {
+		out = append([]byte{0x1b}, out...)
+	}
+	return out
+}
+
+func (m Model) renderAgent(width, height int) string {
+	borderColor := lipgloss.Color("240")
+	titleStyle := lipgloss.NewStyle().Bold(true)
+	if m.focus == focusAgent {
+		borderColor = lipgloss.Color("62")
+		titleStyle = titleStyle.Foreground(lipgloss.Color("62"))
+	}
+	style := lipgloss.NewStyle().
+		Width(width).
+		Height(height).
+		Padding(1, 1).
+		Border(lipgloss.NormalBorder()).
+		BorderForeground(borderColor)
+
+	titleLabel := "Agent: " + m.agentCommand()
+	if !m.agentRunning() {
+		titleLabel += " (exited)"
+	}
+	if limit := width - 2; len(titleLabel) > limit && limit > 1 {
+		titleLabel = titleLabel[:limit-1] + "…"
+	}
+	title := titleStyle.Render(titleLabel)
+	lines := m.agent.session.Lines(m.focus == focusAgent)
+	return style.Render(fmt.Sprintf("%s\n\n%s", title, strings.Join(lines, "\n")))
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestKeyBytes(t *testing.T) {
+	cases := []struct {
+		msg  tea.KeyMsg
+		want string
+	}{
+		{tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("hé")}, "hé"},
+		{tea.KeyMsg{Type: tea.KeyEnter}, "\r"},
+		{tea.KeyMsg{Type: tea.KeyTab}, "\t"},
+		{tea.KeyMsg{Type: tea.KeyBackspace}, "\x7f"},
+		{tea.KeyMsg{Type: tea.KeyCtrlC}, "\x03"},
+		{tea.KeyMsg{Type: tea.KeyUp}, "\x1b[A"},
+		{tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b"), Alt: true}, "\x1bb"},
+	}
+	for _, tc := range cases {
+		if got := string(keyBytes(tc.msg)); got != tc.want {
+			t.Fatalf("keyBytes(%v) = %q, want %q", tc.msg, got, tc.want)
+		}
+	}
+}
+
+func TestAgentLayoutLeavesRoomForDiff(t *testing.T) {
+	m := New(Config{})
+	m.width = 160
+	m.height = 40
+	left, diff, agent := m.agentLayout()
+	if left != 40 || diff < 50 || agent < 50 {
+		t.Fatalf("unexpected layout %d/%d/%d", left, diff, agent)
+	}
+	cols, rows := m.agentSize()
+	if cols != agent-2 || rows != contentHeight(m.paneHeight()) {
+		t.Fatalf("unexpected agent size %dx%d", cols, rows)
+	}
+}
+
+func TestAgentKeysReturnFocusWithoutSession(t *testing.T) {
+	m := New(Config{})
+	m.focus = focusAgent
+	next, _ := m.handleAgentKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	if next.(Model).focus != focusFiles {
+		t.Fatalf("expected focus back on files")
+	}
+	m.focus = focusDiff
+	m.toggleFocus()
+	if m.focus != focusFiles {
+		t.Fatalf("expected tab to skip the agent pane without a session")
+	}
+}
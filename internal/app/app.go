@@ -9,47 +9,115 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 
 	"wing/internal/git"
+	"wing/internal/graphics"
+	"wing/internal/review"
+	"wing/internal/settings"
 )
 
 type Config struct {
 	RepoPath      string
 	RefreshPeriod time.Duration
 	Theme         string
+	Base          string
+	// Checkpoints enables automatic worktree snapshots after each burst
+	// of changes.
+	Checkpoints bool
+	// RecentWindow is how long changed files and lines stay highlighted.
+	RecentWindow time.Duration
+	// Follow selects the most recently changed file on every refresh.
+	Follow bool
+	// AgentCommand is run in the agent pane; it starts with wing when set.
+	AgentCommand string
+	// Backend performs status, diff and commit operations; nil runs the
+	// git binary in RepoPath.
+	Backend git.Backend
+	// Graphics is how image previews are drawn.
+	Graphics graphics.Protocol
+	// Settings are the remembered preferences the view starts with; they
+	// are saved back when changed.
+	Settings settings.Settings
 }
 
 type Model struct {
 	config     Config
+	// tick schedules a timer message; tests replace tea.Tick with one that
+	// fires at once.
+	tick       func(time.Duration, func(time.Time) tea.Msg) tea.Cmd
 	width      int
 	height     int
 	files      []git.StatusEntry
 	rows       []fileRow
 	diff       string
 	diffLines  []string
+	context    diffContext
 	contentLines []string
 	err          error
 	selected   int
 	fileOffset int
 	diffOffset int
+	diffCursor int
+	lineRefs   []lineRef
 	focus      paneFocus
 	modal      modalState
 	modalErr   string
 	commitText textinput.Model
+	baseText   textinput.Model
+	filterText textinput.Model
+	diffOpts   git.DiffOptions
 	mode       viewMode
 	gitInfo    string
 	collapsed  map[string]bool
 	showIgnored bool
+	checkpoints checkpointState
+	review         review.Store
+	// reviewErr is why the review state failed to load; it is then kept
+	// in memory only, so the file is not overwritten.
+	reviewErr      error
+	pendingComment review.Comment
+	commentText    textinput.Model
+	notice         string
+	hashes         map[string]string
+	stats          map[string]git.DiffStat
+	modTimes       map[string]time.Time
+	hideViewed     bool
+	sortMode       sortMode
+	flat           bool
+	filter         fileFilter
+	// filterIgnored is set while showIgnored is on only because the
+	// filter asked for ignored files.
+	filterIgnored  bool
+	settings       settings.Settings
+	recent         recentState
+	agent          agentState
+	patch          patchState
+	patchText      textinput.Model
+	fileView       fileView
+	loads          *loadTracker
+	cache          *contentCache
+	tree           *fileTree
+	head           string
+	// baseID is the commits the base resolved to at the last refresh.
+	baseID         string
 }
 
 type fileRow struct {
 	Path      string
+	OrigPath  string
 	Name      string
 	Status    string
 	IsDir     bool
 	Collapsed bool
 	Ignored   bool
+	Viewed    bool
+	Recent    bool
+	IndexHash string
 	Depth     int
+	// Stat counts the changed lines of a file, or of every file below a
+	// folder.
+	Stat git.DiffStat
 }
 
 func New(config Config) Model {
@@ -57,17 +125,48 @@ func New(config Config) Model {
 	input.Placeholder = "Commit message"
 	input.CharLimit = 120
 	input.Width = 44
+	baseInput := textinput.New()
+	baseInput.Placeholder = "HEAD, main, origin/main, HEAD~3, A..B"
+	baseInput.CharLimit = 120
+	baseInput.Width = 44
+	commentInput := textinput.New()
+	commentInput.Placeholder = "Comment"
+	commentInput.CharLimit = 500
+	commentInput.Width = 60
+	filterInput := textinput.New()
+	filterInput.Placeholder = "untracked deleted *.go internal/*"
+	filterInput.CharLimit = 200
+	filterInput.Width = 60
+	patchInput := textinput.New()
+	patchInput.CharLimit = 500
+	patchInput.Width = 60
+	if config.Backend == nil {
+		config.Backend = git.ExecBackend{RepoPath: config.RepoPath}
+	}
 	return Model{
-		config:     config,
-		focus:      focusFiles,
-		commitText: input,
-		mode:       modeExplorer,
-		collapsed:  make(map[string]bool),
+		config:      config,
+		tick:        tea.Tick,
+		focus:       focusFiles,
+		commitText:  input,
+		baseText:    baseInput,
+		filterText:  filterInput,
+		commentText: commentInput,
+		patchText:   patchInput,
+		diffOpts:    git.DiffOptions{Base: strings.TrimSpace(config.Base), Context: defaultContext},
+		mode:        modeExplorer,
+		collapsed:   make(map[string]bool),
+		recent:      recentState{follow: config.Follow},
+		settings:    config.Settings,
+		sortMode:    parseSortMode(config.Settings.Sort),
+		flat:        config.Settings.Flat,
+		loads:       &loadTracker{},
+		cache:       newContentCache(defaultCacheBytes),
+		tree:        newFileTree(),
 	}
 }
 
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(m.refreshCmd(), m.tickCmd())
+	return tea.Batch(m.refreshCmd(), m.tickCmd(), m.loadReviewCmd())
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -75,31 +174,76 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
-		m.updateContentLines()
+		if m.fileView.preview != nil {
+			// Pictures are laid out for the pane width.
+			m.rebuildDiffLines()
+		} else {
+			m.updateContentLines()
+		}
+		m.resizeAgent()
+		if m.config.AgentCommand != "" && m.agent.session == nil && m.agent.err == nil {
+			return m, m.startAgent()
+		}
+	case agentStartedMsg, agentOutputMsg, agentExitMsg:
+		return m.handleAgentMsg(msg)
+	case patchExportMsg, patchLoadedMsg, patchAppliedMsg:
+		return m.handlePatchMsg(msg)
 	case refreshMsg:
+		if !m.loads.finishRefresh(msg.gen) {
+			return m, nil
+		}
+		// A file selected while the refresh was running has its own diff
+		// on the way; only the file list is still current.
+		diffCurrent := m.loads.currentDiff(msg.diffGen)
 		selectedKey := m.selectedKey()
+		now := time.Now()
+		latest := m.trackChanges(m.hashes, msg.hashes, msg.modTimes, now)
+		m.head = msg.head
+		m.baseID = msg.baseID
+		if diffCurrent && m.mode == modeDiff && msg.err == nil {
+			m.trackLines(msg.path, m.diff, msg.diff, now)
+		}
 		m.files = msg.files
-		if m.collapsed == nil {
-			m.collapsed = make(map[string]bool)
+		m.hashes = msg.hashes
+		m.stats = msg.stats
+		m.modTimes = msg.modTimes
+		m.rebuildRows()
+		if diffCurrent {
+			m.fileView.show(msg.path, msg.meta)
+			m.setDiff(msg.diff, msg.source)
+			m.err = msg.err
+		} else if msg.files == nil && msg.err != nil {
+			// The listing itself failed; the diff on the way cannot
+			// explain the empty file list.
+			m.err = msg.err
 		}
-		m.rows = buildRows(m.files, m.collapsed)
-		m.diff = msg.diff
-		m.diffLines = splitLines(msg.diff)
-		m.updateContentLines()
-		m.err = msg.err
 		m.gitInfo = msg.gitInfo
 		m.selected = indexForKey(m.rows, selectedKey)
 		m.fileOffset = clampOffset(m.fileOffset, len(m.rows), m.filesVisibleHeight())
 		m.ensureSelectionVisible()
+		var follow tea.Cmd
+		if m.recent.follow && m.mode == modeDiff {
+			follow = m.followFile(latest)
+		}
+		return m, tea.Batch(m.observeWorktree(msg.fingerprint), follow)
 	case diffMsg:
-		m.diff = msg.diff
-		m.diffLines = splitLines(msg.diff)
-		m.updateContentLines()
+		if !m.loads.currentDiff(msg.gen) {
+			return m, nil
+		}
+		if entry, ok := m.selectedEntry(); !ok || entry.Path != msg.path {
+			return m, nil
+		}
+		m.fileView.show(msg.path, msg.meta)
+		m.setDiff(msg.diff, msg.source)
 		m.err = msg.err
 	case tea.KeyMsg:
 		if m.modal != modalNone {
 			return m.handleModalKey(msg)
 		}
+		if m.focus == focusAgent {
+			return m.handleAgentKey(msg)
+		}
+		m.notice = ""
 		switch msg.String() {
 		case "tab", "shift+tab":
 			m.toggleFocus()
@@ -107,10 +251,15 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.focus == focusFiles {
 				if row, ok := m.selectedRow(); ok && row.IsDir {
 					m.toggleFolder(row.Path)
+				} else if ok {
+					m.toggleFileMark()
 				}
+			} else if m.focus == focusDiff && m.mode == modeDiff {
+				m.toggleHunkMark()
 			}
 		case "i":
 			m.showIgnored = !m.showIgnored
+			m.filterIgnored = false
 			return m, m.refreshCmd()
 		case "m":
 			m.toggleMode()
@@ -120,33 +269,122 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.moveSelection(-1)
 				return m, m.diffCmd()
 			}
-			m.scrollDiff(-1)
+			m.moveDiff(-1)
 		case "down", "j":
 			if m.focus == focusFiles {
 				m.moveSelection(1)
 				return m, m.diffCmd()
 			}
-			m.scrollDiff(1)
+			m.moveDiff(1)
 		case "pgup":
 			if m.focus == focusFiles {
 				m.moveSelection(-m.filesVisibleHeight())
 				return m, m.diffCmd()
 			}
-			m.scrollDiff(-m.diffVisibleHeight())
+			m.moveDiff(-m.diffVisibleHeight())
 		case "pgdown":
 			if m.focus == focusFiles {
 				m.moveSelection(m.filesVisibleHeight())
 				return m, m.diffCmd()
 			}
-			m.scrollDiff(m.diffVisibleHeight())
+			m.moveDiff(m.diffVisibleHeight())
 		case "enter":
 			m.openCommitModal()
+		case "b":
+			m.openBaseModal()
+		case "t":
+			return m, m.openTimelineModal()
+		case "c":
+			m.startComment(false)
+		case "C":
+			m.startComment(true)
+		case "x":
+			if m.mode == modeDiff && m.focus == focusDiff {
+				return m, m.deleteComments()
+			}
+		case "E":
+			return m, m.exportReviewCmd()
+		case "R":
+			return m, m.exportReportCmd()
+		case "P":
+			m.openPatchExportModal()
+		case "I":
+			m.openPatchImportModal()
+		case "v":
+			if m.focus == focusFiles {
+				return m, m.toggleViewed()
+			}
+		case "V":
+			m.toggleHideViewed()
+			return m, m.diffCmd()
+		case "F":
+			m.recent.follow = !m.recent.follow
+			if m.recent.follow {
+				m.notice = "Following changes."
+			} else {
+				m.notice = "Stopped following changes."
+			}
+			return m, m.refreshCmd()
+		case "w":
+			if m.mode == modeDiff {
+				m.cycleWhitespace()
+				return m, m.diffCmd()
+			}
+		case "a":
+			if m.mode == modeDiff {
+				m.cycleAlgorithm()
+				return m, m.diffCmd()
+			}
+		case "+", "=":
+			if m.mode == modeDiff {
+				m.adjustContext(1)
+				return m, m.diffCmd()
+			}
+		case "-":
+			if m.mode == modeDiff {
+				m.adjustContext(-1)
+				return m, m.diffCmd()
+			}
+		case "[":
+			if m.mode == modeDiff {
+				return m, m.expandHunk(expandStep, 0)
+			}
+		case "]":
+			if m.mode == modeDiff {
+				return m, m.expandHunk(0, expandStep)
+			}
+		case "f":
+			if m.mode == modeDiff {
+				return m, m.toggleFullFile()
+			}
+		case "L":
+			return m, m.loadMore()
+		case "X":
+			m.toggleHex()
+		case "s":
+			return m, m.cycleSort()
+		case "T":
+			return m, m.toggleFlat()
+		case "/":
+			m.openFilterModal()
 		case "h":
 			m.openHelpModal()
+		case "!":
+			if m.agentRunning() {
+				m.focus = focusAgent
+				return m, nil
+			}
+			return m, m.startAgent()
 	case "q", "esc", "ctrl+c":
+		m.closeAgent()
+		m.loads.cancel()
 		return m, tea.Quit
 	}
 	case tickMsg:
+		if m.loads.refreshing {
+			// Let a slow refresh finish rather than restarting it forever.
+			return m, m.tickCmd()
+		}
 		return m, tea.Batch(m.refreshCmd(), m.tickCmd())
 	case commitMsg:
 		if msg.err != nil {
@@ -164,6 +402,70 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.closeModal()
 			return m, m.refreshCmd()
 		}
+	case reviewMsg:
+		m.review = msg.store
+		m.reviewErr = msg.err
+		m.refreshRowsKeepingIndex()
+		if msg.err != nil {
+			m.notice = fmt.Sprintf("Review state: %s", msg.err)
+		}
+	case reviewSavedMsg:
+		if msg.err != nil {
+			m.notice = fmt.Sprintf("Saving review failed: %s", msg.err)
+		}
+	case settingsSavedMsg:
+		if msg.err != nil {
+			m.notice = fmt.Sprintf("Saving settings failed: %s", msg.err)
+		}
+	case exportMsg:
+		switch {
+		case msg.err != nil:
+			m.notice = fmt.Sprintf("Export failed: %s", msg.err)
+		case msg.copied:
+			m.notice = fmt.Sprintf("Review exported to %s and copied.", msg.path)
+		default:
+			m.notice = fmt.Sprintf("Review exported to %s.", msg.path)
+		}
+	case reportMsg:
+		if msg.err != nil {
+			m.notice = fmt.Sprintf("Report failed: %s", msg.err)
+		} else {
+			m.notice = fmt.Sprintf("Report written to %s.", msg.path)
+		}
+	case checkpointMsg:
+		m.checkpoints.busy = false
+		m.checkpoints.err = msg.err
+		if msg.created && m.modal == modalTimeline {
+			return m, m.checkpointsCmd()
+		}
+	case restoreMsg:
+		if msg.err != nil {
+			m.modal = modalRestore
+			m.modalErr = msg.err.Error()
+			return m, nil
+		}
+		m.closeModal()
+		m.context.reset()
+		return m, tea.Batch(m.refreshCmd(), m.checkpointsCmd())
+	case checkpointsMsg:
+		m.checkpoints.list = msg.list
+		m.checkpoints.err = msg.err
+		if m.checkpoints.selected > len(msg.list) {
+			m.checkpoints.selected = len(msg.list)
+		}
+		if m.checkpoints.mark > len(msg.list) {
+			m.checkpoints.mark = -1
+		}
+	case baseMsg:
+		if msg.err != nil {
+			m.modal = modalBase
+			m.modalErr = msg.err.Error()
+		} else {
+			m.diffOpts.Base = msg.base
+			m.diffOffset = 0
+			m.closeModal()
+			return m, m.refreshCmd()
+		}
 	}
 
 	return m, nil
@@ -184,6 +486,10 @@ func (m Model) View() string {
 	left := m.renderFiles(leftWidth, paneHeight)
 	right := m.renderDiff(rightWidth, paneHeight)
 	main := lipgloss.JoinHorizontal(lipgloss.Top, left, right)
+	if m.agentVisible() {
+		_, _, agentWidth := m.agentLayout()
+		main = lipgloss.JoinHorizontal(lipgloss.Top, main, m.renderAgent(agentWidth, paneHeight))
+	}
 
 	status := m.renderStatusBar()
 	return lipgloss.JoinVertical(lipgloss.Top, main, status)
@@ -204,25 +510,39 @@ func (m Model) renderFiles(width, height int) string {
 		BorderForeground(borderColor)
 
 	title := titleStyle.Render("Files")
-	items := make([]string, 0, len(m.rows)+1)
+	if label := m.layoutLabel(); label != "" {
+		title += lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(" · " + label)
+	}
+	if m.filter.active() {
+		title += lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Render(" · " + m.filter.text)
+	}
+	title = ansi.Truncate(title, width-2, "…")
+	var items []string
 	if len(m.rows) == 0 {
-		if m.mode == modeExplorer {
+		if m.filter.active() {
+			items = append(items, "No files match the filter.")
+		} else if m.mode == modeExplorer {
 			items = append(items, "No files found.")
 		} else {
 			items = append(items, "No changes detected.")
 		}
+		items = m.sliceLines(items, 0, m.filesVisibleHeight())
 	} else {
-		for i, row := range m.rows {
-			line := m.renderRow(row, i == m.selected)
-			items = append(items, line)
+		// Only the rows on screen are rendered; the rest may number in
+		// the hundreds of thousands.
+		start, end := visibleRange(m.fileOffset, m.filesVisibleHeight(), len(m.rows))
+		for i := start; i < end; i++ {
+			items = append(items, m.renderRow(m.rows[i], i == m.selected, width-2))
 		}
 	}
 
-	body := strings.Join(m.sliceLines(items, m.fileOffset, m.filesVisibleHeight()), "\n")
+	body := strings.Join(items, "\n")
 	return style.Render(fmt.Sprintf("%s\n\n%s", title, body))
 }
 
-func (m Model) renderRow(row fileRow, selected bool) string {
+// renderRow renders a Files row, with its line counts right-aligned in
+// width when they fit.
+func (m Model) renderRow(row fileRow, selected bool, width int) string {
 	statusText := fmt.Sprintf("%-2s", row.Status)
 	if row.IsDir {
 		statusText = "  "
@@ -242,9 +562,12 @@ func (m Model) renderRow(row fileRow, selected bool) string {
 
 	label := rowLabel(row)
 	labelStyle := lipgloss.NewStyle()
-	if row.Ignored {
+	if row.Ignored || row.Viewed {
 		labelStyle = labelStyle.Foreground(lipgloss.Color("240"))
 	}
+	if row.Recent {
+		labelStyle = labelStyle.Foreground(lipgloss.Color("220")).Bold(true)
+	}
 	if selected {
 		bg := lipgloss.Color("62")
 		if m.focus != focusFiles {
@@ -254,7 +577,31 @@ func (m Model) renderRow(row fileRow, selected bool) string {
 	}
 	label = labelStyle.Render(label)
 
-	return fmt.Sprintf("%s %s", statusText, label)
+	marks := ""
+	if row.Recent {
+		marks += " " + lipgloss.NewStyle().Foreground(lipgloss.Color("220")).Render("●")
+	}
+	if row.Viewed {
+		marks += " " + lipgloss.NewStyle().Foreground(lipgloss.Color("71")).Render("✓")
+	}
+	if hunks, ok := m.patch.marked[row.Path]; ok && !row.IsDir {
+		mark := "◆"
+		if len(hunks) > 0 {
+			mark = "◇"
+		}
+		marks += " " + lipgloss.NewStyle().Foreground(lipgloss.Color("69")).Render(mark)
+	}
+	line := fmt.Sprintf("%s %s%s", statusText, label, marks)
+	for _, bar := range []bool{true, false} {
+		stat := renderStat(row.Stat, bar)
+		if stat == "" {
+			break
+		}
+		if gap := width - lipgloss.Width(line) - lipgloss.Width(stat); gap >= 1 {
+			return line + strings.Repeat(" ", gap) + stat
+		}
+	}
+	return line
 }
 
 func (m Model) renderDiff(width, height int) string {
@@ -296,8 +643,19 @@ func (m Model) renderDiff(width, height int) string {
 	} else {
 		lines := m.sliceLines(m.contentLines, m.diffOffset, m.diffVisibleHeight())
 		if m.mode == modeDiff {
-			lines = colorizeDiffLines(lines)
+			offset := m.diffOffset
+			cursor := -1
+			if m.focus == focusDiff {
+				cursor = m.diffCursor - offset
+			}
+			lines = m.highlightRecentLines(lines, colorizeDiffLines(lines), time.Now())
+			if cursor >= 0 && cursor < len(lines) {
+				style, _ := diffLineStyle(m.contentLines[m.diffCursor])
+				lines[cursor] = style.Background(lipgloss.Color("238")).Render(m.contentLines[m.diffCursor])
+			}
+			lines = m.decorateComments(lines, offset)
 		}
+		lines = m.drawImages(lines, m.diffOffset)
 		body = strings.Join(lines, "\n")
 	}
 
@@ -313,19 +671,45 @@ func rowLabel(row fileRow) string {
 		}
 		return fmt.Sprintf("%s%s %s/", indent, marker, row.Name)
 	}
+	if row.OrigPath != "" {
+		orig := row.OrigPath
+		if path.Dir(orig) == path.Dir(row.Path) {
+			orig = path.Base(orig)
+		}
+		return fmt.Sprintf("%s%s → %s", indent, orig, row.Name)
+	}
 	return fmt.Sprintf("%s%s", indent, row.Name)
 }
 
 type refreshMsg struct {
+	// gen numbers the refresh and diffGen the diff it loaded, so responses
+	// overtaken by a newer request are dropped.
+	gen          uint64
+	diffGen      uint64
+	head         string
+	baseID       string
 	files        []git.StatusEntry
 	diff         string
+	source       *contextSource
+	meta         *fileMeta
 	err          error
 	gitInfo      string
+	fingerprint  string
+	hashes       map[string]string
+	stats        map[string]git.DiffStat
+	// path is the file diff belongs to and modTimes the mtimes of the
+	// changed files, for highlighting and following recent changes.
+	path     string
+	modTimes map[string]time.Time
 }
 
 type diffMsg struct {
-	diff string
-	err  error
+	gen    uint64
+	path   string
+	diff   string
+	source *contextSource
+	meta   *fileMeta
+	err    error
 }
 
 type tickMsg struct{}
@@ -338,110 +722,159 @@ type pushMsg struct {
 	err error
 }
 
+type baseMsg struct {
+	base string
+	err  error
+}
+
 func (m Model) refreshCmd() tea.Cmd {
 	keepPath := m.selectedFilePath()
 	mode := m.mode
 	showIgnored := m.showIgnored
+	opts := m.diffOpts
+	wantSource := m.context.active()
+	checkpoints := m.config.Checkpoints
+	follow := m.recent.follow
+	sortMode := m.sortMode
+	head := m.head
+	baseID := m.baseID
+	previousHashes := m.hashes
+	pages := m.fileView.pagesFor(keepPath)
+	gen, ctx := m.loads.startRefresh()
+	diffGen, diffCtx := m.loads.startDiff()
 	return func() tea.Msg {
 		var (
 			files    []git.StatusEntry
 			statuses []git.StatusEntry
+			branch   git.BranchInfo
 			err      error
 		)
+		statuses, branch, err = m.config.Backend.Status(ctx)
+		fingerprint := ""
+		if err == nil && checkpoints {
+			fingerprint = worktreeFingerprint(m.config.RepoPath, statuses)
+		}
+		resolved := ""
+		if err == nil && opts.Base != "" {
+			// Resolved once per refresh, so a fetch that moves the base
+			// reloads what was diffed against it.
+			resolved, err = m.config.Backend.ResolveBase(ctx, opts.Base)
+		}
+		if err == nil && opts.Base != "" {
+			statuses, err = m.config.Backend.StatusAgainst(ctx, opts.Base, statuses)
+		}
 		if mode == modeExplorer {
-			files, err = git.ListFiles(m.config.RepoPath, showIgnored)
-			if err == nil {
-				var statusErr error
-				statuses, statusErr = git.Status(m.config.RepoPath)
-				if statusErr == nil {
-					files = applyStatuses(files, statuses)
-				}
+			var listErr error
+			files, listErr = m.config.Backend.ListFiles(ctx, showIgnored)
+			if listErr != nil {
+				err = listErr
+			} else if err == nil {
+				files = applyStatuses(files, statuses)
+			} else {
+				err = nil
 			}
 		} else {
-			files, err = git.Status(m.config.RepoPath)
-			statuses = files
+			files = statuses
 		}
 		if err != nil {
-			return refreshMsg{files: nil, diff: "", err: err}
+			return refreshMsg{gen: gen, diffGen: diffGen, files: nil, diff: "", err: err}
 		}
 
-		gitInfo := buildGitInfo(m.config.RepoPath, statuses)
-		selectedPath := keepPath
-		selectedStatus := ""
+		changed := changedPaths(statuses)
+		hashes, hashErr := m.config.Backend.HashFiles(ctx, changed)
+		if hashErr != nil {
+			hashes = nil
+		}
+		var stats map[string]git.DiffStat
+		if len(changed) > 0 {
+			var statErr error
+			stats, statErr = m.config.Backend.NumStat(ctx, opts.Base, untrackedPaths(statuses))
+			if statErr != nil {
+				stats = nil
+			}
+		}
+		gitInfo := buildGitInfo(branch, statuses, stats)
+		var modTimes map[string]time.Time
+		if sortMode == sortModified || follow {
+			// Only changed files are stat'ed, so explorer mode does not
+			// touch every file each tick; clean ones sort after by path.
+			modTimes = fileModTimes(m.config.RepoPath, changed)
+		}
+		selected := git.StatusEntry{Path: keepPath}
 		found := false
-		if selectedPath == "" && len(files) > 0 {
-			selectedPath = files[0].Path
-			selectedStatus = files[0].Status
+		if keepPath == "" && len(files) > 0 {
+			selected = files[0]
 			found = true
 		} else {
 			for _, entry := range files {
-				if entry.Path == selectedPath {
-					selectedStatus = entry.Status
+				if entry.Path == keepPath {
+					selected = entry
 					found = true
 					break
 				}
 			}
 			if !found && len(files) > 0 {
-				selectedPath = files[0].Path
-				selectedStatus = files[0].Status
+				selected = files[0]
 			}
 		}
+		selectedPath := selected.Path
 
-		var diff string
-		var diffErr error
-		if mode == modeExplorer {
-			diff, diffErr = git.FileContents(m.config.RepoPath, selectedPath)
-		} else {
-			diff, diffErr = git.Diff(m.config.RepoPath, selectedPath, selectedStatus)
+		if branch.OID != head || resolved != baseID || !sameChangedPaths(previousHashes, hashes) {
+			// A commit, checkout, fetch or newly changed file can move what a
+			// named base resolves to; start over rather than trust keys.
+			m.cache.clear()
 		}
+		file, diffErr := m.loadFile(diffCtx, loadRequest{entry: selected, mode: mode, opts: opts, source: wantSource, head: branch.OID, baseID: resolved, pages: pages})
 		if diffErr != nil {
 			err = diffErr
 		}
 
-		return refreshMsg{files: files, diff: diff, err: err, gitInfo: gitInfo}
+		return refreshMsg{gen: gen, diffGen: diffGen, files: files, diff: file.diff, source: file.source, meta: file.meta, err: err, head: branch.OID, baseID: resolved, gitInfo: gitInfo, fingerprint: fingerprint, hashes: hashes, stats: stats, path: selectedPath, modTimes: modTimes}
 	}
 }
 
+// diffCmd loads the selected file, superseding any diff still loading so
+// holding j or k only ever shows the file that ends up selected.
 func (m Model) diffCmd() tea.Cmd {
+	gen, ctx := m.loads.startDiff()
 	entry, ok := m.selectedEntry()
 	if !ok {
 		return nil
 	}
 	m.diffOffset = 0
-	mode := m.mode
+	req := loadRequest{entry: entry, mode: m.mode, opts: m.diffOpts, source: m.context.active(), head: m.head, baseID: m.baseID, pages: m.fileView.pagesFor(entry.Path)}
 	return func() tea.Msg {
-		var (
-			diff string
-			err  error
-		)
-		if mode == modeExplorer {
-			diff, err = git.FileContents(m.config.RepoPath, entry.Path)
-		} else {
-			diff, err = git.Diff(m.config.RepoPath, entry.Path, entry.Status)
-		}
-		return diffMsg{diff: diff, err: err}
+		file, err := m.loadFile(ctx, req)
+		return diffMsg{gen: gen, path: entry.Path, diff: file.diff, source: file.source, meta: file.meta, err: err}
 	}
 }
 
 func (m Model) commitCmd(message string) tea.Cmd {
 	return func() tea.Msg {
-		err := git.Commit(m.config.RepoPath, message)
+		err := m.config.Backend.Commit(message)
 		return commitMsg{err: err}
 	}
 }
 
 func (m Model) pushCmd() tea.Cmd {
 	return func() tea.Msg {
-		err := git.Push(m.config.RepoPath)
+		err := m.config.Backend.Push()
 		return pushMsg{err: err}
 	}
 }
 
+func (m Model) baseCmd(base string) tea.Cmd {
+	return func() tea.Msg {
+		err := m.config.Backend.VerifyBase(base)
+		return baseMsg{base: base, err: err}
+	}
+}
+
 func (m Model) tickCmd() tea.Cmd {
 	if m.config.RefreshPeriod <= 0 {
 		return nil
 	}
-	return tea.Tick(m.config.RefreshPeriod, func(time.Time) tea.Msg {
+	return m.tick(m.config.RefreshPeriod, func(time.Time) tea.Msg {
 		return tickMsg{}
 	})
 }
@@ -459,6 +892,10 @@ func (m *Model) moveSelection(delta int) {
 	if next >= len(m.rows) {
 		next = len(m.rows) - 1
 	}
+	if next != m.selected {
+		m.context.reset()
+		m.diffCursor = 0
+	}
 	m.selected = next
 	m.ensureSelectionVisible()
 }
@@ -478,7 +915,7 @@ func (m Model) selectedEntry() (git.StatusEntry, bool) {
 	if row.IsDir {
 		return git.StatusEntry{}, false
 	}
-	return git.StatusEntry{Path: row.Path, Status: row.Status, Ignored: row.Ignored}, true
+	return git.StatusEntry{Path: row.Path, Status: row.Status, Ignored: row.Ignored, OrigPath: row.OrigPath, IndexHash: row.IndexHash}, true
 }
 
 func (m Model) selectedFilePath() string {
@@ -516,11 +953,21 @@ func indexForKey(rows []fileRow, key string) int {
 	return 0
 }
 
+const (
+	defaultContext = 3
+	maxContext     = 999
+)
+
+// diffAlgorithms is the cycle order for the algorithm toggle; "" is git's
+// default (myers).
+var diffAlgorithms = []string{"", "patience", "histogram", "minimal"}
+
 type paneFocus int
 
 const (
 	focusFiles paneFocus = iota
 	focusDiff
+	focusAgent
 )
 
 type viewMode int
@@ -537,6 +984,14 @@ const (
 	modalCommit
 	modalPush
 	modalHelp
+	modalBase
+	modalTimeline
+	modalRestore
+	modalComment
+	modalPatchExport
+	modalPatchImport
+	modalPatchPreview
+	modalFilter
 )
 
 func (m Model) filesVisibleHeight() int {
@@ -580,6 +1035,41 @@ func (m *Model) scrollDiff(delta int) {
 	m.diffOffset = clampOffset(m.diffOffset+delta, len(m.contentLines), visible)
 }
 
+// moveDiff moves the diff cursor in diff mode, scrolling to keep it
+// visible, and plainly scrolls the file view in explorer mode.
+func (m *Model) moveDiff(delta int) {
+	if m.mode != modeDiff {
+		m.scrollDiff(delta)
+		return
+	}
+	m.diffCursor = clampCursor(m.diffCursor+delta, len(m.contentLines))
+	m.ensureDiffCursorVisible()
+}
+
+func (m *Model) ensureDiffCursorVisible() {
+	visible := m.diffVisibleHeight()
+	if visible <= 0 {
+		m.diffOffset = 0
+		return
+	}
+	if m.diffCursor < m.diffOffset {
+		m.diffOffset = m.diffCursor
+	} else if m.diffCursor >= m.diffOffset+visible {
+		m.diffOffset = m.diffCursor - visible + 1
+	}
+	m.diffOffset = clampOffset(m.diffOffset, len(m.contentLines), visible)
+}
+
+func clampCursor(cursor, total int) int {
+	if cursor >= total {
+		cursor = total - 1
+	}
+	if cursor < 0 {
+		cursor = 0
+	}
+	return cursor
+}
+
 func clampOffset(offset, total, visible int) int {
 	if total <= visible {
 		return 0
@@ -602,21 +1092,28 @@ func splitLines(text string) []string {
 }
 
 func (m Model) sliceLines(lines []string, offset, visible int) []string {
-	if visible <= 0 || len(lines) == 0 {
-		return []string{}
+	start, end := visibleRange(offset, visible, len(lines))
+	return lines[start:end]
+}
+
+// visibleRange returns the bounds of the window of visible items starting
+// at offset, clamped to total.
+func visibleRange(offset, visible, total int) (int, int) {
+	if visible <= 0 || total == 0 {
+		return 0, 0
 	}
 	start := offset
 	if start < 0 {
 		start = 0
 	}
-	if start >= len(lines) {
-		start = len(lines) - 1
+	if start >= total {
+		start = total - 1
 	}
 	end := start + visible
-	if end > len(lines) {
-		end = len(lines)
+	if end > total {
+		end = total
 	}
-	return lines[start:end]
+	return start, end
 }
 
 func (m Model) paneHeight() int {
@@ -651,6 +1148,10 @@ func (m Model) diffContentWidth() int {
 }
 
 func (m Model) paneWidths() (int, int) {
+	if m.agentVisible() {
+		left, right, _ := m.agentLayout()
+		return left, right
+	}
 	leftWidth := m.width / 3
 	if leftWidth < 24 {
 		leftWidth = 24
@@ -693,10 +1194,60 @@ func (m *Model) toggleMode() {
 	}
 }
 
+func (m *Model) cycleWhitespace() {
+	switch m.diffOpts.Whitespace {
+	case git.WhitespaceShow:
+		m.diffOpts.Whitespace = git.WhitespaceIgnoreChange
+	case git.WhitespaceIgnoreChange:
+		m.diffOpts.Whitespace = git.WhitespaceIgnoreAll
+	default:
+		m.diffOpts.Whitespace = git.WhitespaceShow
+	}
+}
+
+func (m *Model) cycleAlgorithm() {
+	next := diffAlgorithms[0]
+	for i, algorithm := range diffAlgorithms {
+		if algorithm == m.diffOpts.Algorithm {
+			next = diffAlgorithms[(i+1)%len(diffAlgorithms)]
+			break
+		}
+	}
+	m.diffOpts.Algorithm = next
+}
+
+func (m *Model) adjustContext(delta int) {
+	context := m.diffOpts.Context + delta
+	if context < 0 {
+		context = 0
+	}
+	if context > maxContext {
+		context = maxContext
+	}
+	m.diffOpts.Context = context
+}
+
+func diffOptionsLabel(opts git.DiffOptions) string {
+	parts := []string{fmt.Sprintf("ctx %d", opts.Context)}
+	switch opts.Whitespace {
+	case git.WhitespaceIgnoreChange:
+		parts = append(parts, "-b")
+	case git.WhitespaceIgnoreAll:
+		parts = append(parts, "-w")
+	}
+	if opts.Algorithm != "" {
+		parts = append(parts, opts.Algorithm)
+	}
+	return strings.Join(parts, " ")
+}
+
 func (m *Model) toggleFocus() {
-	if m.focus == focusFiles {
+	switch {
+	case m.focus == focusFiles:
 		m.focus = focusDiff
-	} else {
+	case m.focus == focusDiff && m.agentRunning():
+		m.focus = focusAgent
+	default:
 		m.focus = focusFiles
 	}
 }
@@ -711,7 +1262,7 @@ func (m *Model) toggleFolder(path string) {
 	}
 	m.collapsed[path] = !expanded
 	selectedKey := "dir:" + path
-	m.rows = buildRows(m.files, m.collapsed)
+	m.rebuildRows()
 	m.selected = indexForKey(m.rows, selectedKey)
 	m.fileOffset = clampOffset(m.fileOffset, len(m.rows), m.filesVisibleHeight())
 	m.ensureSelectionVisible()
@@ -726,7 +1277,23 @@ func (m Model) renderStatusBar() string {
 	if gitInfo == "" {
 		gitInfo = "git: -"
 	}
-	status := fmt.Sprintf("Mode: %s  |  %s  |  h for help", modeLabel, gitInfo)
+	base := m.diffOpts.Base
+	if base == "" {
+		base = "index"
+	}
+	parts := []string{"Mode: " + modeLabel, "base: " + base}
+	if m.mode == modeDiff {
+		parts = append(parts, diffOptionsLabel(m.diffOpts))
+	}
+	parts = append(parts, gitInfo)
+	if progress := m.viewedProgress(); progress != "" {
+		parts = append(parts, progress)
+	}
+	if m.notice != "" {
+		parts = append(parts, m.notice)
+	}
+	parts = append(parts, "h for help")
+	status := strings.Join(parts, "  |  ")
 	style := lipgloss.NewStyle().
 		Width(m.width).
 		Height(1).
@@ -757,11 +1324,17 @@ func statusColor(status string) lipgloss.Color {
 	}
 }
 
-func buildGitInfo(repoPath string, statuses []git.StatusEntry) string {
-	branch, err := git.Branch(repoPath)
-	if err != nil || branch == "" {
+func buildGitInfo(branchInfo git.BranchInfo, statuses []git.StatusEntry, stats map[string]git.DiffStat) string {
+	branch := branchInfo.Head
+	if branch == "" {
 		branch = "-"
 	}
+	if branchInfo.Ahead > 0 {
+		branch += fmt.Sprintf(" ↑%d", branchInfo.Ahead)
+	}
+	if branchInfo.Behind > 0 {
+		branch += fmt.Sprintf(" ↓%d", branchInfo.Behind)
+	}
 
 	counts := map[string]int{
 		"M": 0,
@@ -805,6 +1378,9 @@ func buildGitInfo(repoPath string, statuses []git.StatusEntry) string {
 		if counts["?"] > 0 {
 			parts = append(parts, fmt.Sprintf("?%d", counts["?"]))
 		}
+		if total := totalStat(stats); total.Added > 0 || total.Deleted > 0 {
+			parts = append(parts, fmt.Sprintf("+%d -%d", total.Added, total.Deleted))
+		}
 	}
 
 	return strings.Join(parts, " ")
@@ -814,12 +1390,12 @@ func applyStatuses(files []git.StatusEntry, statuses []git.StatusEntry) []git.St
 	if len(files) == 0 || len(statuses) == 0 {
 		return files
 	}
-	statusMap := make(map[string]string, len(statuses))
+	statusMap := make(map[string]git.StatusEntry, len(statuses))
 	for _, entry := range statuses {
 		if entry.Path == "" {
 			continue
 		}
-		statusMap[entry.Path] = entry.Status
+		statusMap[entry.Path] = entry
 	}
 	if len(statusMap) == 0 {
 		return files
@@ -827,142 +1403,69 @@ func applyStatuses(files []git.StatusEntry, statuses []git.StatusEntry) []git.St
 	merged := make([]git.StatusEntry, 0, len(files))
 	for _, entry := range files {
 		if status, ok := statusMap[entry.Path]; ok {
-			entry.Status = status
+			entry.Status = status.Status
+			entry.OrigPath = status.OrigPath
+			entry.IndexHash = status.IndexHash
+			entry.Conflicted = status.Conflicted
+			entry.Ignored = entry.Ignored || status.Ignored
 		}
 		merged = append(merged, entry)
 	}
 	return merged
 }
 
+// buildRows lays out files as a tree, honouring and seeding collapsed.
 func buildRows(files []git.StatusEntry, collapsed map[string]bool) []fileRow {
-	if len(files) == 0 {
-		return nil
-	}
 	if collapsed == nil {
 		collapsed = make(map[string]bool)
 	}
-
-	type folderCount struct {
-		total   int
-		ignored int
-	}
-	folderCounts := make(map[string]folderCount)
-	for _, entry := range files {
-		if entry.Path == "" {
-			continue
-		}
-		parts := strings.Split(entry.Path, "/")
-		if len(parts) <= 1 {
-			continue
-		}
-		for i := 1; i < len(parts); i++ {
-			folderPath := strings.Join(parts[:i], "/")
-			count := folderCounts[folderPath]
-			count.total++
-			if entry.Ignored {
-				count.ignored++
-			}
-			folderCounts[folderPath] = count
-		}
-	}
-
-	rows := make([]fileRow, 0, len(files))
-	seenFolders := make(map[string]struct{})
-	for _, entry := range files {
-		if entry.Path == "" {
-			continue
-		}
-		parts := strings.Split(entry.Path, "/")
-		if len(parts) > 1 {
-			for i := 1; i < len(parts); i++ {
-				folderPath := strings.Join(parts[:i], "/")
-				if _, ok := seenFolders[folderPath]; ok {
-					continue
-				}
-				seenFolders[folderPath] = struct{}{}
-				if _, ok := collapsed[folderPath]; !ok {
-					collapsed[folderPath] = true
-				}
-				count := folderCounts[folderPath]
-				ignored := count.total > 0 && count.ignored == count.total
-				rows = append(rows, fileRow{
-					Path:      folderPath,
-					Name:      path.Base(folderPath),
-					IsDir:     true,
-					Collapsed: collapsed[folderPath],
-					Ignored:   ignored,
-					Depth:     i - 1,
-				})
-			}
-		}
-		rows = append(rows, fileRow{
-			Path:    entry.Path,
-			Name:    path.Base(entry.Path),
-			Status:  entry.Status,
-			Ignored: entry.Ignored,
-			Depth:   len(parts) - 1,
-		})
-	}
-
-	return filterCollapsedRows(rows, collapsed)
+	tree := newFileTree()
+	tree.sync(files, collapsed)
+	return tree.rows(collapsed, nil, nil)
 }
 
-func filterCollapsedRows(rows []fileRow, collapsed map[string]bool) []fileRow {
-	if len(rows) == 0 {
-		return rows
+func colorizeDiffLines(lines []string) []string {
+	if len(lines) == 0 {
+		return lines
 	}
-	out := make([]fileRow, 0, len(rows))
-	for _, row := range rows {
-		if isHiddenByAncestor(row.Path, collapsed) {
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		if style, ok := diffLineStyle(line); ok {
+			out[i] = style.Render(line)
 			continue
 		}
-		if row.IsDir {
-			row.Collapsed = collapsed[row.Path]
-		}
-		out = append(out, row)
+		out[i] = line
 	}
 	return out
 }
 
-func isHiddenByAncestor(itemPath string, collapsed map[string]bool) bool {
-	parts := strings.Split(itemPath, "/")
-	if len(parts) <= 1 {
-		return false
-	}
-	for i := 1; i < len(parts); i++ {
-		ancestor := strings.Join(parts[:i], "/")
-		if collapsed[ancestor] {
-			return true
-		}
+// diffLineStyle picks the style of a unified diff line and reports false
+// for context lines, which are left unstyled.
+func diffLineStyle(line string) (lipgloss.Style, bool) {
+	style := lipgloss.NewStyle()
+	color, ok := diffLineColor(line)
+	if !ok {
+		return style, false
 	}
-	return false
+	return style.Foreground(lipgloss.Color(color)), true
 }
 
-func colorizeDiffLines(lines []string) []string {
-	if len(lines) == 0 {
-		return lines
+// diffLineColor picks the 256-color index of a unified diff line by its
+// prefix; the TUI and the HTML report share it.
+func diffLineColor(line string) (string, bool) {
+	if strings.HasPrefix(line, "+++ ") || strings.HasPrefix(line, "--- ") {
+		return "69", true
 	}
-	plusStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("71"))
-	minusStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("160"))
-	hunkStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("69"))
-	out := make([]string, len(lines))
-	for i, line := range lines {
-		if strings.HasPrefix(line, "+++ ") || strings.HasPrefix(line, "--- ") {
-			out[i] = hunkStyle.Render(line)
-			continue
-		}
-		switch {
-		case strings.HasPrefix(line, "+++") || strings.HasPrefix(line, "+"):
-			out[i] = plusStyle.Render(line)
-		case strings.HasPrefix(line, "---") || strings.HasPrefix(line, "-"):
-			out[i] = minusStyle.Render(line)
-		case strings.HasPrefix(line, "@@"):
-			out[i] = hunkStyle.Render(line)
-		default:
-			out[i] = line
-		}
+	switch {
+	case strings.HasPrefix(line, "+++") || strings.HasPrefix(line, "+"):
+		return "71", true
+	case strings.HasPrefix(line, "---") || strings.HasPrefix(line, "-"):
+		return "160", true
+	case strings.HasPrefix(line, "@@"):
+		return "69", true
+	default:
+		return "", false
 	}
-	return out
 }
 
 func (m *Model) openCommitModal() {
@@ -972,6 +1475,14 @@ func (m *Model) openCommitModal() {
 	m.commitText.Focus()
 }
 
+func (m *Model) openBaseModal() {
+	m.modal = modalBase
+	m.modalErr = ""
+	m.baseText.SetValue(m.diffOpts.Base)
+	m.baseText.CursorEnd()
+	m.baseText.Focus()
+}
+
 func (m *Model) openHelpModal() {
 	m.modal = modalHelp
 	m.modalErr = ""
@@ -982,10 +1493,28 @@ func (m *Model) closeModal() {
 	m.modal = modalNone
 	m.modalErr = ""
 	m.commitText.Blur()
+	m.baseText.Blur()
+	m.filterText.Blur()
+	m.commentText.Blur()
+	m.patchText.Blur()
 }
 
 func (m Model) handleModalKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch m.modal {
+	case modalTimeline:
+		return m.handleTimelineKey(msg)
+	case modalRestore:
+		return m.handleRestoreKey(msg)
+	case modalComment:
+		return m.handleCommentKey(msg)
+	case modalPatchExport:
+		return m.handlePatchExportKey(msg)
+	case modalPatchImport:
+		return m.handlePatchImportKey(msg)
+	case modalPatchPreview:
+		return m.handlePatchPreviewKey(msg)
+	case modalFilter:
+		return m.handleFilterKey(msg)
 	case modalCommit:
 		switch msg.String() {
 		case "esc":
@@ -1019,6 +1548,19 @@ func (m Model) handleModalKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.closeModal()
 			return m, nil
 		}
+	case modalBase:
+		switch msg.String() {
+		case "esc":
+			m.closeModal()
+			return m, nil
+		case "enter":
+			m.modalErr = ""
+			return m, m.baseCmd(strings.TrimSpace(m.baseText.Value()))
+		default:
+			var cmd tea.Cmd
+			m.baseText, cmd = m.baseText.Update(msg)
+			return m, cmd
+		}
 	}
 	return m, nil
 }
@@ -1045,6 +1587,34 @@ func (m Model) renderModal() string {
 		body = append(body, "Commit created. Push now?")
 		body = append(body, "")
 		body = append(body, "Enter to push, Esc to cancel.")
+	case modalBase:
+		title = titleStyle.Render("Compare base")
+		body = append(body, "Compare the worktree against a ref, or a range A..B.")
+		body = append(body, "Leave empty to compare against the index.")
+		body = append(body, m.baseText.View())
+		body = append(body, "")
+		body = append(body, "Enter to apply, Esc to cancel.")
+	case modalTimeline:
+		title = titleStyle.Render("Timeline")
+		body = m.renderTimeline()
+	case modalRestore:
+		title = titleStyle.Render("Restore checkpoint")
+		body = m.renderRestore()
+	case modalComment:
+		title = titleStyle.Render("Comment")
+		body = m.renderCommentModal()
+	case modalPatchExport:
+		title = titleStyle.Render("Export patch")
+		body = m.renderPatchExport()
+	case modalPatchImport:
+		title = titleStyle.Render("Import patch")
+		body = m.renderPatchImport()
+	case modalPatchPreview:
+		title = titleStyle.Render("Apply patch")
+		body = m.renderPatchPreview()
+	case modalFilter:
+		title = titleStyle.Render("Filter files")
+		body = m.renderFilterModal()
 	case modalHelp:
 		title = titleStyle.Render("Help")
 		body = append(body, "Navigation:")
@@ -1054,10 +1624,40 @@ func (m Model) renderModal() string {
 		body = append(body, "")
 		body = append(body, "Modes:")
 		body = append(body, "  m to toggle explorer/diff")
+		body = append(body, "  b to set the compare base")
+		body = append(body, "  t for checkpoint timeline (diff/restore)")
+		body = append(body, "  F to follow the most recently changed file")
+		body = append(body, "  s to sort files by path/status/size/modified")
+		body = append(body, "  T to toggle a flat file list")
+		body = append(body, "  / to filter files by status or path glob")
+		body = append(body, "")
+		body = append(body, "Diff options:")
+		body = append(body, "  w to cycle whitespace (show/-b/-w)")
+		body = append(body, "  +/- to grow/shrink context lines")
+		body = append(body, "  a to cycle diff algorithm")
+		body = append(body, "  [/] to expand context above/below hunk")
+		body = append(body, "  f to toggle full file view")
+		body = append(body, "")
+		body = append(body, "Large files:")
+		body = append(body, "  L to load more of a long file or diff")
+		body = append(body, "  X to toggle the hex preview of a binary file")
+		body = append(body, "")
+		body = append(body, "Review:")
+		body = append(body, "  c/C to comment on line/hunk (diff focused)")
+		body = append(body, "  x to delete comments on the line")
+		body = append(body, "  E to export comments as Markdown")
+		body = append(body, "  R to export an HTML report of all changes")
+		body = append(body, "  v to mark file viewed, V to hide viewed")
+		body = append(body, "")
+		body = append(body, "Agent:")
+		body = append(body, "  ! to launch or focus the agent pane")
+		body = append(body, "  ctrl+] to leave the agent pane")
 		body = append(body, "")
 		body = append(body, "Actions:")
 		body = append(body, "  Enter to commit")
-		body = append(body, "  space to toggle folder")
+		body = append(body, "  P to export a patch, I to import one")
+		body = append(body, "  space to toggle folder, or mark a file or")
+		body = append(body, "    hunk (diff focused) for patch export")
 		body = append(body, "  i to show/hide ignored files")
 		body = append(body, "  h for help, q/Esc to quit")
 	}
@@ -3,6 +3,8 @@ package app
 import (
 	"strings"
 	"testing"
+
+	"wing/internal/git"
 )
 
 func TestToggleModeDoesNotChangeFocus(t *testing.T) {
@@ -94,3 +96,60 @@ func TestRenderDiffTitleChangesWithMode(t *testing.T) {
 		t.Fatalf("expected Diff title in diff mode")
 	}
 }
+
+func TestBaseFromConfigShownInStatusBar(t *testing.T) {
+	m := New(Config{Base: " main "})
+	m.width = 120
+	if m.diffOpts.Base != "main" {
+		t.Fatalf("expected trimmed base, got %q", m.diffOpts.Base)
+	}
+	if !strings.Contains(m.renderStatusBar(), "base: main") {
+		t.Fatalf("expected base in status bar")
+	}
+	m.diffOpts.Base = ""
+	if !strings.Contains(m.renderStatusBar(), "base: index") {
+		t.Fatalf("expected index base in status bar")
+	}
+}
+
+func TestDiffOptionToggles(t *testing.T) {
+	m := New(Config{})
+	m.adjustContext(2)
+	if m.diffOpts.Context != defaultContext+2 {
+		t.Fatalf("expected context %d, got %d", defaultContext+2, m.diffOpts.Context)
+	}
+	m.adjustContext(-100)
+	if m.diffOpts.Context != 0 {
+		t.Fatalf("expected context clamp to 0, got %d", m.diffOpts.Context)
+	}
+
+	m.cycleWhitespace()
+	m.cycleWhitespace()
+	if m.diffOpts.Whitespace != git.WhitespaceIgnoreAll {
+		t.Fatalf("expected ignore-all-space, got %v", m.diffOpts.Whitespace)
+	}
+	m.cycleWhitespace()
+	if m.diffOpts.Whitespace != git.WhitespaceShow {
+		t.Fatalf("expected whitespace shown, got %v", m.diffOpts.Whitespace)
+	}
+
+	for _, want := range []string{"patience", "histogram", "minimal", ""} {
+		m.cycleAlgorithm()
+		if m.diffOpts.Algorithm != want {
+			t.Fatalf("expected algorithm %q, got %q", want, m.diffOpts.Algorithm)
+		}
+	}
+}
+
+func TestBuildGitInfoBranchHeader(t *testing.T) {
+	info := buildGitInfo(git.BranchInfo{Head: "main", Ahead: 2, Behind: 1}, []git.StatusEntry{
+		{Path: "a.go", Status: "M"},
+		{Path: "b.go", Status: "??"},
+	}, map[string]git.DiffStat{"a.go": {Added: 3, Deleted: 1}, "b.go": {Added: 10}})
+	if info != "git: main ↑2 ↓1 M1 ?1 +13 -1" {
+		t.Fatalf("unexpected git info %q", info)
+	}
+	if info := buildGitInfo(git.BranchInfo{}, nil, nil); info != "git: - clean" {
+		t.Fatalf("unexpected empty git info %q", info)
+	}
+}
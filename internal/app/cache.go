@@ -0,0 +1,214 @@
+package app
+
+import (
+	"container/list"
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"wing/internal/git"
+	"wing/internal/graphics"
+)
+
+// defaultCacheBytes bounds the memory held by cached diffs and file
+// contents.
+const defaultCacheBytes = 32 << 20
+
+// racyWindow is how recently a file may have been written before its size
+// and mtime stop being trusted: a second write within the same clock tick
+// can leave both unchanged, so such files are always reloaded.
+const racyWindow = 2 * time.Second
+
+// cacheKey identifies one load of a file. Anything that changes the output
+// changes the key: the staged blob, the worktree file's size and mtime, the
+// commits HEAD and the base point at and the diff options.
+type cacheKey struct {
+	path   string
+	mode   viewMode
+	opts   git.DiffOptions
+	source bool
+	status string
+	index  string
+	head   string
+	baseID string
+	pages  int
+	size   int64
+	mtime  int64
+}
+
+type cacheEntry struct {
+	key  cacheKey
+	file loadedFile
+	size int
+}
+
+// contentCache is a least-recently-used cache of loaded diffs and file
+// contents bounded by their total size. It is shared by every copy of the
+// Model and filled from commands, so it locks.
+type contentCache struct {
+	mu    sync.Mutex
+	limit int
+	used  int
+	order *list.List
+	items map[cacheKey]*list.Element
+}
+
+func newContentCache(limit int) *contentCache {
+	return &contentCache{limit: limit, order: list.New(), items: make(map[cacheKey]*list.Element)}
+}
+
+func (c *contentCache) get(key cacheKey) (loadedFile, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return loadedFile{}, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).file, true
+}
+
+// put stores a load, evicting the least recently used ones to stay within
+// the limit. Loads larger than the whole limit are not kept.
+func (c *contentCache) put(key cacheKey, file loadedFile) {
+	size := len(file.diff) + len(key.path)
+	if file.source != nil {
+		for _, line := range file.source.lines {
+			size += len(line)
+		}
+	}
+	if file.meta != nil {
+		size += len(file.meta.data)
+		for _, img := range []*graphics.Image{file.meta.oldImage, file.meta.newImage} {
+			if img != nil {
+				size += img.Footprint()
+			}
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if size > c.limit {
+		return
+	}
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+	c.items[key] = c.order.PushFront(&cacheEntry{key: key, file: file, size: size})
+	c.used += size
+	for c.used > c.limit {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *contentCache) remove(elem *list.Element) {
+	entry := c.order.Remove(elem).(*cacheEntry)
+	delete(c.items, entry.key)
+	c.used -= entry.size
+}
+
+func (c *contentCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.items = make(map[cacheKey]*list.Element)
+	c.used = 0
+}
+
+// loadRequest is everything that decides what loading a file produces.
+type loadRequest struct {
+	entry  git.StatusEntry
+	mode   viewMode
+	opts   git.DiffOptions
+	source bool
+	// head is the commit HEAD pointed at when the request was made.
+	head string
+	// baseID is the commits the base resolved to, so moving a branch
+	// named as the base misses the cache.
+	baseID string
+	pages  int
+}
+
+// loadedFile is the diff or contents of a file, with the expanded context
+// source and, for binary or partly loaded files, what is known about them.
+type loadedFile struct {
+	diff   string
+	source *contextSource
+	meta   *fileMeta
+}
+
+// cacheKeyFor builds the key for req, or reports false when the worktree
+// file changed too recently to be keyed by its mtime.
+func cacheKeyFor(repoPath string, req loadRequest, now time.Time) (cacheKey, bool) {
+	key := cacheKey{path: req.entry.Path, mode: req.mode, pages: req.pages, size: -1}
+	if req.mode == modeDiff {
+		key.opts = req.opts
+		key.source = req.source
+		key.status = req.entry.Status
+		key.index = req.entry.IndexHash
+		key.head = req.head
+		key.baseID = req.baseID
+	}
+	if info, err := os.Stat(filepath.Join(repoPath, req.entry.Path)); err == nil {
+		if now.Sub(info.ModTime()) < racyWindow {
+			return cacheKey{}, false
+		}
+		key.size = info.Size()
+		key.mtime = info.ModTime().UnixNano()
+	}
+	return key, true
+}
+
+// loadFile reads the diff of a file in diff mode or its contents in
+// explorer mode, served from the cache when nothing it depends on has
+// changed.
+func (m Model) loadFile(ctx context.Context, req loadRequest) (loadedFile, error) {
+	key, cacheable := cacheKeyFor(m.config.RepoPath, req, time.Now())
+	if cacheable {
+		if file, ok := m.cache.get(key); ok {
+			return file, nil
+		}
+	}
+	var (
+		file loadedFile
+		err  error
+	)
+	if req.mode == modeExplorer {
+		var data git.FileData
+		data, err = m.config.Backend.ReadFile(ctx, req.entry.Path, int64(req.pages)*textPageBytes)
+		if err == nil {
+			file.diff, file.meta = fileText(data, req.pages)
+			if file.meta != nil && file.meta.binary {
+				file.meta.newImage = decodeImage(data, func(limit int64) (git.FileData, error) {
+					return m.config.Backend.ReadFile(ctx, req.entry.Path, limit)
+				})
+			}
+		}
+	} else {
+		file.diff, err = m.config.Backend.Diff(ctx, req.entry, req.opts)
+		if err == nil && isBinaryDiff(file.diff) {
+			file.meta = m.binaryMeta(ctx, req.entry, req.opts, int64(req.pages)*hexPageBytes)
+		} else if err == nil && req.source {
+			file.source = loadContextSource(ctx, m.config.Backend, req.entry.Path, req.opts)
+		}
+	}
+	if err == nil && cacheable && (!req.source || file.source != nil || file.meta != nil) {
+		m.cache.put(key, file)
+	}
+	return file, err
+}
+
+// sameChangedPaths reports whether two refreshes saw the same set of
+// changed files.
+func sameChangedPaths(previous, current map[string]string) bool {
+	if len(previous) != len(current) {
+		return false
+	}
+	for path := range current {
+		if _, ok := previous[path]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,144 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"wing/internal/git"
+	"wing/internal/git/gittest"
+)
+
+func TestContentCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newContentCache(20)
+	a, b, c := cacheKey{path: "a"}, cacheKey{path: "b"}, cacheKey{path: "c"}
+	cache.put(a, loadedFile{diff: "aaaaaaaa"})
+	cache.put(b, loadedFile{diff: "bbbbbbbb"})
+	if _, ok := cache.get(a); !ok {
+		t.Fatalf("expected a cached")
+	}
+	cache.put(c, loadedFile{diff: "cccccccc"})
+	if _, ok := cache.get(b); ok {
+		t.Fatalf("expected b, the least recently used, to be evicted")
+	}
+	if _, ok := cache.get(a); !ok {
+		t.Fatalf("expected a to survive eviction")
+	}
+	cache.put(cacheKey{path: "huge"}, loadedFile{diff: string(make([]byte, 64))})
+	if _, ok := cache.get(cacheKey{path: "huge"}); ok || cache.used > cache.limit {
+		t.Fatalf("expected an oversized load to be skipped, used %d", cache.used)
+	}
+	cache.clear()
+	if _, ok := cache.get(a); ok || cache.used != 0 {
+		t.Fatalf("expected clear to empty the cache")
+	}
+}
+
+func TestCacheKeyFollowsFileState(t *testing.T) {
+	repo := t.TempDir()
+	path := filepath.Join(repo, "a.go")
+	if err := os.WriteFile(path, []byte("one\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	entry := git.StatusEntry{Path: "a.go", Status: "M", IndexHash: "1111"}
+	opts := git.DiffOptions{Context: 3}
+	if _, ok := cacheKeyFor(repo, loadRequest{entry: entry, mode: modeDiff, opts: opts, head: "head"}, now); ok {
+		t.Fatalf("expected a file written just now not to be cacheable")
+	}
+
+	old := now.Add(-time.Minute)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	key, ok := cacheKeyFor(repo, loadRequest{entry: entry, mode: modeDiff, opts: opts, head: "head"}, now)
+	if !ok || key.size != 4 {
+		t.Fatalf("expected a settled file to be cacheable, got %+v", key)
+	}
+	staged := entry
+	staged.IndexHash = "2222"
+	if other, _ := cacheKeyFor(repo, loadRequest{entry: staged, mode: modeDiff, opts: opts, head: "head"}, now); other == key {
+		t.Fatalf("expected staging to change the key")
+	}
+	if other, _ := cacheKeyFor(repo, loadRequest{entry: entry, mode: modeDiff, opts: opts, head: "moved"}, now); other == key {
+		t.Fatalf("expected a new HEAD to change the key")
+	}
+	if other, _ := cacheKeyFor(repo, loadRequest{entry: entry, mode: modeDiff, opts: opts, head: "head", baseID: "fetched"}, now); other == key {
+		t.Fatalf("expected a moved base to change the key")
+	}
+	contents, _ := cacheKeyFor(repo, loadRequest{entry: entry, mode: modeExplorer, opts: opts, head: "head"}, now)
+	wider, _ := cacheKeyFor(repo, loadRequest{entry: entry, mode: modeExplorer, opts: git.DiffOptions{Context: 8}, source: true, head: "moved"}, now)
+	if contents != wider {
+		t.Fatalf("expected file contents to depend only on the file")
+	}
+}
+
+// countingBackend counts the diffs actually computed.
+type countingBackend struct {
+	*gittest.Fake
+	diffs atomic.Int32
+}
+
+func (b *countingBackend) Diff(ctx context.Context, entry git.StatusEntry, opts git.DiffOptions) (string, error) {
+	b.diffs.Add(1)
+	return b.Fake.Diff(ctx, entry, opts)
+}
+
+func TestUnchangedDiffsComeFromCache(t *testing.T) {
+	backend := &countingBackend{Fake: &gittest.Fake{
+		Entries: []git.StatusEntry{{Path: "a.go", Status: "M"}, {Path: "b.go", Status: "M"}},
+		Diffs:   map[string]string{"a.go": "+a", "b.go": "+b"},
+	}}
+	h := newHarness(t, backend, 80, 16)
+	h.press("m")
+	start := backend.diffs.Load()
+	h.press("j", "k", "j", "k")
+	if got := backend.diffs.Load() - start; got != 1 {
+		t.Fatalf("expected only b.go to be diffed once, got %d diffs", got)
+	}
+	h.run(h.model.refreshCmd())
+	if got := backend.diffs.Load() - start; got != 1 {
+		t.Fatalf("expected an idle refresh to reuse the diff, got %d diffs", got)
+	}
+
+	backend.Update(func(f *gittest.Fake) {
+		f.Entries = append(f.Entries, git.StatusEntry{Path: "c.go", Status: "??"})
+		f.Diffs["a.go"] = "+a2"
+	})
+	h.run(h.model.refreshCmd())
+	if h.model.diff != "+a2" {
+		t.Fatalf("expected a changed status list to drop cached diffs, got %q", h.model.diff)
+	}
+}
+
+func TestMovedBaseReloadsDiff(t *testing.T) {
+	entries := []git.StatusEntry{{Path: "a.go", Status: "M"}}
+	backend := &countingBackend{Fake: &gittest.Fake{
+		Entries:  entries,
+		Against:  map[string][]git.StatusEntry{"origin/main": entries},
+		Resolved: map[string]string{"origin/main": "aaaa"},
+		Diffs:    map[string]string{"a.go": "+a"},
+	}}
+	h := newHarness(t, backend, 80, 16)
+	h.press("m")
+	h.model.diffOpts.Base = "origin/main"
+	h.run(h.model.refreshCmd())
+	start := backend.diffs.Load()
+	h.run(h.model.refreshCmd())
+	if got := backend.diffs.Load() - start; got != 0 {
+		t.Fatalf("expected an idle refresh against a base to reuse the diff, got %d diffs", got)
+	}
+
+	// A fetch moves origin/main without touching HEAD or the worktree.
+	backend.Update(func(f *gittest.Fake) {
+		f.Resolved["origin/main"] = "bbbb"
+		f.Diffs["a.go"] = "+a2"
+	})
+	h.run(h.model.refreshCmd())
+	if h.model.diff != "+a2" {
+		t.Fatalf("expected a moved base to reload the diff, got %q", h.model.diff)
+	}
+}
@@ -0,0 +1,281 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"wing/internal/git"
+	"wing/internal/review"
+)
+
+// lineRef maps a rendered diff line to file line numbers. header marks hunk
+// headers; hunk is the diffLines index of the enclosing hunk header, or -1
+// for the file header.
+type lineRef struct {
+	old    int
+	new    int
+	hunk   int
+	header bool
+}
+
+type reviewMsg struct {
+	store review.Store
+	err   error
+}
+
+type reviewSavedMsg struct {
+	err error
+}
+
+type exportMsg struct {
+	path   string
+	copied bool
+	err    error
+}
+
+// diffLineRefs numbers every line of a unified diff.
+func diffLineRefs(lines []string) []lineRef {
+	refs := make([]lineRef, len(lines))
+	hunk := -1
+	oldLine, newLine := 0, 0
+	for i, line := range lines {
+		if strings.HasPrefix(line, "@@ ") {
+			parsed := git.ParseDiff(line)
+			if len(parsed.Hunks) == 1 {
+				hunk = i
+				oldLine = parsed.Hunks[0].OldStart
+				newLine = parsed.Hunks[0].NewStart
+				refs[i] = lineRef{old: oldLine, new: newLine, hunk: hunk, header: true}
+				continue
+			}
+		}
+		if hunk == -1 {
+			refs[i] = lineRef{hunk: -1}
+			continue
+		}
+		ref := lineRef{hunk: hunk}
+		switch {
+		case strings.HasPrefix(line, "+"):
+			ref.new = newLine
+			newLine++
+		case strings.HasPrefix(line, "-"):
+			ref.old = oldLine
+			oldLine++
+		case strings.HasPrefix(line, " "):
+			ref.old = oldLine
+			ref.new = newLine
+			oldLine++
+			newLine++
+		}
+		refs[i] = ref
+	}
+	return refs
+}
+
+func (m Model) loadReviewCmd() tea.Cmd {
+	return func() tea.Msg {
+		store, err := review.Load(m.config.RepoPath)
+		return reviewMsg{store: store, err: err}
+	}
+}
+
+func saveReviewCmd(store review.Store) tea.Cmd {
+	return func() tea.Msg {
+		return reviewSavedMsg{err: store.Save()}
+	}
+}
+
+// saveReview writes the review state back, unless it failed to load: the
+// file then still holds comments wing could not read, and saving would
+// replace them.
+func (m *Model) saveReview() tea.Cmd {
+	if m.reviewErr != nil {
+		m.notice = fmt.Sprintf("Not saving review: %s", m.reviewErr)
+		return nil
+	}
+	return saveReviewCmd(m.review)
+}
+
+// exportReviewCmd writes the feedback document next to the review state and
+// copies it to the clipboard when one is available.
+func (m Model) exportReviewCmd() tea.Cmd {
+	comments := append([]review.Comment(nil), m.review.Comments...)
+	return func() tea.Msg {
+		storePath, err := review.StorePath(m.config.RepoPath)
+		if err != nil {
+			return exportMsg{err: err}
+		}
+		doc := review.ExportMarkdown(comments)
+		path := filepath.Join(filepath.Dir(storePath), "review.md")
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return exportMsg{err: err}
+		}
+		if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+			return exportMsg{err: err}
+		}
+		copied := clipboard.WriteAll(doc) == nil
+		return exportMsg{path: path, copied: copied}
+	}
+}
+
+// startComment opens the comment modal for the line under the diff cursor,
+// or for its whole hunk when wholeHunk is set.
+func (m *Model) startComment(wholeHunk bool) {
+	entry, ok := m.selectedEntry()
+	if !ok || m.mode != modeDiff {
+		return
+	}
+	if m.focus != focusDiff {
+		m.notice = "Focus the diff pane (Tab) to comment."
+		return
+	}
+	if m.diffCursor < 0 || m.diffCursor >= len(m.lineRefs) {
+		return
+	}
+	ref := m.lineRefs[m.diffCursor]
+	if ref.hunk == -1 {
+		m.notice = "Move to a diff line to comment."
+		return
+	}
+	comment := review.Comment{Path: entry.Path}
+	if wholeHunk || ref.header {
+		header := m.lineRefs[ref.hunk]
+		comment.Hunk = true
+		comment.Line = header.new
+		comment.OldLine = header.old
+		comment.Snippet = m.hunkLines(ref.hunk)
+	} else {
+		line := m.diffLines[m.diffCursor]
+		comment.Line = ref.new
+		comment.OldLine = ref.old
+		if strings.HasPrefix(line, "-") {
+			comment.Line = 0
+		}
+		comment.Snippet = []string{line}
+	}
+	m.pendingComment = comment
+	m.modal = modalComment
+	m.modalErr = ""
+	m.commentText.SetValue("")
+	m.commentText.Focus()
+}
+
+// hunkLines returns the body of the hunk whose header is at index start.
+func (m Model) hunkLines(start int) []string {
+	out := []string{}
+	for i := start + 1; i < len(m.diffLines); i++ {
+		if m.lineRefs[i].header {
+			break
+		}
+		out = append(out, m.diffLines[i])
+	}
+	return out
+}
+
+// commentsAt returns the comments shown on diffLines index i.
+func (m Model) commentsAt(i int) []review.Comment {
+	entry, ok := m.selectedEntry()
+	if !ok || i < 0 || i >= len(m.lineRefs) || i >= len(m.diffLines) {
+		return nil
+	}
+	ref := m.lineRefs[i]
+	if ref.hunk == -1 {
+		return nil
+	}
+	line := m.diffLines[i]
+	out := []review.Comment{}
+	for _, comment := range m.review.CommentsFor(entry.Path) {
+		if ref.header {
+			end := ref.new + len(m.hunkLines(i))
+			if comment.Hunk && comment.Line >= ref.new && comment.Line < end {
+				out = append(out, comment)
+			}
+			continue
+		}
+		if comment.Hunk {
+			continue
+		}
+		if comment.Line != 0 && !strings.HasPrefix(line, "-") && comment.Line == ref.new {
+			out = append(out, comment)
+		} else if comment.Line == 0 && strings.HasPrefix(line, "-") && comment.OldLine == ref.old {
+			out = append(out, comment)
+		}
+	}
+	return out
+}
+
+// deleteComments removes the comments shown on the cursor line.
+func (m *Model) deleteComments() tea.Cmd {
+	comments := m.commentsAt(m.diffCursor)
+	if len(comments) == 0 {
+		return nil
+	}
+	for _, comment := range comments {
+		m.review.DeleteComment(comment.ID)
+	}
+	m.notice = fmt.Sprintf("Deleted %d comment(s).", len(comments))
+	return m.saveReview()
+}
+
+func (m Model) handleCommentKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.closeModal()
+		return m, nil
+	case "enter":
+		body := strings.TrimSpace(m.commentText.Value())
+		if body == "" {
+			m.modalErr = "Comment is required."
+			return m, nil
+		}
+		comment := m.pendingComment
+		comment.Body = body
+		m.review.AddComment(comment)
+		m.closeModal()
+		return m, m.saveReview()
+	}
+	var cmd tea.Cmd
+	m.commentText, cmd = m.commentText.Update(msg)
+	return m, cmd
+}
+
+func (m Model) renderCommentModal() []string {
+	comment := m.pendingComment
+	target := comment.Location()
+	if comment.Hunk {
+		target = "hunk at " + target
+	}
+	body := []string{fmt.Sprintf("%s, %s", comment.Path, target)}
+	snippet := comment.Snippet
+	if len(snippet) > 6 {
+		snippet = append(append([]string(nil), snippet[:6]...), "…")
+	}
+	body = append(body, colorizeDiffLines(snippet)...)
+	body = append(body, "")
+	body = append(body, m.commentText.View())
+	body = append(body, "")
+	body = append(body, "Enter to save, Esc to cancel.")
+	return body
+}
+
+// decorateComments appends the comments on each visible line.
+func (m Model) decorateComments(lines []string, offset int) []string {
+	if len(m.review.Comments) == 0 {
+		return lines
+	}
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("178"))
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		out[i] = line
+		for _, comment := range m.commentsAt(offset + i) {
+			out[i] += style.Render("  ✎ " + comment.Body)
+		}
+	}
+	return out
+}
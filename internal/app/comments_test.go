@@ -0,0 +1,131 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const commentDiff = "--- a/f.go\n+++ b/f.go\n@@ -10,3 +10,3 @@\n keep\n-old\n+new\n tail"
+
+func commentTestModel() Model {
+	m := New(Config{})
+	m.width = 120
+	m.height = 40
+	m.mode = modeDiff
+	m.focus = focusDiff
+	m.rows = []fileRow{{Path: "f.go", Name: "f.go", Status: "M"}}
+	m.setDiff(commentDiff, nil)
+	return m
+}
+
+func TestDiffLineRefs(t *testing.T) {
+	refs := diffLineRefs(strings.Split(commentDiff, "\n"))
+	want := []lineRef{
+		{hunk: -1},
+		{hunk: -1},
+		{old: 10, new: 10, hunk: 2, header: true},
+		{old: 10, new: 10, hunk: 2},
+		{old: 11, hunk: 2},
+		{new: 11, hunk: 2},
+		{old: 12, new: 12, hunk: 2},
+	}
+	for i := range want {
+		if refs[i] != want[i] {
+			t.Fatalf("line %d expected %+v, got %+v", i, want[i], refs[i])
+		}
+	}
+}
+
+func TestCommentOnLine(t *testing.T) {
+	m := commentTestModel()
+	m.moveDiff(5)
+	m.startComment(false)
+	if m.modal != modalComment {
+		t.Fatalf("expected comment modal")
+	}
+	if m.pendingComment.Line != 11 || m.pendingComment.Snippet[0] != "+new" {
+		t.Fatalf("unexpected pending comment %+v", m.pendingComment)
+	}
+	m.commentText.SetValue("rename this")
+	next, cmd := m.handleCommentKey(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(Model)
+	if cmd == nil || len(m.review.Comments) != 1 {
+		t.Fatalf("expected comment to be saved")
+	}
+	if got := m.commentsAt(5); len(got) != 1 || got[0].Body != "rename this" {
+		t.Fatalf("expected comment on +new line, got %+v", got)
+	}
+	if got := m.commentsAt(4); len(got) != 0 {
+		t.Fatalf("expected no comment on removed line, got %+v", got)
+	}
+	if !strings.Contains(m.renderDiff(100, 30), "✎ rename this") {
+		t.Fatalf("expected comment in rendered diff")
+	}
+
+	m.deleteComments()
+	if len(m.review.Comments) != 0 {
+		t.Fatalf("expected comment to be deleted")
+	}
+}
+
+func TestCommentOnRemovedLineAndHunk(t *testing.T) {
+	m := commentTestModel()
+	m.moveDiff(4)
+	m.startComment(false)
+	if m.pendingComment.Line != 0 || m.pendingComment.OldLine != 11 {
+		t.Fatalf("expected old-side comment, got %+v", m.pendingComment)
+	}
+	m.closeModal()
+
+	m.startComment(true)
+	comment := m.pendingComment
+	if !comment.Hunk || comment.Line != 10 || len(comment.Snippet) != 4 {
+		t.Fatalf("expected hunk comment, got %+v", comment)
+	}
+	comment.Body = "simplify"
+	m.review.AddComment(comment)
+	if got := m.commentsAt(2); len(got) != 1 {
+		t.Fatalf("expected hunk comment on header, got %+v", got)
+	}
+}
+
+func TestCommentNeedsDiffFocus(t *testing.T) {
+	m := commentTestModel()
+	m.focus = focusFiles
+	m.startComment(false)
+	if m.modal != modalNone || m.notice == "" {
+		t.Fatalf("expected a notice instead of the comment modal")
+	}
+}
+
+func TestReviewThatFailedToLoadIsNotSaved(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-q")
+	path := filepath.Join(repo, ".git", "wing", "review.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := commentTestModel()
+	m.config.RepoPath = repo
+	next, _ := m.Update(m.loadReviewCmd()())
+	m = next.(Model)
+	m.moveDiff(5)
+	m.startComment(false)
+	m.commentText.SetValue("keep me")
+	next, cmd := m.handleCommentKey(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(Model)
+	if cmd != nil || !strings.Contains(m.notice, "Not saving review") {
+		t.Fatalf("expected the review not to be saved, got notice %q", m.notice)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "{" {
+		t.Fatalf("expected the review file to be left alone, got %q", data)
+	}
+}
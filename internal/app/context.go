@@ -0,0 +1,171 @@
+package app
+
+import (
+	"context"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"wing/internal/git"
+)
+
+// expandStep is how many lines [ and ] reveal around a hunk per press.
+const expandStep = 10
+
+// diffContext tracks on-demand context expansion for the selected file.
+type diffContext struct {
+	expand   map[int]git.Expansion
+	fullFile bool
+	source   *contextSource
+	// hunkStarts holds the diffLines index of each rendered hunk header and
+	// hunkOrigins the parsed hunk indices each rendered hunk was built from.
+	hunkStarts  []int
+	hunkOrigins [][]int
+	hunks       []git.Hunk
+}
+
+// contextSource is the file side that expanded context lines are read from.
+type contextSource struct {
+	lines   []string
+	fromOld bool
+}
+
+func (c diffContext) active() bool {
+	return c.fullFile || len(c.expand) > 0
+}
+
+func (c *diffContext) reset() {
+	c.expand = nil
+	c.source = nil
+	c.hunkStarts = nil
+	c.hunkOrigins = nil
+	c.hunks = nil
+}
+
+// loadContextSource reads the new side of path for the current diff
+// options, falling back to the old side when the file no longer exists.
+func loadContextSource(ctx context.Context, backend git.Backend, path string, opts git.DiffOptions) *contextSource {
+	var (
+		text string
+		err  error
+	)
+	if git.IsRange(opts.Base) {
+		text, err = backend.BlobContents(ctx, git.RangeEnd(opts.Base), path)
+		if err != nil {
+			return nil
+		}
+		return &contextSource{lines: splitLines(text)}
+	}
+	text, err = backend.FileContents(ctx, path)
+	if err == nil {
+		return &contextSource{lines: splitLines(text)}
+	}
+	text, err = backend.BlobContents(ctx, opts.Base, path)
+	if err != nil {
+		return nil
+	}
+	return &contextSource{lines: splitLines(text), fromOld: true}
+}
+
+// setDiff stores the raw diff text and rebuilds the rendered lines.
+func (m *Model) setDiff(diff string, source *contextSource) {
+	m.diff = diff
+	if m.context.active() {
+		m.context.source = source
+	}
+	m.rebuildDiffLines()
+}
+
+func (m *Model) rebuildDiffLines() {
+	m.context.hunkStarts = nil
+	m.context.hunkOrigins = nil
+	m.context.hunks = nil
+	m.lineRefs = nil
+	if m.mode != modeDiff {
+		m.diffLines = append(splitLines(m.diff), m.fileView.lines()...)
+		m.diffLines = append(m.diffLines, m.layoutImages()...)
+		m.updateContentLines()
+		return
+	}
+	parsed := git.ParseDiff(m.diff)
+	if source := m.context.source; source != nil && m.context.active() {
+		expand := m.context.expand
+		if m.context.fullFile {
+			expand = make(map[int]git.Expansion, len(parsed.Hunks))
+			for i := range parsed.Hunks {
+				expand[i] = git.Expansion{Above: len(source.lines), Below: len(source.lines)}
+			}
+		}
+		parsed = parsed.Expand(source.lines, source.fromOld, expand)
+	}
+	line := len(parsed.Header)
+	for _, hunk := range parsed.Hunks {
+		m.context.hunkStarts = append(m.context.hunkStarts, line)
+		m.context.hunkOrigins = append(m.context.hunkOrigins, hunk.Origins)
+		m.context.hunks = append(m.context.hunks, hunk)
+		line += len(hunk.Lines) + 1
+	}
+	if len(parsed.Hunks) == 0 {
+		m.diffLines = splitLines(m.diff)
+	} else {
+		m.diffLines = parsed.Lines()
+	}
+	m.diffLines = append(m.fileView.pageDiffLines(m.diffLines), m.fileView.lines()...)
+	m.diffLines = append(m.diffLines, m.layoutImages()...)
+	m.lineRefs = diffLineRefs(m.diffLines)
+	m.diffCursor = clampCursor(m.diffCursor, len(m.diffLines))
+	m.updateContentLines()
+}
+
+// currentHunk returns the rendered hunk under the diff cursor.
+func (m Model) currentHunk() (int, bool) {
+	if len(m.context.hunkStarts) == 0 {
+		return 0, false
+	}
+	current := 0
+	for i, start := range m.context.hunkStarts {
+		if start > m.diffCursor {
+			break
+		}
+		current = i
+	}
+	return current, true
+}
+
+// expandHunk grows the context of the hunk under the diff cursor,
+// loading the file contents first if they are not cached yet.
+func (m *Model) expandHunk(above, below int) tea.Cmd {
+	current, ok := m.currentHunk()
+	if !ok {
+		return nil
+	}
+	origins := m.context.hunkOrigins[current]
+	if m.context.expand == nil {
+		m.context.expand = make(map[int]git.Expansion)
+	}
+	if above > 0 {
+		first := origins[0]
+		grow := m.context.expand[first]
+		grow.Above += above
+		m.context.expand[first] = grow
+	}
+	if below > 0 {
+		last := origins[len(origins)-1]
+		grow := m.context.expand[last]
+		grow.Below += below
+		m.context.expand[last] = grow
+	}
+	if m.context.source == nil {
+		return m.diffCmd()
+	}
+	m.rebuildDiffLines()
+	return nil
+}
+
+func (m *Model) toggleFullFile() tea.Cmd {
+	m.context.fullFile = !m.context.fullFile
+	if m.context.fullFile && m.context.source == nil {
+		return m.diffCmd()
+	}
+	m.rebuildDiffLines()
+	return nil
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func contextTestModel() Model {
+	m := New(Config{})
+	m.height = 40
+	m.width = 120
+	m.mode = modeDiff
+	source := make([]string, 0, 30)
+	for i := 1; i <= 30; i++ {
+		source = append(source, fmt.Sprintf("l%d", i))
+	}
+	m.context.source = &contextSource{lines: source}
+	m.setDiff("--- a/f\n+++ b/f\n@@ -10,3 +10,3 @@\n l10\n-old\n+l11\n l12", nil)
+	return m
+}
+
+func TestExpandHunkAddsContext(t *testing.T) {
+	m := contextTestModel()
+	if cmd := m.expandHunk(expandStep, 0); cmd != nil {
+		t.Fatalf("expected expansion without reload when source is cached")
+	}
+	if m.diffLines[2] != "@@ -1,12 +1,12 @@" {
+		t.Fatalf("unexpected hunk header %q", m.diffLines[2])
+	}
+	if m.diffLines[3] != " l1" {
+		t.Fatalf("expected context from line 1, got %q", m.diffLines[3])
+	}
+	m.expandHunk(0, 2)
+	if last := m.diffLines[len(m.diffLines)-1]; last != " l14" {
+		t.Fatalf("expected context below to end at l14, got %q", last)
+	}
+}
+
+func TestToggleFullFile(t *testing.T) {
+	m := contextTestModel()
+	m.toggleFullFile()
+	body := strings.Join(m.diffLines, "\n")
+	if !strings.Contains(body, " l1\n") || !strings.HasSuffix(body, " l30") {
+		t.Fatalf("expected whole file in diff, got:\n%s", body)
+	}
+	m.toggleFullFile()
+	if len(m.diffLines) != 7 {
+		t.Fatalf("expected original diff after toggling off, got %d lines", len(m.diffLines))
+	}
+}
+
+func TestMoveSelectionResetsExpansion(t *testing.T) {
+	m := contextTestModel()
+	m.rows = make([]fileRow, 3)
+	m.expandHunk(expandStep, 0)
+	m.moveSelection(1)
+	if len(m.context.expand) != 0 || m.context.source != nil {
+		t.Fatalf("expected expansion to reset on selection change")
+	}
+}
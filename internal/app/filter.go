@@ -0,0 +1,182 @@
+package app
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"wing/internal/git"
+)
+
+// filterCategories names the status categories a filter can pick.
+var filterCategories = map[string]statusCategory{
+	"modified":   categoryModified,
+	"added":      categoryAdded,
+	"deleted":    categoryDeleted,
+	"untracked":  categoryUntracked,
+	"renamed":    categoryRenamed,
+	"conflicted": categoryConflicted,
+	"ignored":    categoryIgnored,
+}
+
+// fileFilter restricts the Files rows to some status categories and to
+// paths matching some globs. A file is listed when it is in any of the
+// categories and matches any of the globs; an empty list allows all.
+type fileFilter struct {
+	categories map[statusCategory]bool
+	globs      []string
+	text       string
+}
+
+// parseFilter reads space-separated category names and path globs, such as
+// "untracked deleted *.go".
+func parseFilter(text string) (fileFilter, error) {
+	filter := fileFilter{}
+	var words []string
+	for _, word := range strings.Fields(text) {
+		if category, ok := filterCategories[strings.ToLower(word)]; ok {
+			if filter.categories == nil {
+				filter.categories = make(map[statusCategory]bool)
+			}
+			filter.categories[category] = true
+			words = append(words, strings.ToLower(word))
+			continue
+		}
+		glob := strings.TrimPrefix(word, "./")
+		if _, err := path.Match(glob, ""); err != nil {
+			return fileFilter{}, fmt.Errorf("bad glob %q", word)
+		}
+		filter.globs = append(filter.globs, glob)
+		words = append(words, glob)
+	}
+	filter.text = strings.Join(words, " ")
+	return filter, nil
+}
+
+func (f fileFilter) active() bool {
+	return f.text != ""
+}
+
+// wants reports whether the filter includes the category.
+func (f fileFilter) wants(category statusCategory) bool {
+	return f.categories[category]
+}
+
+// matches reports whether the filter lists the file of entry.
+func (f fileFilter) matches(entry git.StatusEntry) bool {
+	if len(f.categories) > 0 && !f.categories[categoryOf(entry)] {
+		return false
+	}
+	if len(f.globs) == 0 {
+		return true
+	}
+	for _, glob := range f.globs {
+		if matchGlob(glob, entry.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches glob against the file name when it has no slash, and
+// against the path or any folder above the file, so "*.go" lists Go files
+// anywhere and "docs" and "internal/*" everything below them.
+func matchGlob(glob, file string) bool {
+	if !strings.Contains(glob, "/") {
+		if ok, _ := path.Match(glob, path.Base(file)); ok {
+			return true
+		}
+	}
+	for dir := file; dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if ok, _ := path.Match(glob, dir); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Model) openFilterModal() {
+	m.modal = modalFilter
+	m.modalErr = ""
+	m.filterText.SetValue(m.filter.text)
+	m.filterText.CursorEnd()
+	m.filterText.Focus()
+}
+
+func (m Model) handleFilterKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.closeModal()
+		return m, nil
+	case "enter":
+		filter, err := parseFilter(m.filterText.Value())
+		if err != nil {
+			m.modalErr = err.Error()
+			return m, nil
+		}
+		m.closeModal()
+		return m, m.applyFilter(filter)
+	}
+	var cmd tea.Cmd
+	m.filterText, cmd = m.filterText.Update(msg)
+	return m, cmd
+}
+
+// applyFilter lists only the files filter matches, listing ignored files
+// first when it asks for them and hiding them again once it stops asking,
+// unless they were already shown.
+func (m *Model) applyFilter(filter fileFilter) tea.Cmd {
+	m.filter = filter
+	if !filter.active() {
+		m.notice = "Showing all files."
+	} else {
+		m.notice = "Filtering files: " + filter.text + "."
+	}
+	m.refreshRowsKeepingIndex()
+	if filter.wants(categoryIgnored) && !m.showIgnored {
+		m.showIgnored = true
+		m.filterIgnored = true
+		return m.refreshCmd()
+	}
+	if !filter.wants(categoryIgnored) && m.filterIgnored {
+		m.showIgnored = false
+		m.filterIgnored = false
+		return m.refreshCmd()
+	}
+	return m.diffCmd()
+}
+
+// filteredOut reports whether the filter hides the file at path.
+func (m Model) filteredOut(path string) bool {
+	node, ok := m.tree.files[path]
+	return ok && !m.filter.matches(node.entry)
+}
+
+func (m Model) renderFilterModal() []string {
+	return []string{
+		"Show only files in some status categories or matching path globs:",
+		"  " + strings.Join(filterCategoryNames(), " "),
+		"A glob with a slash matches the path or a folder above the file.",
+		m.filterText.View(),
+		"",
+		"Enter to apply, empty to show all, Esc to cancel.",
+	}
+}
+
+// filterCategoryNames lists the category names in the order rows sort by
+// status.
+func filterCategoryNames() []string {
+	byCategory := make(map[statusCategory]string, len(filterCategories))
+	for name, category := range filterCategories {
+		byCategory[category] = name
+	}
+	var names []string
+	for category := categoryConflicted; category <= categoryIgnored; category++ {
+		if name, ok := byCategory[category]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"wing/internal/git"
+)
+
+func TestParseFilter(t *testing.T) {
+	filter, err := parseFilter("  Untracked deleted ./internal/*  *.go ")
+	if err != nil {
+		t.Fatalf("parseFilter error: %v", err)
+	}
+	if filter.text != "untracked deleted internal/* *.go" {
+		t.Fatalf("unexpected filter text %q", filter.text)
+	}
+	if !filter.wants(categoryUntracked) || !filter.wants(categoryDeleted) || filter.wants(categoryModified) {
+		t.Fatalf("unexpected categories %v", filter.categories)
+	}
+	if _, err := parseFilter("[oops"); err == nil {
+		t.Fatalf("expected a bad glob to be rejected")
+	}
+	if filter, _ := parseFilter("   "); filter.active() {
+		t.Fatalf("expected a blank filter to show everything")
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	cases := []struct {
+		filter string
+		entry  git.StatusEntry
+		want   bool
+	}{
+		{"untracked", git.StatusEntry{Path: "notes.md", Status: "??"}, true},
+		{"untracked", git.StatusEntry{Path: "main.go", Status: "M"}, false},
+		{"modified deleted", git.StatusEntry{Path: "old.go", Status: " D"}, true},
+		{"*.go", git.StatusEntry{Path: "internal/app/app.go", Status: "M"}, true},
+		{"*.go", git.StatusEntry{Path: "README.md", Status: "M"}, false},
+		{"internal/*", git.StatusEntry{Path: "internal/app/app.go", Status: "M"}, true},
+		{"docs", git.StatusEntry{Path: "docs/guide/intro.md"}, true},
+		{"cmd/*.go", git.StatusEntry{Path: "internal/cmd/main.go"}, false},
+		{"modified *.md", git.StatusEntry{Path: "notes.md", Status: "??"}, false},
+		{"ignored", git.StatusEntry{Path: "wing.log", Ignored: true}, true},
+	}
+	for _, c := range cases {
+		filter, err := parseFilter(c.filter)
+		if err != nil {
+			t.Fatalf("parseFilter(%q) error: %v", c.filter, err)
+		}
+		if got := filter.matches(c.entry); got != c.want {
+			t.Errorf("%q matches %+v = %v, want %v", c.filter, c.entry, got, c.want)
+		}
+	}
+}
+
+func TestFilterBarRestrictsRows(t *testing.T) {
+	h := newHarness(t, fakeRepo(), 100, 20)
+	h.press("m", "/", "untracked", "enter")
+	expectPaths(t, h.model.rows, "notes.md")
+	if view := h.model.View(); !strings.Contains(view, "Files · untracked") {
+		t.Fatalf("expected the filter in the Files title, got:\n%s", view)
+	}
+
+	h.press("/", "nothing.txt", "enter")
+	if view := h.model.View(); !strings.Contains(view, "No files match the filter.") {
+		t.Fatalf("expected an empty filtered list, got:\n%s", view)
+	}
+
+	h.press("/", "[", "enter")
+	if h.model.modal != modalFilter || !strings.Contains(h.model.modalErr, "bad glob") {
+		t.Fatalf("expected a bad glob to keep the filter bar open, got %q", h.model.modalErr)
+	}
+	h.press("esc")
+
+	h.press("/")
+	h.model.filterText.SetValue("")
+	h.press("enter")
+	if h.model.filter.active() || len(h.model.rows) == 0 {
+		t.Fatalf("expected clearing the filter to list every file again, got %q", rowPaths(h.model.rows))
+	}
+}
+
+func TestIgnoredFilterListsIgnoredFiles(t *testing.T) {
+	h := newHarness(t, fakeRepo(), 100, 20)
+	h.press("/", "ignored", "enter")
+	if !h.model.showIgnored {
+		t.Fatalf("expected filtering ignored files to list them")
+	}
+	expectPaths(t, h.model.rows, "wing.log")
+
+	h.press("/")
+	h.model.filterText.SetValue("")
+	h.press("enter")
+	if h.model.showIgnored {
+		t.Fatalf("expected clearing the filter to hide ignored files again")
+	}
+
+	h.press("i", "/", "ignored", "enter")
+	h.press("/")
+	h.model.filterText.SetValue("untracked")
+	h.press("enter")
+	if !h.model.showIgnored {
+		t.Fatalf("expected ignored files shown before the filter to stay shown")
+	}
+}
+
+func TestExplorerKeepsConflicts(t *testing.T) {
+	repo := fakeRepo()
+	repo.Entries = append(repo.Entries, git.StatusEntry{Path: "go.mod", Status: "UU", Conflicted: true})
+	h := newHarness(t, repo, 100, 20)
+	if h.model.mode != modeExplorer {
+		t.Fatalf("expected to start in explorer mode")
+	}
+	h.press("/", "conflicted", "enter")
+	expectPaths(t, h.model.rows, "go.mod")
+
+	h.press("/")
+	h.model.filterText.SetValue("")
+	h.press("enter", "T", "s")
+	if h.model.sortMode != sortStatus || h.model.rows[0].Path != "go.mod" {
+		t.Fatalf("expected the conflict first when sorting by status, got %q", rowPaths(h.model.rows))
+	}
+}
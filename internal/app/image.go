@@ -0,0 +1,193 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"wing/internal/git"
+	"wing/internal/graphics"
+)
+
+const (
+	// maxImageBytes bounds how much of a picture is read to preview it.
+	maxImageBytes = 16 << 20
+	// maxImageRows bounds how tall a preview is drawn.
+	maxImageRows = 24
+	// imageGap separates the old and new picture.
+	imageGap = 3
+	// imageSendWindow is how long the pictures are transmitted with the
+	// rows once shown, long enough for a frame to carry them out.
+	imageSendWindow = 500 * time.Millisecond
+)
+
+// imagePreview is the old and new picture laid out for the pane. Its rows
+// are drawn over the blank lines reserved for them at the end of the
+// content.
+type imagePreview struct {
+	old, new *graphics.Image
+	width    int
+	height   int
+	setup    string
+	rows     []string
+	// shownAt is when a row was first drawn. The terminal keeps the
+	// transmitted pictures, so setup is only drawn for a short while after
+	// and later repaints of the row send just the placeholders.
+	shownAt time.Time
+}
+
+func isImageKind(kind string) bool {
+	return strings.HasPrefix(kind, "image/")
+}
+
+// decodeImage decodes a picture read in part, reading it whole first when
+// it is small enough. It returns nil for anything it cannot show.
+func decodeImage(data git.FileData, read func(limit int64) (git.FileData, error)) *graphics.Image {
+	if !isImageKind(detectKind(data.Data)) {
+		return nil
+	}
+	if data.Truncated {
+		if data.Size > maxImageBytes {
+			return nil
+		}
+		var err error
+		if data, err = read(maxImageBytes); err != nil {
+			return nil
+		}
+	}
+	img, err := graphics.Decode(data.Data)
+	if err != nil {
+		return nil
+	}
+	return img
+}
+
+func imageSize(img *graphics.Image) string {
+	return fmt.Sprintf("%d×%d", img.Width, img.Height)
+}
+
+// dimensionChange renders the dimensions of a changed picture.
+func dimensionChange(old, new *graphics.Image) string {
+	switch {
+	case old == nil:
+		return imageSize(new)
+	case new == nil:
+		return imageSize(old)
+	case old.Width == new.Width && old.Height == new.Height:
+		return imageSize(new) + " (unchanged)"
+	}
+	return imageSize(old) + " → " + imageSize(new)
+}
+
+// layoutImages lays out the pictures of the shown file side by side for
+// the pane, returning their captions followed by a blank line for every
+// row drawImages fills in.
+func (m *Model) layoutImages() []string {
+	meta := m.fileView.meta
+	if meta == nil || m.fileView.hex || (meta.oldImage == nil && meta.newImage == nil) {
+		m.fileView.preview = nil
+		return nil
+	}
+	width := m.diffContentWidth()
+	height := min(maxImageRows, max(m.diffVisibleHeight()-2, 1))
+	preview := m.fileView.preview
+	if preview == nil || preview.old != meta.oldImage || preview.new != meta.newImage ||
+		preview.width != width || preview.height != height {
+		preview = &imagePreview{old: meta.oldImage, new: meta.newImage, width: width, height: height}
+		preview.render(m.config.Graphics)
+		m.fileView.preview = preview
+	}
+	var captions []string
+	switch {
+	case meta.oldImage == nil:
+		captions = []string{"added " + imageSize(meta.newImage)}
+	case meta.newImage == nil:
+		captions = []string{"deleted " + imageSize(meta.oldImage)}
+	default:
+		captions = []string{"old " + imageSize(meta.oldImage), "new " + imageSize(meta.newImage)}
+	}
+	lines := []string{"", joinColumns(captions, preview.columnWidth())}
+	for range preview.rows {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+// columnWidth is the cells given to each picture.
+func (p *imagePreview) columnWidth() int {
+	if p.old != nil && p.new != nil {
+		return max((p.width-imageGap)/2, 1)
+	}
+	return p.width
+}
+
+// render draws both pictures, each fitted to its column, into shared rows.
+func (p *imagePreview) render(protocol graphics.Protocol) {
+	column := p.columnWidth()
+	var pictures []graphics.Picture
+	height := 0
+	for _, img := range []*graphics.Image{p.old, p.new} {
+		if img == nil {
+			continue
+		}
+		cols, rows := img.Fit(column, p.height)
+		picture := img.Render(protocol, cols, rows)
+		p.setup += picture.Setup
+		height = max(height, len(picture.Rows))
+		pictures = append(pictures, picture)
+	}
+	p.rows = make([]string, height)
+	for row := range p.rows {
+		cells := make([]string, len(pictures))
+		for i, picture := range pictures {
+			if row < len(picture.Rows) {
+				cells[i] = picture.Rows[row]
+			}
+		}
+		p.rows[row] = joinColumns(cells, column)
+	}
+}
+
+// joinColumns pads each cell to width and joins them with the image gap.
+func joinColumns(cells []string, width int) string {
+	padded := make([]string, len(cells))
+	for i, cell := range cells {
+		padded[i] = cell + strings.Repeat(" ", max(width-lipgloss.Width(cell), 0))
+	}
+	return strings.TrimRight(strings.Join(padded, strings.Repeat(" ", imageGap)), " ")
+}
+
+// drawImages fills the lines reserved for the image preview with its rows,
+// transmitting the pictures along with the first row shown while they are
+// new to the terminal.
+func (m Model) drawImages(lines []string, offset int) []string {
+	preview := m.fileView.preview
+	if preview == nil {
+		return lines
+	}
+	start := len(m.contentLines) - len(preview.rows)
+	now := time.Now()
+	sent := false
+	// lines may share its array with the content lines.
+	out := append([]string(nil), lines...)
+	for i := range out {
+		row := offset + i - start
+		if row < 0 || row >= len(preview.rows) {
+			continue
+		}
+		out[i] = preview.rows[row]
+		if sent {
+			continue
+		}
+		sent = true
+		if preview.shownAt.IsZero() {
+			preview.shownAt = now
+		}
+		if now.Sub(preview.shownAt) < imageSendWindow {
+			out[i] = preview.setup + out[i]
+		}
+	}
+	return out
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"wing/internal/git"
+	"wing/internal/git/gittest"
+	"wing/internal/graphics"
+)
+
+func encodePNG(t *testing.T, width, height int, fill color.RGBA) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, fill)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return buf.String()
+}
+
+func imageRepo(t *testing.T) *gittest.Fake {
+	return &gittest.Fake{
+		Info:    git.BranchInfo{Head: "main"},
+		Entries: []git.StatusEntry{{Path: "assets/icon.png", Status: "M"}},
+		Tracked: []string{"assets/icon.png"},
+		Diffs: map[string]string{"assets/icon.png": strings.Join([]string{
+			"diff --git a/assets/icon.png b/assets/icon.png",
+			"Binary files a/assets/icon.png and b/assets/icon.png differ",
+		}, "\n")},
+		Blobs: map[string]string{":assets/icon.png": encodePNG(t, 16, 8, color.RGBA{0xff, 0, 0, 0xff})},
+		Files: map[string]string{"assets/icon.png": encodePNG(t, 32, 32, color.RGBA{0, 0, 0xff, 0xff})},
+	}
+}
+
+func TestViewImageDiff(t *testing.T) {
+	h := newHarness(t, imageRepo(t), 100, 30)
+	h.press("m")
+	if !contentContains(h.model, "Dimensions: 16×8 → 32×32") {
+		t.Fatalf("expected the dimensions, got %q", h.model.diffLines)
+	}
+	h.expectGolden("diff_image")
+}
+
+func TestImagePreviewFollowsPaneWidth(t *testing.T) {
+	h := newHarness(t, imageRepo(t), 100, 30)
+	h.press("m")
+	wide := len(h.model.fileView.preview.rows)
+	h.resize(60, 30)
+	if h.model.fileView.preview.width != h.model.diffContentWidth() {
+		t.Fatalf("expected the preview to be laid out again for the new width")
+	}
+	if narrow := len(h.model.fileView.preview.rows); narrow >= wide {
+		t.Fatalf("expected a narrower pane to draw shorter pictures, got %d then %d rows", wide, narrow)
+	}
+	h.press("X")
+	if h.model.fileView.preview != nil || strings.Contains(h.model.View(), "▀") {
+		t.Fatalf("expected the hex preview to replace the pictures")
+	}
+}
+
+func TestImagePreviewWithKitty(t *testing.T) {
+	h := newHarness(t, imageRepo(t), 100, 30)
+	h.model.config.Graphics = graphics.Kitty
+	h.press("m")
+	view := h.model.View()
+	if strings.Count(view, "\x1b_Ga=T") != 2 {
+		t.Fatalf("expected both pictures to be transmitted once")
+	}
+	if !strings.Contains(view, "\U0010EEEE") {
+		t.Fatalf("expected placeholder cells in the view")
+	}
+}
+
+func TestExplorerPreviewsImages(t *testing.T) {
+	h := newHarness(t, imageRepo(t), 100, 30)
+	h.press("space", "down")
+	if !contentContains(h.model, "Dimensions: 32×32") || !contentContains(h.model, "added 32×32") {
+		t.Fatalf("expected the worktree picture, got %q", h.model.diffLines)
+	}
+}
+
+func TestKittyPicturesAreTransmittedOnlyWhileNew(t *testing.T) {
+	h := newHarness(t, imageRepo(t), 100, 30)
+	h.model.config.Graphics = graphics.Kitty
+	h.press("m")
+	if !strings.Contains(h.model.View(), "\x1b_Ga=T") {
+		t.Fatalf("expected the pictures transmitted when first shown")
+	}
+	h.model.fileView.preview.shownAt = time.Now().Add(-imageSendWindow)
+	view := h.model.View()
+	if strings.Contains(view, "\x1b_G") || !strings.Contains(view, "\U0010EEEE") {
+		t.Fatalf("expected later repaints to draw only the placeholders")
+	}
+}
@@ -0,0 +1,302 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"wing/internal/git"
+	"wing/internal/graphics"
+)
+
+const (
+	// textPageBytes is how much of a file explorer mode reads per page.
+	textPageBytes = 256 << 10
+	// diffPageLines is how many diff lines are rendered per page.
+	diffPageLines = 5000
+	// hexPageBytes is how much of a binary file the hex preview shows per
+	// page.
+	hexPageBytes = 4 << 10
+)
+
+// fileMeta describes a file that is binary or was only partly loaded.
+type fileMeta struct {
+	binary    bool
+	truncated bool
+	// kind is the detected MIME type, e.g. "image/png".
+	kind string
+	// size and oldSize are the full sizes of the new and old side, -1 when
+	// that side does not exist. A file shown on its own, outside a diff,
+	// has no old side and compared unset.
+	size     int64
+	oldSize  int64
+	compared bool
+	// shown is how many bytes of text were loaded.
+	shown int64
+	// data is the start of the file for the hex preview: the new side, or
+	// the old one when the file was deleted.
+	data []byte
+	// oldImage and newImage are the decoded sides of a picture.
+	oldImage *graphics.Image
+	newImage *graphics.Image
+}
+
+// fileView is the paging and preview state of the shown file. It is reset
+// whenever another file is shown.
+type fileView struct {
+	path    string
+	pages   int
+	hex     bool
+	meta    *fileMeta
+	preview *imagePreview
+}
+
+// pagesFor returns how many pages to load for path.
+func (v fileView) pagesFor(path string) int {
+	if path == v.path && v.pages > 1 {
+		return v.pages
+	}
+	return 1
+}
+
+// show records what is known about the file at path once it has loaded.
+func (v *fileView) show(path string, meta *fileMeta) {
+	if path != v.path {
+		*v = fileView{path: path, pages: 1}
+	}
+	v.meta = meta
+}
+
+// fileText turns the start of a worktree file into the text to show, with
+// metadata when the file is binary or did not fit. A binary file keeps only
+// the pages of its hex preview.
+func fileText(data git.FileData, pages int) (string, *fileMeta) {
+	if data.Binary {
+		preview := data.Data[:min(len(data.Data), pages*hexPageBytes)]
+		return "", &fileMeta{
+			binary:    true,
+			truncated: int64(len(preview)) < data.Size,
+			kind:      detectKind(data.Data),
+			size:      data.Size,
+			oldSize:   -1,
+			data:      bytes.Clone(preview),
+		}
+	}
+	if !data.Truncated {
+		return string(data.Data), nil
+	}
+	// Drop the partial last line so a page never ends mid-line.
+	text := data.Data
+	if cut := bytes.LastIndexByte(text, '\n'); cut != -1 {
+		text = text[:cut+1]
+	}
+	return string(text), &fileMeta{truncated: true, size: data.Size, oldSize: -1, shown: int64(len(text))}
+}
+
+// detectKind sniffs the MIME type of data without its parameters.
+func detectKind(data []byte) string {
+	kind, _, err := mime.ParseMediaType(http.DetectContentType(data))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return kind
+}
+
+// isBinaryDiff reports whether git left out the content of a binary file.
+func isBinaryDiff(diff string) bool {
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			return false
+		}
+		if line == "GIT binary patch" || (strings.HasPrefix(line, "Binary files ") && strings.HasSuffix(line, " differ")) {
+			return true
+		}
+	}
+	return false
+}
+
+// diffSides returns the revisions the diff of entry against base compares:
+// "" for the index on the old side, and newRev "" with worktree true when the
+// new side is the working tree. A staged rename is diffed against HEAD, the
+// only place its old path is left.
+func diffSides(entry git.StatusEntry, base string) (oldRev, newRev string, worktree bool) {
+	if !git.IsRange(base) {
+		if base == "" && entry.OrigPath != "" {
+			return "HEAD", "", true
+		}
+		if base == "" {
+			return "", "", true
+		}
+		return base, "", true
+	}
+	start := base[:strings.Index(base, "..")]
+	if start == "" {
+		start = "HEAD"
+	}
+	return start, git.RangeEnd(base), false
+}
+
+// binaryMeta reads both sides of a binary file to describe the change. A
+// side that cannot be read is taken to be missing.
+func (m Model) binaryMeta(ctx context.Context, entry git.StatusEntry, opts git.DiffOptions, limit int64) *fileMeta {
+	oldRev, newRev, worktree := diffSides(entry, opts.Base)
+	meta := &fileMeta{binary: true, size: -1, oldSize: -1, compared: true}
+	oldPath := entry.Path
+	if entry.OrigPath != "" {
+		oldPath = entry.OrigPath
+	}
+	readOld := func(limit int64) (git.FileData, error) {
+		return m.config.Backend.ReadBlob(ctx, oldRev, oldPath, limit)
+	}
+	readNew := func(limit int64) (git.FileData, error) {
+		if worktree {
+			return m.config.Backend.ReadFile(ctx, entry.Path, limit)
+		}
+		return m.config.Backend.ReadBlob(ctx, newRev, entry.Path, limit)
+	}
+	old, err := readOld(limit)
+	if err == nil {
+		meta.oldSize = old.Size
+		meta.oldImage = decodeImage(old, readOld)
+	}
+	data, err := readNew(limit)
+	if err == nil {
+		meta.size = data.Size
+		meta.newImage = decodeImage(data, readNew)
+	} else {
+		data = old
+	}
+	meta.data = data.Data
+	meta.truncated = int64(len(data.Data)) < data.Size
+	meta.kind = detectKind(data.Data)
+	return meta
+}
+
+// lines renders the metadata shown below the file or diff.
+func (v fileView) lines() []string {
+	meta := v.meta
+	if meta == nil {
+		return nil
+	}
+	if !meta.binary {
+		if !meta.truncated {
+			return nil
+		}
+		return []string{"", fmt.Sprintf("… showing %s of %s · L to load more", formatSize(meta.shown), formatSize(meta.size))}
+	}
+	size := formatSize(meta.size)
+	if meta.compared {
+		size = sizeChange(meta.oldSize, meta.size)
+	}
+	lines := []string{"", "Binary file, " + meta.kind, "Size: " + size}
+	if meta.oldImage != nil || meta.newImage != nil {
+		lines = append(lines, "Dimensions: "+dimensionChange(meta.oldImage, meta.newImage))
+	}
+	if !v.hex {
+		return append(lines, "X to show a hex preview")
+	}
+	lines = append(lines, "")
+	lines = append(lines, splitLines(strings.TrimSuffix(hex.Dump(meta.data), "\n"))...)
+	if meta.truncated {
+		total := meta.size
+		if total < 0 {
+			total = meta.oldSize
+		}
+		lines = append(lines, fmt.Sprintf("… showing %s of %s · L to load more", formatSize(int64(len(meta.data))), formatSize(total)))
+	}
+	return lines
+}
+
+// pageDiffLines keeps the pages of diff lines loaded so far, with a footer
+// counting the rest.
+func (v fileView) pageDiffLines(lines []string) []string {
+	limit := v.pages * diffPageLines
+	if limit < diffPageLines {
+		limit = diffPageLines
+	}
+	if len(lines) <= limit {
+		return lines
+	}
+	rest := len(lines) - limit
+	return append(lines[:limit:limit], "", fmt.Sprintf("… %d more lines · L to load more", rest))
+}
+
+// moreDiffLines reports whether the diff has lines past the loaded pages.
+func (m Model) moreDiffLines() bool {
+	return m.mode == modeDiff && len(splitLines(m.diff)) > m.fileView.pagesFor(m.fileView.path)*diffPageLines
+}
+
+// loadMore shows the next page of the selected file.
+func (m *Model) loadMore() tea.Cmd {
+	entry, ok := m.selectedEntry()
+	if !ok || entry.Path != m.fileView.path {
+		return nil
+	}
+	meta := m.fileView.meta
+	more := m.moreDiffLines()
+	if !more && (meta == nil || !meta.truncated) {
+		m.notice = "The whole file is shown."
+		return nil
+	}
+	m.fileView.pages = m.fileView.pagesFor(entry.Path) + 1
+	if more && (meta == nil || !meta.binary) {
+		m.rebuildDiffLines()
+		return nil
+	}
+	return m.diffCmd()
+}
+
+// toggleHex shows or hides the hex preview of a binary file.
+func (m *Model) toggleHex() {
+	if m.fileView.meta == nil || !m.fileView.meta.binary {
+		m.notice = "Hex preview is only for binary files."
+		return
+	}
+	m.fileView.hex = !m.fileView.hex
+	m.rebuildDiffLines()
+}
+
+// formatSize renders a byte count for people.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size) / unit
+	for _, suffix := range []string{"KiB", "MiB", "GiB"} {
+		if value < unit || suffix == "GiB" {
+			if value == float64(int64(value)) {
+				return fmt.Sprintf("%d %s", int64(value), suffix)
+			}
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+		value /= unit
+	}
+	return ""
+}
+
+// sizeChange renders the size of a change, either side being -1 when it
+// does not exist.
+func sizeChange(oldSize, newSize int64) string {
+	switch {
+	case oldSize < 0 && newSize < 0:
+		return "unknown"
+	case oldSize < 0:
+		return formatSize(newSize) + " (added)"
+	case newSize < 0:
+		return formatSize(oldSize) + " (deleted)"
+	}
+	delta := newSize - oldSize
+	sign := "+"
+	if delta < 0 {
+		sign = "-"
+		delta = -delta
+	}
+	return fmt.Sprintf("%s → %s (%s%s)", formatSize(oldSize), formatSize(newSize), sign, formatSize(delta))
+}
@@ -0,0 +1,151 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"wing/internal/git"
+	"wing/internal/git/gittest"
+)
+
+var pngHeader = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+func contentContains(m Model, text string) bool {
+	return strings.Contains(strings.Join(m.diffLines, "\n"), text)
+}
+
+func TestExplorerShowsBinaryMetadataAndHex(t *testing.T) {
+	fake := &gittest.Fake{
+		Info:    git.BranchInfo{Head: "main"},
+		Tracked: []string{"logo.png"},
+		Files:   map[string]string{"logo.png": pngHeader + strings.Repeat("\x00", 2032)},
+	}
+	h := newHarness(t, fake, 80, 16)
+	if !contentContains(h.model, "Binary file, image/png") || !contentContains(h.model, "Size: 2 KiB\n") {
+		t.Fatalf("expected binary metadata, got %q", h.model.diffLines)
+	}
+	if contentContains(h.model, "IHDR") {
+		t.Fatalf("expected no hex dump before X")
+	}
+	h.press("X")
+	if !contentContains(h.model, "|.PNG........IHDR|") {
+		t.Fatalf("expected a hex dump after X, got %q", h.model.diffLines)
+	}
+	h.press("X")
+	if contentContains(h.model, "IHDR") {
+		t.Fatalf("expected X to hide the hex dump again")
+	}
+}
+
+func TestExplorerLoadsLargeFilesInPages(t *testing.T) {
+	line := strings.Repeat("x", 1023) + "\n"
+	fake := &gittest.Fake{
+		Info:    git.BranchInfo{Head: "main"},
+		Tracked: []string{"big.log"},
+		Files:   map[string]string{"big.log": strings.Repeat(line, 300)},
+	}
+	h := newHarness(t, fake, 80, 16)
+	if len(h.model.diffLines) != 258 || !contentContains(h.model, "… showing 256 KiB of 300 KiB · L to load more") {
+		t.Fatalf("expected the first 256 lines and a footer, got %d lines", len(h.model.diffLines))
+	}
+	h.press("L")
+	if len(h.model.diffLines) != 300 || contentContains(h.model, "load more") {
+		t.Fatalf("expected the whole file after L, got %d lines", len(h.model.diffLines))
+	}
+	h.press("L")
+	if h.model.notice != "The whole file is shown." {
+		t.Fatalf("expected a notice once everything is shown, got %q", h.model.notice)
+	}
+}
+
+func TestDiffRendersLongDiffsInPages(t *testing.T) {
+	lines := []string{"diff --git a/gen.go b/gen.go", "--- a/gen.go", "+++ b/gen.go", "@@ -0,0 +1,6000 @@"}
+	for i := 0; i < 6000; i++ {
+		lines = append(lines, fmt.Sprintf("+line %d", i))
+	}
+	fake := &gittest.Fake{
+		Info:    git.BranchInfo{Head: "main"},
+		Entries: []git.StatusEntry{{Path: "gen.go", Status: "M"}},
+		Diffs:   map[string]string{"gen.go": strings.Join(lines, "\n")},
+	}
+	h := newHarness(t, fake, 80, 16)
+	h.press("m")
+	if len(h.model.diffLines) != diffPageLines+2 || !contentContains(h.model, "… 1004 more lines · L to load more") {
+		t.Fatalf("expected one page of diff lines, got %d", len(h.model.diffLines))
+	}
+	h.press("L")
+	if len(h.model.diffLines) != len(lines) {
+		t.Fatalf("expected the whole diff after L, got %d lines", len(h.model.diffLines))
+	}
+}
+
+func TestDiffDescribesBinaryChanges(t *testing.T) {
+	fake := &gittest.Fake{
+		Info:    git.BranchInfo{Head: "main"},
+		Entries: []git.StatusEntry{{Path: "logo.png", Status: "M"}},
+		Diffs: map[string]string{"logo.png": strings.Join([]string{
+			"diff --git a/logo.png b/logo.png",
+			"Binary files a/logo.png and b/logo.png differ",
+		}, "\n")},
+		Blobs: map[string]string{":logo.png": pngHeader + strings.Repeat("\x00", 1008)},
+		Files: map[string]string{"logo.png": pngHeader + strings.Repeat("\x00", 3056)},
+	}
+	h := newHarness(t, fake, 80, 16)
+	h.press("m")
+	if !contentContains(h.model, "Binary file, image/png") || !contentContains(h.model, "Size: 1 KiB → 3 KiB (+2 KiB)") {
+		t.Fatalf("expected binary metadata, got %q", h.model.diffLines)
+	}
+}
+
+func TestExplorerPagesTheHexOfLargeBinaries(t *testing.T) {
+	fake := &gittest.Fake{
+		Info:    git.BranchInfo{Head: "main"},
+		Tracked: []string{"dump.bin"},
+		Files:   map[string]string{"dump.bin": "\x00" + strings.Repeat("\x01", 1<<20-1)},
+	}
+	h := newHarness(t, fake, 80, 16)
+	h.press("X")
+	dump := hexPageBytes / 16
+	if len(h.model.diffLines) > dump+8 || !contentContains(h.model, "… showing 4 KiB of 1 MiB · L to load more") {
+		t.Fatalf("expected one page of hex, got %d lines", len(h.model.diffLines))
+	}
+	h.press("L")
+	if !contentContains(h.model, "… showing 8 KiB of 1 MiB") {
+		t.Fatalf("expected L to load another page, notice %q", h.model.notice)
+	}
+}
+
+func TestDiffReadsRenamedBinariesFromHead(t *testing.T) {
+	fake := &gittest.Fake{
+		Info:    git.BranchInfo{Head: "main"},
+		Entries: []git.StatusEntry{{Path: "new.png", OrigPath: "old.png", Status: "R"}},
+		Diffs: map[string]string{"new.png": strings.Join([]string{
+			"diff --git a/old.png b/new.png",
+			"Binary files a/old.png and b/new.png differ",
+		}, "\n")},
+		Blobs: map[string]string{"HEAD:old.png": pngHeader + strings.Repeat("\x00", 1008)},
+		Files: map[string]string{"new.png": pngHeader + strings.Repeat("\x00", 2032)},
+	}
+	h := newHarness(t, fake, 80, 16)
+	h.press("m")
+	if !contentContains(h.model, "Size: 1 KiB → 2 KiB (+1 KiB)") {
+		t.Fatalf("expected the old side read from HEAD, got %q", h.model.diffLines)
+	}
+}
+
+func TestSizeChange(t *testing.T) {
+	cases := []struct {
+		old, new int64
+		want     string
+	}{
+		{-1, 10, "10 B (added)"},
+		{1536, -1, "1.5 KiB (deleted)"},
+		{3 << 20, 1 << 20, "3 MiB → 1 MiB (-2 MiB)"},
+	}
+	for _, c := range cases {
+		if got := sizeChange(c.old, c.new); got != c.want {
+			t.Errorf("sizeChange(%d, %d) = %q, want %q", c.old, c.new, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package app
+
+import "context"
+
+// loadTracker numbers diff and refresh requests so a response that arrives
+// after a newer request was issued is dropped instead of overwriting the
+// pane, and cancels the git processes of the request it supersedes.
+//
+// Commands are built from value receivers, so the tracker lives behind a
+// pointer shared by every copy of the Model; it is only touched from
+// Update and the command constructors, never from the commands themselves.
+type loadTracker struct {
+	refresh       uint64
+	diff          uint64
+	refreshing    bool
+	cancelRefresh context.CancelFunc
+	cancelDiff    context.CancelFunc
+}
+
+// startRefresh supersedes any refresh in flight.
+func (l *loadTracker) startRefresh() (uint64, context.Context) {
+	if l.cancelRefresh != nil {
+		l.cancelRefresh()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	l.refresh++
+	l.refreshing = true
+	l.cancelRefresh = cancel
+	return l.refresh, ctx
+}
+
+// startDiff supersedes any diff in flight, including the one loaded by a
+// refresh.
+func (l *loadTracker) startDiff() (uint64, context.Context) {
+	if l.cancelDiff != nil {
+		l.cancelDiff()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	l.diff++
+	l.cancelDiff = cancel
+	return l.diff, ctx
+}
+
+// finishRefresh reports whether a refresh response is the latest one.
+func (l *loadTracker) finishRefresh(gen uint64) bool {
+	if gen != l.refresh {
+		return false
+	}
+	l.refreshing = false
+	return true
+}
+
+func (l *loadTracker) currentDiff(gen uint64) bool {
+	return gen == l.diff
+}
+
+func (l *loadTracker) cancel() {
+	if l.cancelRefresh != nil {
+		l.cancelRefresh()
+	}
+	if l.cancelDiff != nil {
+		l.cancelDiff()
+	}
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"wing/internal/git"
+	"wing/internal/git/gittest"
+)
+
+func loadsTestHarness(t *testing.T) (*harness, *gittest.Fake) {
+	t.Helper()
+	fake := &gittest.Fake{
+		Info: git.BranchInfo{Head: "main"},
+		Entries: []git.StatusEntry{
+			{Path: "a.go", Status: "M"},
+			{Path: "b.go", Status: "M"},
+			{Path: "c.go", Status: "M"},
+		},
+		Diffs: map[string]string{"a.go": "+a", "b.go": "+b", "c.go": "+c"},
+	}
+	h := newHarness(t, fake, 80, 16)
+	h.press("m")
+	return h, fake
+}
+
+// step moves the selection and returns the diff load it issues without
+// running it.
+func step(h *harness, delta int) tea.Cmd {
+	h.model.moveSelection(delta)
+	return h.model.diffCmd()
+}
+
+func TestOutOfOrderDiffsKeepLatestSelection(t *testing.T) {
+	h, _ := loadsTestHarness(t)
+	toB := step(h, 1)
+	toC := step(h, 1)
+
+	h.send(toC())
+	h.send(toB())
+	if h.model.diff != "+c" {
+		t.Fatalf("expected the late b.go response to be dropped, got %q", h.model.diff)
+	}
+}
+
+func TestSupersededDiffIsCancelled(t *testing.T) {
+	h, fake := loadsTestHarness(t)
+	fake.Update(func(f *gittest.Fake) {
+		f.Wait = map[string]chan struct{}{"b.go": make(chan struct{})}
+	})
+	toB := step(h, 1)
+	done := make(chan tea.Msg, 1)
+	go func() { done <- toB() }()
+	toC := step(h, 1)
+
+	select {
+	case msg := <-done:
+		if err := msg.(diffMsg).err; !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected the b.go load to be cancelled, got %v", err)
+		}
+		h.send(msg)
+	case <-time.After(time.Second):
+		t.Fatalf("expected the b.go load to stop once c.go was selected")
+	}
+	h.send(toC())
+	if h.model.diff != "+c" || h.model.err != nil {
+		t.Fatalf("expected c.go without an error, got %q (%v)", h.model.diff, h.model.err)
+	}
+}
+
+func TestStaleRefreshIsDropped(t *testing.T) {
+	h, fake := loadsTestHarness(t)
+	older := h.model.refreshCmd()
+	fake.Update(func(f *gittest.Fake) {
+		f.Entries = append(f.Entries, git.StatusEntry{Path: "d.go", Status: "??"})
+	})
+	newer := h.model.refreshCmd()
+	if !h.model.loads.refreshing {
+		t.Fatalf("expected a refresh in flight")
+	}
+	if _, cmd := h.model.Update(tickMsg{}); cmd == nil {
+		t.Fatalf("expected the tick to be rescheduled")
+	}
+	if h.model.loads.refresh != 4 {
+		t.Fatalf("expected the tick to wait for the refresh in flight, got generation %d", h.model.loads.refresh)
+	}
+
+	h.send(newer())
+	h.send(older())
+	if len(h.model.files) != 4 {
+		t.Fatalf("expected the newer file list to stay, got %+v", h.model.files)
+	}
+	if h.model.loads.refreshing {
+		t.Fatalf("expected the refresh to be finished")
+	}
+}
+
+func TestRefreshKeepsDiffSelectedMeanwhile(t *testing.T) {
+	h, fake := loadsTestHarness(t)
+	refresh := h.model.refreshCmd()
+	toB := step(h, 1)
+	h.send(toB())
+
+	fake.Update(func(f *gittest.Fake) {
+		f.Entries = append(f.Entries, git.StatusEntry{Path: "d.go", Status: "??"})
+	})
+	h.send(refresh())
+	if len(h.model.files) != 4 {
+		t.Fatalf("expected the refreshed file list, got %+v", h.model.files)
+	}
+	if h.model.diff != "+b" {
+		t.Fatalf("expected the diff selected during the refresh to stay, got %q", h.model.diff)
+	}
+}
+
+func TestRefreshErrorShowsDespiteDiffMeanwhile(t *testing.T) {
+	h, fake := loadsTestHarness(t)
+	fake.Update(func(f *gittest.Fake) { f.Err = errors.New("not a git repository") })
+	refresh := h.model.refreshCmd()
+	step(h, 1)
+
+	h.send(refresh())
+	if h.model.err == nil || !strings.Contains(h.model.View(), "not a git repository") {
+		t.Fatalf("expected the failed refresh to be reported, got %v", h.model.err)
+	}
+}
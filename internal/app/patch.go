@@ -0,0 +1,509 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"wing/internal/git"
+	"wing/internal/review"
+)
+
+// patchPreviewRows is how many patch lines the import preview shows.
+const patchPreviewRows = 12
+
+type patchScope int
+
+const (
+	patchAll patchScope = iota
+	patchFile
+	patchHunk
+	patchMarked
+)
+
+var patchScopeLabels = []string{"all changes", "selected file", "current hunk", "marked"}
+
+// patchState holds the patch export options and the patch being imported.
+type patchState struct {
+	scope patchScope
+	mail  bool
+	// marked holds the files marked for export with space, by path: a
+	// file without hunks is marked whole.
+	marked map[string][]git.Hunk
+	// path, text and check describe the loaded import; offset scrolls its
+	// preview.
+	path   string
+	text   string
+	check  git.PatchCheck
+	offset int
+}
+
+type patchExportMsg struct {
+	path string
+	err  error
+}
+
+type patchLoadedMsg struct {
+	path  string
+	text  string
+	check git.PatchCheck
+	err   error
+}
+
+type patchAppliedMsg struct {
+	mode git.ApplyMode
+	err  error
+}
+
+// resolvePatchPath expands ~ and makes input relative to the repository.
+func resolvePatchPath(repoPath, input string) string {
+	path := strings.TrimSpace(input)
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
+		}
+	}
+	if path != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(repoPath, path)
+	}
+	return path
+}
+
+func (m *Model) openPatchExportModal() {
+	m.modal = modalPatchExport
+	m.modalErr = ""
+	m.patchText.Placeholder = "empty for .git/wing/changes.patch"
+	m.patchText.SetValue("")
+	m.patchText.Focus()
+	if len(m.patch.marked) > 0 {
+		m.patch.scope = patchMarked
+	} else if !m.canExportScope(m.patch.scope) {
+		m.patch.scope = patchAll
+	}
+}
+
+func (m *Model) openPatchImportModal() {
+	m.modal = modalPatchImport
+	m.modalErr = ""
+	m.patchText.Placeholder = "Patch file"
+	m.patchText.SetValue("")
+	m.patchText.Focus()
+}
+
+func (m Model) canExportScope(scope patchScope) bool {
+	switch scope {
+	case patchHunk:
+		_, ok := m.currentHunk()
+		return ok && m.mode == modeDiff
+	case patchMarked:
+		return len(m.patch.marked) > 0
+	}
+	return true
+}
+
+// patchPart is a file to export and, when only some of it is, the hunks of
+// the view to export.
+type patchPart struct {
+	entry git.StatusEntry
+	hunks []git.Hunk
+}
+
+// patchParts returns the changed files in the export scope.
+func (m Model) patchParts() []patchPart {
+	var parts []patchPart
+	switch m.patch.scope {
+	case patchFile, patchHunk:
+		if entry, ok := m.selectedEntry(); ok && entry.Status != "" {
+			part := patchPart{entry: entry}
+			if m.patch.scope == patchHunk {
+				hunk, _ := m.currentViewHunk()
+				part.hunks = []git.Hunk{hunk}
+			}
+			parts = append(parts, part)
+		}
+	default:
+		for _, entry := range m.files {
+			if entry.Status == "" || entry.Ignored {
+				continue
+			}
+			hunks, marked := m.patch.marked[entry.Path]
+			if m.patch.scope == patchMarked && !marked {
+				continue
+			}
+			parts = append(parts, patchPart{entry: entry, hunks: hunks})
+		}
+	}
+	return parts
+}
+
+// toggleFileMark marks the selected file for export, or unmarks it and
+// any of its hunks.
+func (m *Model) toggleFileMark() {
+	entry, ok := m.selectedEntry()
+	if !ok || entry.Status == "" || entry.Ignored {
+		m.notice = "Only changed files can be marked for export."
+		return
+	}
+	if _, marked := m.patch.marked[entry.Path]; marked {
+		delete(m.patch.marked, entry.Path)
+		m.notice = fmt.Sprintf("Unmarked %s (%d marked).", entry.Path, len(m.patch.marked))
+		return
+	}
+	if m.patch.marked == nil {
+		m.patch.marked = make(map[string][]git.Hunk)
+	}
+	m.patch.marked[entry.Path] = nil
+	m.notice = fmt.Sprintf("Marked %s for export (%d marked).", entry.Path, len(m.patch.marked))
+}
+
+// toggleHunkMark marks the hunk under the diff cursor for export, or
+// unmarks it.
+func (m *Model) toggleHunkMark() {
+	entry, ok := m.selectedEntry()
+	hunk, found := m.currentViewHunk()
+	if !ok || !found || entry.Status == "" || entry.Ignored {
+		return
+	}
+	hunks, marked := m.patch.marked[entry.Path]
+	if marked && len(hunks) == 0 {
+		m.notice = entry.Path + " is marked whole; unmark it in Files to pick hunks."
+		return
+	}
+	var kept []git.Hunk
+	for _, other := range hunks {
+		if other.NewStart != hunk.NewStart || other.NewLines != hunk.NewLines {
+			kept = append(kept, other)
+		}
+	}
+	if len(kept) == len(hunks) {
+		kept = append(kept, hunk)
+	}
+	m.notice = fmt.Sprintf("Marked %d hunk(s) of %s for export.", len(kept), entry.Path)
+	if len(kept) == 0 {
+		delete(m.patch.marked, entry.Path)
+		return
+	}
+	if m.patch.marked == nil {
+		m.patch.marked = make(map[string][]git.Hunk)
+	}
+	m.patch.marked[entry.Path] = kept
+}
+
+// currentViewHunk returns the rendered hunk under the diff cursor.
+func (m Model) currentViewHunk() (git.Hunk, bool) {
+	current, ok := m.currentHunk()
+	if !ok || current >= len(m.context.hunks) {
+		return git.Hunk{}, false
+	}
+	return m.context.hunks[current], true
+}
+
+func (m Model) exportPatchCmd(input string) tea.Cmd {
+	parts := m.patchParts()
+	mail := m.patch.mail
+	base := m.diffOpts.Base
+	return func() tea.Msg {
+		if len(parts) == 0 {
+			return patchExportMsg{err: errors.New("no changes to export")}
+		}
+		var whole []git.StatusEntry
+		for _, part := range parts {
+			if len(part.hunks) == 0 {
+				whole = append(whole, part.entry)
+			}
+		}
+		var b strings.Builder
+		if len(whole) > 0 {
+			patch, err := m.config.Backend.Patch(base, whole)
+			if err != nil {
+				return patchExportMsg{err: err}
+			}
+			b.WriteString(patch)
+		}
+		for _, part := range parts {
+			if len(part.hunks) == 0 {
+				continue
+			}
+			patch, err := m.config.Backend.Patch(base, []git.StatusEntry{part.entry})
+			if err != nil {
+				return patchExportMsg{err: err}
+			}
+			// The view may ignore whitespace, expand context or compare
+			// against the index, so its hunk lines need not apply to the
+			// patch base; export the patch's own hunks at the same lines.
+			parsed := git.ParseDiff(strings.TrimRight(patch, "\n")).Overlapping(part.hunks...)
+			if len(parsed.Hunks) == 0 {
+				return patchExportMsg{err: fmt.Errorf("the hunks of %s are not in the patch", part.entry.Path)}
+			}
+			b.WriteString(parsed.String() + "\n")
+		}
+		patch := b.String()
+		if strings.TrimSpace(patch) == "" {
+			return patchExportMsg{err: errors.New("no changes to export")}
+		}
+		if mail {
+			subject := fmt.Sprintf("Update %d files", len(parts))
+			if len(parts) == 1 {
+				subject = "Update " + parts[0].entry.Path
+			}
+			var err error
+			if patch, err = m.config.Backend.FormatPatch(subject, patch, time.Now()); err != nil {
+				return patchExportMsg{err: err}
+			}
+		}
+		path := resolvePatchPath(m.config.RepoPath, input)
+		if path == "" {
+			storePath, err := review.StorePath(m.config.RepoPath)
+			if err != nil {
+				return patchExportMsg{err: err}
+			}
+			path = filepath.Join(filepath.Dir(storePath), "changes.patch")
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return patchExportMsg{err: err}
+		}
+		if err := os.WriteFile(path, []byte(patch), 0o644); err != nil {
+			return patchExportMsg{err: err}
+		}
+		return patchExportMsg{path: path}
+	}
+}
+
+func (m Model) loadPatchCmd(input string) tea.Cmd {
+	return func() tea.Msg {
+		path := resolvePatchPath(m.config.RepoPath, input)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return patchLoadedMsg{err: err}
+		}
+		check, err := m.config.Backend.CheckPatch(string(data))
+		return patchLoadedMsg{path: path, text: string(data), check: check, err: err}
+	}
+}
+
+func (m Model) applyPatchCmd(mode git.ApplyMode) tea.Cmd {
+	text := m.patch.text
+	return func() tea.Msg {
+		return patchAppliedMsg{mode: mode, err: m.config.Backend.ApplyPatch(text, mode)}
+	}
+}
+
+func (m Model) handlePatchMsg(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case patchExportMsg:
+		if msg.err != nil {
+			m.modalErr = msg.err.Error()
+			return m, nil
+		}
+		m.closeModal()
+		m.notice = fmt.Sprintf("Patch written to %s.", msg.path)
+		if m.patch.scope == patchMarked {
+			m.patch.marked = nil
+		}
+	case patchLoadedMsg:
+		if msg.err != nil {
+			m.modalErr = msg.err.Error()
+			return m, nil
+		}
+		m.patchText.Blur()
+		m.patch.path = msg.path
+		m.patch.text = msg.text
+		m.patch.check = msg.check
+		m.patch.offset = 0
+		m.modal = modalPatchPreview
+		m.modalErr = ""
+	case patchAppliedMsg:
+		if msg.err != nil {
+			m.modalErr = msg.err.Error()
+			return m, nil
+		}
+		m.closeModal()
+		switch msg.mode {
+		case git.ApplyIndex:
+			m.notice = "Patch staged."
+		case git.ApplyThreeWay:
+			m.notice = "Patch applied with a three-way merge."
+		default:
+			m.notice = "Patch applied to the worktree."
+		}
+		return m, m.refreshCmd()
+	}
+	return m, nil
+}
+
+func (m Model) handlePatchExportKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.closeModal()
+		return m, nil
+	case "tab":
+		m.patch.scope = (m.patch.scope + 1) % patchScope(len(patchScopeLabels))
+		for !m.canExportScope(m.patch.scope) {
+			m.patch.scope = (m.patch.scope + 1) % patchScope(len(patchScopeLabels))
+		}
+		return m, nil
+	case "ctrl+t":
+		m.patch.mail = !m.patch.mail
+		return m, nil
+	case "enter":
+		m.modalErr = ""
+		return m, m.exportPatchCmd(m.patchText.Value())
+	}
+	var cmd tea.Cmd
+	m.patchText, cmd = m.patchText.Update(msg)
+	return m, cmd
+}
+
+func (m Model) handlePatchImportKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.closeModal()
+		return m, nil
+	case "enter":
+		if strings.TrimSpace(m.patchText.Value()) == "" {
+			m.modalErr = "Patch file is required."
+			return m, nil
+		}
+		m.modalErr = ""
+		return m, m.loadPatchCmd(m.patchText.Value())
+	}
+	var cmd tea.Cmd
+	m.patchText, cmd = m.patchText.Update(msg)
+	return m, cmd
+}
+
+func (m Model) handlePatchPreviewKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	lines := splitLines(m.patch.text)
+	switch msg.String() {
+	case "esc":
+		m.closeModal()
+	case "up", "k":
+		m.patch.offset = clampOffset(m.patch.offset-1, len(lines), patchPreviewRows)
+	case "down", "j":
+		m.patch.offset = clampOffset(m.patch.offset+1, len(lines), patchPreviewRows)
+	case "w":
+		if len(m.patch.check.WorktreeConflicts) > 0 {
+			m.modalErr = "Patch does not apply to the worktree; press 3 for a three-way merge."
+			return m, nil
+		}
+		m.modalErr = ""
+		return m, m.applyPatchCmd(git.ApplyWorktree)
+	case "s":
+		if len(m.patch.check.IndexConflicts) > 0 {
+			m.modalErr = "Patch does not apply to the index."
+			return m, nil
+		}
+		m.modalErr = ""
+		return m, m.applyPatchCmd(git.ApplyIndex)
+	case "3":
+		m.modalErr = ""
+		return m, m.applyPatchCmd(git.ApplyThreeWay)
+	}
+	return m, nil
+}
+
+func (m Model) renderPatchExport() []string {
+	selected := lipgloss.NewStyle().Background(lipgloss.Color("62"))
+	scopes := make([]string, len(patchScopeLabels))
+	for i, label := range patchScopeLabels {
+		if patchScope(i) == patchMarked {
+			label = fmt.Sprintf("%s (%d)", label, len(m.patch.marked))
+		}
+		if patchScope(i) == m.patch.scope {
+			label = selected.Render(label)
+		}
+		scopes[i] = label
+	}
+	format := "plain diff"
+	if m.patch.mail {
+		format = "mail (git am)"
+	}
+	source := "worktree against HEAD"
+	if m.diffOpts.Base != "" {
+		source = "against " + m.diffOpts.Base
+	}
+	return []string{
+		"Write changes as a patch file (" + source + ").",
+		"Scope:  " + strings.Join(scopes, "  "),
+		"Format: " + format,
+		m.patchText.View(),
+		"",
+		"Tab to change scope, ctrl+t to switch format,",
+		"Enter to write, Esc to cancel.",
+	}
+}
+
+func (m Model) renderPatchImport() []string {
+	return []string{
+		"Apply a patch file to the worktree or index.",
+		"Paths are relative to the repository root.",
+		m.patchText.View(),
+		"",
+		"Enter to preview, Esc to cancel.",
+	}
+}
+
+func (m Model) renderPatchPreview() []string {
+	check := m.patch.check
+	okStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("71"))
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("160"))
+
+	body := []string{fmt.Sprintf("%s: %d %s", filepath.Base(m.patch.path), len(check.Files), plural(len(check.Files), "file", "files"))}
+	for _, file := range check.Files {
+		label := file.Path
+		if file.OrigPath != "" {
+			label = file.OrigPath + " → " + file.Path
+		}
+		stats := "binary"
+		if file.Added >= 0 {
+			stats = okStyle.Render(fmt.Sprintf("+%d", file.Added)) + " " + errStyle.Render(fmt.Sprintf("-%d", file.Removed))
+		}
+		body = append(body, fmt.Sprintf("  %s  %s", label, stats))
+	}
+	body = append(body, "")
+	for _, target := range []struct {
+		name      string
+		conflicts []string
+	}{
+		{"Worktree", check.WorktreeConflicts},
+		{"Index", check.IndexConflicts},
+	} {
+		if len(target.conflicts) == 0 {
+			body = append(body, okStyle.Render(target.name+": applies cleanly"))
+			continue
+		}
+		body = append(body, errStyle.Render(target.name+" conflicts:"))
+		for i, conflict := range target.conflicts {
+			if i == 5 {
+				body = append(body, errStyle.Render(fmt.Sprintf("  … %d more", len(target.conflicts)-i)))
+				break
+			}
+			body = append(body, errStyle.Render("  "+conflict))
+		}
+	}
+	body = append(body, "")
+
+	lines := splitLines(m.patch.text)
+	end := m.patch.offset + patchPreviewRows
+	if end > len(lines) {
+		end = len(lines)
+	}
+	preview := lines[m.patch.offset:end]
+	width := m.width - 12
+	for i, line := range preview {
+		if runes := []rune(line); width > 0 && len(runes) > width {
+			preview[i] = string(runes[:width])
+		}
+	}
+	body = append(body, colorizeDiffLines(preview)...)
+	body = append(body, "")
+	body = append(body, "w to apply to the worktree, s to stage it,")
+	body = append(body, "3 for a three-way merge, j/k to scroll, Esc to cancel.")
+	return body
+}
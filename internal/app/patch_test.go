@@ -0,0 +1,236 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"wing/internal/git"
+)
+
+func TestResolvePatchPath(t *testing.T) {
+	if got := resolvePatchPath("/repo", " fix.patch "); got != filepath.Join("/repo", "fix.patch") {
+		t.Fatalf("unexpected relative path %q", got)
+	}
+	if got := resolvePatchPath("/repo", "/tmp/fix.patch"); got != "/tmp/fix.patch" {
+		t.Fatalf("unexpected absolute path %q", got)
+	}
+	if got := resolvePatchPath("/repo", ""); got != "" {
+		t.Fatalf("expected empty path, got %q", got)
+	}
+}
+
+func TestPatchScopes(t *testing.T) {
+	m := New(Config{})
+	m.width = 120
+	m.height = 30
+	m.mode = modeDiff
+	m.files = []git.StatusEntry{{Path: "a.go", Status: "M"}, {Path: "b.go", Status: "??"}, {Path: "c.log", Status: "!!", Ignored: true}}
+	m.rebuildRows()
+	m.setDiff("--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-x\n+y\n@@ -10 +10 @@\n-p\n+q", nil)
+
+	if got := m.patchParts(); len(got) != 2 {
+		t.Fatalf("expected all changed files, got %+v", got)
+	}
+	m.patch.scope = patchFile
+	if got := m.patchParts(); len(got) != 1 || got[0].entry.Path != "a.go" || got[0].hunks != nil {
+		t.Fatalf("expected the selected file, got %+v", got)
+	}
+
+	m.diffCursor = 6
+	if hunk, ok := m.currentViewHunk(); !ok || hunk.HeaderLine() != "@@ -10,1 +10,1 @@" {
+		t.Fatalf("unexpected hunk %+v", hunk)
+	}
+
+	m.openPatchExportModal()
+	for i := 0; i < 3; i++ {
+		next, _ := m.handlePatchExportKey(tea.KeyMsg{Type: tea.KeyTab})
+		m = next.(Model)
+	}
+	if m.patch.scope != patchFile {
+		t.Fatalf("expected scope to cycle back to file, got %d", m.patch.scope)
+	}
+	m.mode = modeExplorer
+	next, _ := m.handlePatchExportKey(tea.KeyMsg{Type: tea.KeyTab})
+	if next.(Model).patch.scope != patchAll {
+		t.Fatalf("expected hunk scope skipped outside diff mode")
+	}
+}
+
+func TestPatchPreviewBlocksConflicts(t *testing.T) {
+	m := New(Config{})
+	m.width = 120
+	m.height = 30
+	m.modal = modalPatchPreview
+	m.patch.path = "/tmp/fix.patch"
+	m.patch.text = "--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-x\n+y\n"
+	m.patch.check = git.PatchCheck{
+		Files:             []git.PatchFile{{Path: "a.go", Added: 1, Removed: 1}},
+		WorktreeConflicts: []string{"patch failed: a.go:1"},
+	}
+	next, cmd := m.handlePatchPreviewKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("w")})
+	if cmd != nil || next.(Model).modalErr == "" {
+		t.Fatalf("expected worktree apply to be blocked")
+	}
+	if _, cmd := m.handlePatchPreviewKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")}); cmd == nil {
+		t.Fatalf("expected staging to be allowed")
+	}
+	body := strings.Join(m.renderPatchPreview(), "\n")
+	for _, want := range []string{"fix.patch: 1 file", "a.go", "Worktree conflicts:", "patch failed: a.go:1", "Index: applies cleanly", "+y"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in preview:\n%s", want, body)
+		}
+	}
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v (%s)", args, err, strings.TrimSpace(string(out)))
+	}
+}
+
+func TestHunkExportAppliesToTheBase(t *testing.T) {
+	repo := t.TempDir()
+	lines := make([]string, 20)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	write := func() {
+		if err := os.WriteFile(filepath.Join(repo, "a.txt"), []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	runGit(t, repo, "init", "-q")
+	write()
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "-c", "user.name=wing", "-c", "user.email=wing@example.com", "commit", "-q", "-m", "first")
+	// A staged change next to an unstaged one: the view compares against
+	// the index and shows the staged line as context, which HEAD lacks.
+	lines[9] = "staged"
+	write()
+	runGit(t, repo, "add", "-A")
+	lines[11] = "unstaged  "
+	write()
+
+	m := New(Config{RepoPath: repo})
+	m.width = 120
+	m.height = 30
+	m.mode = modeDiff
+	m.diffOpts.Whitespace = git.WhitespaceIgnoreAll
+	m.files = []git.StatusEntry{{Path: "a.txt", Status: "MM"}}
+	m.rebuildRows()
+	diff, err := git.Diff(repo, m.files[0], m.diffOpts)
+	if err != nil {
+		t.Fatalf("Diff error: %v", err)
+	}
+	m.setDiff(diff, nil)
+	m.patch.scope = patchHunk
+
+	out := filepath.Join(t.TempDir(), "hunk.patch")
+	msg := m.exportPatchCmd(out)().(patchExportMsg)
+	if msg.err != nil {
+		t.Fatalf("export error: %v", msg.err)
+	}
+	runGit(t, repo, "reset", "-q", "--hard")
+	runGit(t, repo, "apply", "--check", out)
+}
+
+func TestPatchExportUsesTheBackend(t *testing.T) {
+	m := New(Config{Backend: fakeRepo(), RepoPath: t.TempDir()})
+	m.files = []git.StatusEntry{{Path: "cmd/wing/main.go", Status: "M"}, {Path: "notes.md", Status: "??"}}
+	out := filepath.Join(t.TempDir(), "all.patch")
+	msg := m.exportPatchCmd(out)().(patchExportMsg)
+	if msg.err != nil {
+		t.Fatalf("export error: %v", msg.err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !strings.Contains(string(data), "+var version = \"1.0\"") || !strings.Contains(string(data), "+# Notes") {
+		t.Fatalf("expected the fake's diffs in the patch, got:\n%s", data)
+	}
+}
+
+func TestMarkedFilesAndHunksExportTogether(t *testing.T) {
+	repo := t.TempDir()
+	lines := make([]string, 20)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	write := func(name string) {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	runGit(t, repo, "init", "-q")
+	write("a.txt")
+	write("b.txt")
+	write("c.txt")
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "-c", "user.name=wing", "-c", "user.email=wing@example.com", "commit", "-q", "-m", "first")
+	lines[1] = "changed"
+	lines[17] = "changed"
+	write("a.txt")
+	write("b.txt")
+	write("c.txt")
+
+	m := New(Config{RepoPath: repo})
+	m.width = 120
+	m.height = 30
+	m.mode = modeDiff
+	m.files = []git.StatusEntry{{Path: "a.txt", Status: "M"}, {Path: "b.txt", Status: "M"}, {Path: "c.txt", Status: "M"}}
+	m.rebuildRows()
+
+	// Mark b.txt whole, then the second hunk of a.txt.
+	m.selected = 1
+	m.toggleFileMark()
+	m.selected = 0
+	diff, err := git.Diff(repo, m.files[0], m.diffOpts)
+	if err != nil {
+		t.Fatalf("Diff error: %v", err)
+	}
+	m.setDiff(diff, nil)
+	m.focus = focusDiff
+	m.diffCursor = len(m.contentLines) - 1
+	m.toggleHunkMark()
+	if hunks := m.patch.marked["a.txt"]; len(hunks) != 1 || hunks[0].NewStart < 10 {
+		t.Fatalf("expected the second hunk of a.txt marked, got %+v", m.patch.marked)
+	}
+	if view := m.renderRow(m.rows[1], false, 60); !strings.Contains(view, "◆") {
+		t.Fatalf("expected the marked file flagged, got %q", view)
+	}
+
+	m.openPatchExportModal()
+	if m.patch.scope != patchMarked {
+		t.Fatalf("expected marks to select the marked scope, got %d", m.patch.scope)
+	}
+	out := filepath.Join(t.TempDir(), "marked.patch")
+	msg := m.exportPatchCmd(out)().(patchExportMsg)
+	if msg.err != nil {
+		t.Fatalf("export error: %v", msg.err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	patch := string(data)
+	if strings.Contains(patch, "c.txt") || strings.Count(patch, "+changed") != 3 {
+		t.Fatalf("expected b.txt and the second hunk of a.txt, got:\n%s", patch)
+	}
+	runGit(t, repo, "reset", "-q", "--hard")
+	runGit(t, repo, "apply", "--check", out)
+
+	next, _ := m.handlePatchMsg(msg)
+	if m = next.(Model); len(m.patch.marked) != 0 {
+		t.Fatalf("expected the marks to be cleared after the export, got %+v", m.patch.marked)
+	}
+}
@@ -0,0 +1,188 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// defaultRecentWindow is how long a change stays highlighted when the
+// config does not say otherwise.
+const defaultRecentWindow = 10 * time.Second
+
+// recentState remembers when each changed file last changed across
+// refreshes, and which added lines of the selected file's diff are new.
+type recentState struct {
+	changedAt map[string]time.Time
+	// lines maps the text of added diff lines of linesPath to when they
+	// first appeared.
+	lines     map[string]time.Time
+	linesPath string
+	follow    bool
+}
+
+// fileModTimes stats each path, skipping files that no longer exist.
+func fileModTimes(repoPath string, paths []string) map[string]time.Time {
+	times := make(map[string]time.Time, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(filepath.Join(repoPath, path)); err == nil {
+			times[path] = info.ModTime()
+		}
+	}
+	return times
+}
+
+func (m Model) recentWindow() time.Duration {
+	if m.config.RecentWindow > 0 {
+		return m.config.RecentWindow
+	}
+	return defaultRecentWindow
+}
+
+func (m Model) isRecent(changedAt time.Time, now time.Time) bool {
+	return !changedAt.IsZero() && now.Sub(changedAt) < m.recentWindow()
+}
+
+// trackChanges compares fresh content hashes with the previous refresh and
+// stamps the files that changed. The first refresh only sets the baseline.
+// It returns the most recently modified of the changed files.
+func (m *Model) trackChanges(previous, current map[string]string, modTimes map[string]time.Time, now time.Time) string {
+	if m.recent.changedAt == nil {
+		m.recent.changedAt = make(map[string]time.Time)
+	}
+	if previous == nil {
+		return ""
+	}
+	latest := ""
+	var latestTime time.Time
+	for path, hash := range current {
+		if old, ok := previous[path]; ok && old == hash {
+			continue
+		}
+		m.recent.changedAt[path] = now
+		if modTime := modTimes[path]; latest == "" || modTime.After(latestTime) {
+			latest = path
+			latestTime = modTime
+		}
+	}
+	for path, changedAt := range m.recent.changedAt {
+		if !m.isRecent(changedAt, now) {
+			delete(m.recent.changedAt, path)
+		}
+	}
+	return latest
+}
+
+// trackLines stamps the added lines of diff that were not in the previous
+// diff of the same file.
+func (m *Model) trackLines(path, previous, diff string, now time.Time) {
+	if path != m.recent.linesPath {
+		m.recent.linesPath = path
+		m.recent.lines = nil
+		return
+	}
+	if previous == diff {
+		return
+	}
+	before := make(map[string]int)
+	for _, line := range strings.Split(previous, "\n") {
+		if isAddedLine(line) {
+			before[line]++
+		}
+	}
+	lines := make(map[string]time.Time)
+	for line, changedAt := range m.recent.lines {
+		if m.isRecent(changedAt, now) {
+			lines[line] = changedAt
+		}
+	}
+	for _, line := range strings.Split(diff, "\n") {
+		if !isAddedLine(line) {
+			continue
+		}
+		if before[line] > 0 {
+			before[line]--
+			continue
+		}
+		lines[line] = now
+	}
+	m.recent.lines = lines
+}
+
+func isAddedLine(line string) bool {
+	return strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++ ")
+}
+
+// markRecentRows flags rows whose file, or a file below the folder, changed
+// within the recent window.
+func (m *Model) markRecentRows(now time.Time) {
+	if len(m.recent.changedAt) == 0 {
+		return
+	}
+	recentDirs := make(map[string]bool)
+	for path, changedAt := range m.recent.changedAt {
+		if !m.isRecent(changedAt, now) {
+			continue
+		}
+		for dir := filepath.ToSlash(filepath.Dir(path)); dir != "." && dir != "/"; dir = filepath.ToSlash(filepath.Dir(dir)) {
+			recentDirs[dir] = true
+		}
+	}
+	for i, row := range m.rows {
+		if row.IsDir {
+			m.rows[i].Recent = recentDirs[row.Path]
+			continue
+		}
+		m.rows[i].Recent = m.isRecent(m.recent.changedAt[row.Path], now)
+	}
+}
+
+// isRecentLine reports whether line is an added line that appeared within
+// the recent window.
+func (m Model) isRecentLine(line string, now time.Time) bool {
+	if !isAddedLine(line) {
+		return false
+	}
+	changedAt, ok := m.recent.lines[line]
+	return ok && m.isRecent(changedAt, now)
+}
+
+// highlightRecentLines restyles the rendered lines whose raw text was added
+// recently.
+func (m Model) highlightRecentLines(raw, rendered []string, now time.Time) []string {
+	if len(m.recent.lines) == 0 {
+		return rendered
+	}
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("120")).Bold(true)
+	for i, line := range raw {
+		if i < len(rendered) && m.isRecentLine(line, now) {
+			rendered[i] = style.Render(line)
+		}
+	}
+	return rendered
+}
+
+// followFile selects path, expanding its folders, so the diff pane tracks
+// the file the agent touched last.
+func (m *Model) followFile(path string) tea.Cmd {
+	if path == "" || path == m.selectedFilePath() {
+		return nil
+	}
+	for dir := filepath.ToSlash(filepath.Dir(path)); dir != "." && dir != "/"; dir = filepath.ToSlash(filepath.Dir(dir)) {
+		m.collapsed[dir] = false
+	}
+	m.rebuildRows()
+	index := rowKeyIndex(m.rows, "file:"+path)
+	if index == -1 {
+		return nil
+	}
+	m.selected = index
+	m.context.reset()
+	m.diffCursor = 0
+	m.ensureSelectionVisible()
+	return m.diffCmd()
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"wing/internal/git"
+)
+
+func TestTrackChangesMarksChangedFiles(t *testing.T) {
+	m := New(Config{})
+	now := time.Now()
+	if latest := m.trackChanges(nil, map[string]string{"a.go": "h1"}, nil, now); latest != "" {
+		t.Fatalf("expected first refresh to only set the baseline, got %q", latest)
+	}
+	previous := map[string]string{"a.go": "h1", "b.go": "h2"}
+	current := map[string]string{"a.go": "h1", "b.go": "h2-changed", "c.go": "h3"}
+	modTimes := map[string]time.Time{"b.go": now.Add(-time.Minute), "c.go": now}
+	if latest := m.trackChanges(previous, current, modTimes, now); latest != "c.go" {
+		t.Fatalf("expected c.go as most recent, got %q", latest)
+	}
+	if _, ok := m.recent.changedAt["a.go"]; ok {
+		t.Fatalf("expected unchanged a.go not to be marked")
+	}
+
+	m.files = []git.StatusEntry{{Path: "a.go", Status: "M"}, {Path: "dir/b.go", Status: "M"}}
+	m.recent.changedAt = map[string]time.Time{"dir/b.go": now}
+	m.collapsed["dir"] = true
+	m.rebuildRows()
+	if len(m.rows) != 2 || !m.rows[1].Recent || m.rows[1].Path != "dir" || m.rows[0].Recent {
+		t.Fatalf("expected collapsed folder to carry the mark, got %+v", m.rows)
+	}
+
+	m.recent.changedAt["dir/b.go"] = now.Add(-time.Hour)
+	m.rebuildRows()
+	if m.rows[1].Recent {
+		t.Fatalf("expected mark to expire after the window")
+	}
+}
+
+func TestTrackLinesMarksNewAddedLines(t *testing.T) {
+	m := New(Config{})
+	now := time.Now()
+	first := "+++ b/a.go\n@@ -1 +1,2 @@\n+one\n same\n"
+	second := "+++ b/a.go\n@@ -1 +1,3 @@\n+one\n+two\n same\n"
+	m.trackLines("a.go", "", first, now)
+	if len(m.recent.lines) != 0 {
+		t.Fatalf("expected a new file to only set the baseline")
+	}
+	m.trackLines("a.go", first, second, now)
+	if !m.isRecentLine("+two", now) || m.isRecentLine("+one", now) {
+		t.Fatalf("expected only +two to be recent, got %v", m.recent.lines)
+	}
+	if m.isRecentLine("+two", now.Add(time.Hour)) {
+		t.Fatalf("expected line mark to expire")
+	}
+	m.trackLines("b.go", second, first, now)
+	if len(m.recent.lines) != 0 {
+		t.Fatalf("expected switching files to reset line marks")
+	}
+}
+
+func TestFollowFileSelectsAndExpands(t *testing.T) {
+	m := New(Config{Follow: true})
+	m.mode = modeDiff
+	m.files = []git.StatusEntry{{Path: "a.go", Status: "M"}, {Path: "dir/sub/b.go", Status: "M"}}
+	m.rebuildRows()
+	if cmd := m.followFile("dir/sub/b.go"); cmd == nil {
+		t.Fatalf("expected a diff request for the followed file")
+	}
+	if got := m.selectedFilePath(); got != "dir/sub/b.go" {
+		t.Fatalf("expected followed file selected, got %q", got)
+	}
+	if cmd := m.followFile("dir/sub/b.go"); cmd != nil {
+		t.Fatalf("expected no request when already selected")
+	}
+}
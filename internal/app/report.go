@@ -0,0 +1,288 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"wing/internal/git"
+	"wing/internal/review"
+)
+
+// reportFile is one changed file in the HTML review report.
+type reportFile struct {
+	entry    git.StatusEntry
+	diff     string
+	added    int
+	removed  int
+	comments []review.Comment
+}
+
+type reportData struct {
+	repo      string
+	branch    string
+	base      string
+	generated time.Time
+	files     []reportFile
+}
+
+type reportMsg struct {
+	path string
+	err  error
+}
+
+// exportReportCmd writes every changed file's diff, with the file tree,
+// stats and review comments, to a self-contained HTML file.
+func (m Model) exportReportCmd() tea.Cmd {
+	opts := m.diffOpts
+	store := m.review
+	return func() tea.Msg {
+		data, err := buildReport(m.config.Backend, m.config.RepoPath, opts, store)
+		if err != nil {
+			return reportMsg{err: err}
+		}
+		storePath, err := review.StorePath(m.config.RepoPath)
+		if err != nil {
+			return reportMsg{err: err}
+		}
+		path := filepath.Join(filepath.Dir(storePath), "review.html")
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return reportMsg{err: err}
+		}
+		if err := os.WriteFile(path, []byte(renderReportHTML(data)), 0o644); err != nil {
+			return reportMsg{err: err}
+		}
+		return reportMsg{path: path}
+	}
+}
+
+func buildReport(backend git.Backend, repoPath string, opts git.DiffOptions, store review.Store) (reportData, error) {
+	ctx := context.Background()
+	statuses, branch, err := backend.Status(ctx)
+	if err != nil {
+		return reportData{}, err
+	}
+	if opts.Base != "" {
+		if statuses, err = backend.StatusAgainst(ctx, opts.Base, statuses); err != nil {
+			return reportData{}, err
+		}
+	}
+	root, err := filepath.Abs(repoPath)
+	if err != nil {
+		root = repoPath
+	}
+	data := reportData{
+		repo:      filepath.Base(root),
+		branch:    branch.Head,
+		base:      opts.Base,
+		generated: time.Now(),
+	}
+	for _, entry := range statuses {
+		if entry.Ignored {
+			continue
+		}
+		diff, err := backend.Diff(ctx, entry, opts)
+		if err != nil {
+			return reportData{}, err
+		}
+		file := reportFile{entry: entry, diff: diff, comments: store.CommentsFor(entry.Path)}
+		file.added, file.removed = diffStats(diff)
+		data.files = append(data.files, file)
+	}
+	sort.Slice(data.files, func(i, j int) bool {
+		return data.files[i].entry.Path < data.files[j].entry.Path
+	})
+	return data, nil
+}
+
+// diffStats counts the added and removed lines in the hunks of diff.
+func diffStats(diff string) (int, int) {
+	added, removed := 0, 0
+	for _, hunk := range git.ParseDiff(diff).Hunks {
+		for _, line := range hunk.Lines {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				added++
+			case strings.HasPrefix(line, "-"):
+				removed++
+			}
+		}
+	}
+	return added, removed
+}
+
+const reportCSS = `body{margin:0;font:14px/1.5 -apple-system,BlinkMacSystemFont,"Segoe UI",sans-serif;background:#1c1c1c;color:#d0d0d0}
+header{padding:16px 24px;border-bottom:1px solid #444}
+h1{margin:0 0 4px;font-size:20px}
+.meta{color:#8a8a8a}
+.add{color:#5faf5f}.del{color:#d70000}
+main{display:flex;align-items:flex-start}
+nav{position:sticky;top:0;min-width:240px;max-height:100vh;overflow:auto;padding:16px;border-right:1px solid #444}
+nav ul{list-style:none;margin:0;padding-left:14px}
+nav>ul{padding-left:0}
+nav a{color:#d0d0d0;text-decoration:none}
+nav a:hover{text-decoration:underline}
+.status{display:inline-block;width:2.2em;font-family:monospace}
+.content{flex:1;min-width:0;padding:16px 24px}
+section{margin-bottom:24px;border:1px solid #444;border-radius:4px}
+section h2{margin:0;padding:8px 12px;font-size:14px;font-family:monospace;background:#262626;border-bottom:1px solid #444}
+pre{margin:0;padding:8px 12px;overflow-x:auto;font:12px/1.45 ui-monospace,Menlo,Consolas,monospace}
+.comments{padding:8px 12px;border-top:1px solid #444;background:#262626}
+.comment{margin:4px 0}
+.comment b{color:#d7af00}`
+
+func renderReportHTML(data reportData) string {
+	totalAdded, totalRemoved := 0, 0
+	for _, file := range data.files {
+		totalAdded += file.added
+		totalRemoved += file.removed
+	}
+
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>%s review</title>\n", html.EscapeString(data.repo))
+	fmt.Fprintf(&b, "<style>\n%s\n</style>\n</head>\n<body>\n", reportCSS)
+
+	b.WriteString("<header>\n")
+	fmt.Fprintf(&b, "<h1>%s</h1>\n", html.EscapeString(data.repo))
+	meta := []string{}
+	if data.branch != "" {
+		meta = append(meta, "branch "+html.EscapeString(data.branch))
+	}
+	if data.base != "" {
+		meta = append(meta, "base "+html.EscapeString(data.base))
+	}
+	meta = append(meta, fmt.Sprintf("%d %s changed", len(data.files), plural(len(data.files), "file", "files")))
+	meta = append(meta, fmt.Sprintf("<span class=\"add\">+%d</span> <span class=\"del\">-%d</span>", totalAdded, totalRemoved))
+	meta = append(meta, "generated "+data.generated.Format("2006-01-02 15:04"))
+	fmt.Fprintf(&b, "<div class=\"meta\">%s</div>\n</header>\n", strings.Join(meta, " · "))
+
+	b.WriteString("<main>\n<nav>\n")
+	writeReportTree(&b, data.files)
+	b.WriteString("</nav>\n<div class=\"content\">\n")
+	if len(data.files) == 0 {
+		b.WriteString("<p>No changes.</p>\n")
+	}
+	for i, file := range data.files {
+		writeReportFile(&b, i, file)
+	}
+	b.WriteString("</div>\n</main>\n</body>\n</html>\n")
+	return b.String()
+}
+
+// reportDir is a folder of the report's file tree.
+type reportDir struct {
+	dirs  map[string]*reportDir
+	files []int
+}
+
+func writeReportTree(b *strings.Builder, files []reportFile) {
+	root := &reportDir{dirs: map[string]*reportDir{}}
+	for i, file := range files {
+		dir := root
+		parts := strings.Split(file.entry.Path, "/")
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := dir.dirs[part]
+			if !ok {
+				next = &reportDir{dirs: map[string]*reportDir{}}
+				dir.dirs[part] = next
+			}
+			dir = next
+		}
+		dir.files = append(dir.files, i)
+	}
+	writeReportDir(b, root, files)
+}
+
+func writeReportDir(b *strings.Builder, dir *reportDir, files []reportFile) {
+	b.WriteString("<ul>\n")
+	names := make([]string, 0, len(dir.dirs))
+	for name := range dir.dirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(b, "<li>%s/\n", html.EscapeString(name))
+		writeReportDir(b, dir.dirs[name], files)
+		b.WriteString("</li>\n")
+	}
+	for _, i := range dir.files {
+		file := files[i]
+		fmt.Fprintf(b, "<li><span class=\"status\">%s</span><a href=\"#file-%d\">%s</a> %s</li>\n",
+			html.EscapeString(file.entry.Status), i, html.EscapeString(path.Base(file.entry.Path)), reportStats(file))
+	}
+	b.WriteString("</ul>\n")
+}
+
+func writeReportFile(b *strings.Builder, i int, file reportFile) {
+	label := file.entry.Path
+	if file.entry.OrigPath != "" {
+		label = file.entry.OrigPath + " → " + file.entry.Path
+	}
+	fmt.Fprintf(b, "<section id=\"file-%d\">\n<h2><span class=\"status\">%s</span>%s %s</h2>\n",
+		i, html.EscapeString(file.entry.Status), html.EscapeString(label), reportStats(file))
+	b.WriteString("<pre>")
+	for _, line := range splitLines(file.diff) {
+		escaped := html.EscapeString(line)
+		if color, ok := diffLineColor(line); ok {
+			fmt.Fprintf(b, "<span style=\"color:%s\">%s</span>\n", ansi256Hex(color), escaped)
+			continue
+		}
+		b.WriteString(escaped + "\n")
+	}
+	b.WriteString("</pre>\n")
+	if len(file.comments) > 0 {
+		b.WriteString("<div class=\"comments\">\n")
+		for _, comment := range file.comments {
+			fmt.Fprintf(b, "<div class=\"comment\"><b>%s</b> %s</div>\n",
+				html.EscapeString(comment.Location()), html.EscapeString(comment.Body))
+		}
+		b.WriteString("</div>\n")
+	}
+	b.WriteString("</section>\n")
+}
+
+func reportStats(file reportFile) string {
+	return fmt.Sprintf("<span class=\"add\">+%d</span> <span class=\"del\">-%d</span>", file.added, file.removed)
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// ansi16Hex are the xterm defaults for the first 16 palette colors.
+var ansi16Hex = []string{
+	"#000000", "#800000", "#008000", "#808000", "#000080", "#800080", "#008080", "#c0c0c0",
+	"#808080", "#ff0000", "#00ff00", "#ffff00", "#0000ff", "#ff00ff", "#00ffff", "#ffffff",
+}
+
+// ansi256Hex converts an xterm 256-color index, as used by the TUI styles,
+// to a CSS color.
+func ansi256Hex(color string) string {
+	var index int
+	if _, err := fmt.Sscanf(color, "%d", &index); err != nil || index < 0 || index > 255 {
+		return "inherit"
+	}
+	switch {
+	case index < 16:
+		return ansi16Hex[index]
+	case index < 232:
+		levels := []int{0, 95, 135, 175, 215, 255}
+		index -= 16
+		return fmt.Sprintf("#%02x%02x%02x", levels[index/36], levels[index/6%6], levels[index%6])
+	default:
+		gray := 8 + 10*(index-232)
+		return fmt.Sprintf("#%02x%02x%02x", gray, gray, gray)
+	}
+}
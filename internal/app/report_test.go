@@ -0,0 +1,72 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"wing/internal/git"
+	"wing/internal/review"
+)
+
+func TestRenderReportHTML(t *testing.T) {
+	diff := "diff --git a/src/a.go b/src/a.go\n--- a/src/a.go\n+++ b/src/a.go\n@@ -1,2 +1,2 @@\n ctx <b>\n-old\n+new\n"
+	added, removed := diffStats(diff)
+	if added != 1 || removed != 1 {
+		t.Fatalf("unexpected stats +%d -%d", added, removed)
+	}
+	data := reportData{
+		repo:      "wing",
+		branch:    "main",
+		generated: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		files: []reportFile{
+			{
+				entry:    git.StatusEntry{Path: "src/a.go", Status: "M"},
+				diff:     diff,
+				added:    added,
+				removed:  removed,
+				comments: []review.Comment{{Path: "src/a.go", Line: 2, Body: "why <new>?"}},
+			},
+			{entry: git.StatusEntry{Path: "README.md", Status: "??"}, added: 3},
+		},
+	}
+	doc := renderReportHTML(data)
+	for _, want := range []string{
+		"2 files changed · <span class=\"add\">+4</span> <span class=\"del\">-1</span>",
+		"<li>src/\n<ul>\n<li><span class=\"status\">M</span><a href=\"#file-0\">a.go</a>",
+		"<span style=\"color:#d70000\">-old</span>",
+		"<span style=\"color:#5faf5f\">+new</span>",
+		"<span style=\"color:#5f87ff\">@@ -1,2 +1,2 @@</span>",
+		" ctx &lt;b&gt;\n",
+		"<b>line 2</b> why &lt;new&gt;?",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Fatalf("expected %q in report:\n%s", want, doc)
+		}
+	}
+	if strings.Contains(doc, "<script") || strings.Contains(doc, "http") {
+		t.Fatalf("expected a self-contained report")
+	}
+}
+
+func TestAnsi256Hex(t *testing.T) {
+	cases := map[string]string{"1": "#800000", "69": "#5f87ff", "160": "#d70000", "238": "#444444", "x": "inherit"}
+	for color, want := range cases {
+		if got := ansi256Hex(color); got != want {
+			t.Fatalf("ansi256Hex(%q) = %q, want %q", color, got, want)
+		}
+	}
+}
+
+func TestBuildReportReadsTheBackend(t *testing.T) {
+	data, err := buildReport(fakeRepo(), t.TempDir(), git.DiffOptions{}, review.Store{})
+	if err != nil {
+		t.Fatalf("buildReport error: %v", err)
+	}
+	if data.branch != "main" || len(data.files) != 3 {
+		t.Fatalf("expected the fake's branch and changes, got %q with %d files", data.branch, len(data.files))
+	}
+	if file := data.files[0]; file.entry.Path != "cmd/wing/main.go" || file.added != 1 || file.removed != 1 {
+		t.Fatalf("unexpected first file %+v", file)
+	}
+}
@@ -85,3 +85,22 @@ func TestBuildRowsIgnoredFolder(t *testing.T) {
 		t.Fatalf("expected src folder to not be ignored")
 	}
 }
+
+func TestRowLabelRename(t *testing.T) {
+	rows := buildRows([]git.StatusEntry{
+		{Path: "docs/new.md", OrigPath: "docs/old.md", Status: "R"},
+		{Path: "src/moved.go", OrigPath: "lib/moved.go", Status: "R"},
+	}, map[string]bool{"docs": false, "src": false})
+	labels := map[string]string{}
+	for _, row := range rows {
+		if !row.IsDir {
+			labels[row.Path] = rowLabel(row)
+		}
+	}
+	if got := labels["docs/new.md"]; got != "  old.md → new.md" {
+		t.Fatalf("unexpected same-dir rename label %q", got)
+	}
+	if got := labels["src/moved.go"]; got != "  lib/moved.go → moved.go" {
+		t.Fatalf("unexpected cross-dir rename label %q", got)
+	}
+}
@@ -0,0 +1,207 @@
+package app
+
+import (
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"wing/internal/git"
+	"wing/internal/settings"
+)
+
+// sortMode is the order of the Files rows.
+type sortMode int
+
+const (
+	sortPath sortMode = iota
+	sortStatus
+	sortSize
+	sortModified
+)
+
+var sortNames = map[sortMode]string{
+	sortPath:     "path",
+	sortStatus:   "status",
+	sortSize:     "size",
+	sortModified: "modified",
+}
+
+func (s sortMode) String() string {
+	return sortNames[s]
+}
+
+// parseSortMode reads a remembered sort name; anything unknown sorts by
+// path.
+func parseSortMode(name string) sortMode {
+	for mode, modeName := range sortNames {
+		if modeName == name {
+			return mode
+		}
+	}
+	return sortPath
+}
+
+// statusCategory groups the many porcelain states into what a reviewer
+// tells apart.
+type statusCategory int
+
+const (
+	categoryConflicted statusCategory = iota
+	categoryModified
+	categoryAdded
+	categoryDeleted
+	categoryRenamed
+	categoryUntracked
+	categoryClean
+	categoryIgnored
+)
+
+func categoryOf(entry git.StatusEntry) statusCategory {
+	status := strings.TrimSpace(entry.Status)
+	switch {
+	case entry.Conflicted:
+		return categoryConflicted
+	case entry.Ignored:
+		return categoryIgnored
+	case status == "":
+		return categoryClean
+	case status == "??":
+		return categoryUntracked
+	case entry.OrigPath != "" || strings.ContainsAny(status, "RC"):
+		return categoryRenamed
+	case strings.Contains(status, "D"):
+		return categoryDeleted
+	case strings.Contains(status, "A"):
+		return categoryAdded
+	}
+	return categoryModified
+}
+
+// rowOrder sorts siblings by a key, smallest first, keeping path order
+// among equal keys. A folder's key combines the keys of everything below
+// it and is worked out once per layout.
+type rowOrder struct {
+	key     func(node *treeNode) int64
+	combine func(a, b int64) int64
+	folders map[*treeNode]int64
+}
+
+func (o *rowOrder) nodeKey(node *treeNode) int64 {
+	if !node.isDir {
+		return o.key(node)
+	}
+	if key, ok := o.folders[node]; ok {
+		return key
+	}
+	var key int64
+	for i, child := range node.children {
+		if i == 0 {
+			key = o.nodeKey(child)
+		} else {
+			key = o.combine(key, o.nodeKey(child))
+		}
+	}
+	o.folders[node] = key
+	return key
+}
+
+// sorted returns nodes in order; a nil order keeps them as they are.
+func (o *rowOrder) sorted(nodes []*treeNode) []*treeNode {
+	if o == nil {
+		return nodes
+	}
+	out := append([]*treeNode(nil), nodes...)
+	sort.SliceStable(out, func(i, j int) bool { return o.nodeKey(out[i]) < o.nodeKey(out[j]) })
+	return out
+}
+
+// rowOrder returns the order of the current sort mode, nil for by path.
+// Folders sort by their most pressing status, the lines changed below
+// them, or their most recently modified file.
+func (m Model) rowOrder() *rowOrder {
+	order := &rowOrder{folders: make(map[*treeNode]int64), combine: minKey}
+	switch m.sortMode {
+	case sortStatus:
+		order.key = func(node *treeNode) int64 { return int64(categoryOf(node.entry)) }
+	case sortSize:
+		order.key = func(node *treeNode) int64 {
+			stat := m.stats[node.path]
+			return -int64(stat.Added + stat.Deleted)
+		}
+		order.combine = func(a, b int64) int64 { return a + b }
+	case sortModified:
+		order.key = func(node *treeNode) int64 {
+			modTime, ok := m.modTimes[node.path]
+			if !ok {
+				return 0
+			}
+			return -modTime.UnixNano()
+		}
+	default:
+		return nil
+	}
+	return order
+}
+
+func minKey(a, b int64) int64 {
+	return min(a, b)
+}
+
+// cycleSort moves to the next sort mode, loading modification times when
+// they are needed.
+func (m *Model) cycleSort() tea.Cmd {
+	m.sortMode = (m.sortMode + 1) % sortMode(len(sortNames))
+	m.notice = "Sorted by " + m.sortMode.String() + "."
+	m.refreshRowsKeepingIndex()
+	cmds := []tea.Cmd{m.saveSettings()}
+	if m.sortMode == sortModified {
+		cmds = append(cmds, m.refreshCmd())
+	}
+	return tea.Batch(cmds...)
+}
+
+// toggleFlat switches between the folder tree and a flat list of files.
+func (m *Model) toggleFlat() tea.Cmd {
+	m.flat = !m.flat
+	if m.flat {
+		m.notice = "Listing files without folders."
+	} else {
+		m.notice = "Listing files in folders."
+	}
+	m.refreshRowsKeepingIndex()
+	return m.saveSettings()
+}
+
+// saveSettings remembers the sort mode and layout for the next run.
+func (m *Model) saveSettings() tea.Cmd {
+	m.settings.Sort = ""
+	if m.sortMode != sortPath {
+		m.settings.Sort = m.sortMode.String()
+	}
+	m.settings.Flat = m.flat
+	return saveSettingsCmd(m.settings)
+}
+
+type settingsSavedMsg struct {
+	err error
+}
+
+func saveSettingsCmd(s settings.Settings) tea.Cmd {
+	return func() tea.Msg {
+		return settingsSavedMsg{err: s.Save()}
+	}
+}
+
+// layoutLabel describes a sort mode or layout other than the default, for
+// the Files title.
+func (m Model) layoutLabel() string {
+	var parts []string
+	if m.sortMode != sortPath {
+		parts = append(parts, "by "+m.sortMode.String())
+	}
+	if m.flat {
+		parts = append(parts, "flat")
+	}
+	return strings.Join(parts, ", ")
+}
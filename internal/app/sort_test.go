@@ -0,0 +1,153 @@
+package app
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"wing/internal/git"
+	"wing/internal/settings"
+)
+
+func TestCategoryOf(t *testing.T) {
+	cases := []struct {
+		entry git.StatusEntry
+		want  statusCategory
+	}{
+		{git.StatusEntry{Status: "M"}, categoryModified},
+		{git.StatusEntry{Status: "MM"}, categoryModified},
+		{git.StatusEntry{Status: "A"}, categoryAdded},
+		{git.StatusEntry{Status: " D"}, categoryDeleted},
+		{git.StatusEntry{Status: "R", OrigPath: "old.go"}, categoryRenamed},
+		{git.StatusEntry{Status: "??"}, categoryUntracked},
+		{git.StatusEntry{Status: "UU", Conflicted: true}, categoryConflicted},
+		{git.StatusEntry{Ignored: true}, categoryIgnored},
+		{git.StatusEntry{}, categoryClean},
+	}
+	for _, c := range cases {
+		if got := categoryOf(c.entry); got != c.want {
+			t.Errorf("categoryOf(%+v) = %d, want %d", c.entry, got, c.want)
+		}
+	}
+}
+
+func TestParseSortMode(t *testing.T) {
+	for mode, name := range sortNames {
+		if got := parseSortMode(name); got != mode {
+			t.Errorf("parseSortMode(%q) = %v, want %v", name, got, mode)
+		}
+	}
+	if got := parseSortMode("bogus"); got != sortPath {
+		t.Fatalf("expected unknown names to sort by path, got %v", got)
+	}
+}
+
+func sortedTree(t *testing.T, m Model, entries []git.StatusEntry) []fileRow {
+	t.Helper()
+	tree := newFileTree()
+	collapsed := map[string]bool{"a": false, "b": false}
+	tree.sync(entries, collapsed)
+	return tree.rows(collapsed, nil, m.rowOrder())
+}
+
+func TestSortByStatusGroupsFolders(t *testing.T) {
+	m := New(Config{})
+	m.sortMode = sortStatus
+	rows := sortedTree(t, m, []git.StatusEntry{
+		{Path: "a/clean.go"},
+		{Path: "b/new.go", Status: "??"},
+		{Path: "b/edit.go", Status: "M"},
+		{Path: "c.go", Status: "UU", Conflicted: true},
+	})
+	expectPaths(t, rows, "c.go", "b/", "b/edit.go", "b/new.go", "a/", "a/clean.go")
+}
+
+func TestSortBySizeSumsFolders(t *testing.T) {
+	m := New(Config{})
+	m.sortMode = sortSize
+	m.stats = map[string]git.DiffStat{
+		"a/one.go": {Added: 3},
+		"a/two.go": {Added: 3},
+		"big.go":   {Added: 5},
+		"small.go": {Deleted: 1},
+	}
+	rows := sortedTree(t, m, []git.StatusEntry{
+		{Path: "a/one.go", Status: "M"},
+		{Path: "a/two.go", Status: "M"},
+		{Path: "big.go", Status: "M"},
+		{Path: "small.go", Status: "M"},
+	})
+	expectPaths(t, rows, "a/", "a/one.go", "a/two.go", "big.go", "small.go")
+}
+
+func TestSortByModifiedPutsNewestFirst(t *testing.T) {
+	m := New(Config{})
+	m.sortMode = sortModified
+	now := time.Now()
+	m.modTimes = map[string]time.Time{
+		"a/old.go": now.Add(-time.Hour),
+		"a/new.go": now,
+		"mid.go":   now.Add(-time.Minute),
+	}
+	rows := sortedTree(t, m, []git.StatusEntry{
+		{Path: "a/new.go", Status: "M"},
+		{Path: "a/old.go", Status: "M"},
+		{Path: "mid.go", Status: "M"},
+	})
+	expectPaths(t, rows, "a/", "a/new.go", "a/old.go", "mid.go")
+}
+
+func TestSortByModifiedListsCleanFilesAfterByPath(t *testing.T) {
+	m := New(Config{})
+	m.sortMode = sortModified
+	m.modTimes = map[string]time.Time{"z.go": time.Now()}
+	rows := sortedTree(t, m, []git.StatusEntry{
+		{Path: "a.go"},
+		{Path: "c.go"},
+		{Path: "b.go"},
+		{Path: "z.go", Status: "M"},
+	})
+	expectPaths(t, rows, "z.go", "a.go", "b.go", "c.go")
+}
+
+func TestFlatRowsListFilesByFullPath(t *testing.T) {
+	m := New(Config{})
+	m.sortMode = sortSize
+	m.stats = map[string]git.DiffStat{"a/b/deep.go": {Added: 9}, "top.go": {Added: 1}}
+	tree := newFileTree()
+	tree.sync([]git.StatusEntry{{Path: "a/b/deep.go", Status: "M"}, {Path: "a/x.go"}, {Path: "top.go", Status: "M"}}, map[string]bool{})
+	rows := tree.flatRows(func(path string) bool { return path == "a/x.go" }, m.rowOrder())
+	expectPaths(t, rows, "a/b/deep.go", "top.go")
+	if rows[0].Name != "a/b/deep.go" || rows[0].Depth != 0 {
+		t.Fatalf("expected flat rows named by their path, got %+v", rows[0])
+	}
+}
+
+func TestSortKeysUpdateTitleAndSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	prefs, err := settings.Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	h := newHarness(t, fakeRepo(), 100, 20)
+	h.model.settings = prefs
+	h.press("s")
+	h.press("s")
+	h.press("T")
+	if view := h.model.View(); !strings.Contains(view, "by size, flat") {
+		t.Fatalf("expected the layout in the Files title, got:\n%s", view)
+	}
+	saved, err := settings.Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if saved.Sort != "size" || !saved.Flat {
+		t.Fatalf("expected the layout to be remembered, got %+v", saved)
+	}
+	for _, row := range h.model.rows {
+		if row.IsDir {
+			t.Fatalf("expected no folders in the flat list, got %+v", row)
+		}
+	}
+}
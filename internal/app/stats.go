@@ -0,0 +1,91 @@
+package app
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"wing/internal/git"
+)
+
+// statBarWidth is the number of blocks in a row's change bar.
+const statBarWidth = 5
+
+// untrackedPaths lists the untracked files among statuses, whose lines
+// git does not count.
+func untrackedPaths(statuses []git.StatusEntry) []string {
+	var paths []string
+	for _, entry := range statuses {
+		if entry.Status == "??" {
+			paths = append(paths, entry.Path)
+		}
+	}
+	return paths
+}
+
+// dirStats sums the file stats into every folder above them.
+func dirStats(stats map[string]git.DiffStat) map[string]git.DiffStat {
+	dirs := make(map[string]git.DiffStat)
+	for file, stat := range stats {
+		for dir := path.Dir(file); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			if sum, ok := dirs[dir]; ok {
+				dirs[dir] = sum.Add(stat)
+			} else {
+				dirs[dir] = stat
+			}
+		}
+	}
+	return dirs
+}
+
+// totalStat sums the stats of every changed file.
+func totalStat(stats map[string]git.DiffStat) git.DiffStat {
+	var total git.DiffStat
+	for _, stat := range stats {
+		total.Added += stat.Added
+		total.Deleted += stat.Deleted
+	}
+	return total
+}
+
+// renderStat renders added and removed line counts, followed when bar is
+// set by a bar of blocks split between them, GitHub style. It is empty for
+// rows without changed lines.
+func renderStat(stat git.DiffStat, bar bool) string {
+	if stat.Binary {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render("bin")
+	}
+	total := stat.Added + stat.Deleted
+	if total == 0 {
+		return ""
+	}
+	var counts []string
+	if stat.Added > 0 {
+		counts = append(counts, addedStyle.Render(fmt.Sprintf("+%d", stat.Added)))
+	}
+	if stat.Deleted > 0 {
+		counts = append(counts, deletedStyle.Render(fmt.Sprintf("-%d", stat.Deleted)))
+	}
+	if !bar {
+		return strings.Join(counts, " ")
+	}
+	filled := min(total, statBarWidth)
+	added := (filled*stat.Added + total/2) / total
+	if stat.Added > 0 && added == 0 {
+		added = 1
+	}
+	if stat.Deleted > 0 && added == filled {
+		added = filled - 1
+	}
+	blocks := addedStyle.Render(strings.Repeat("■", added)) +
+		deletedStyle.Render(strings.Repeat("■", filled-added)) +
+		lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(strings.Repeat("□", statBarWidth-filled))
+	return strings.Join(counts, " ") + " " + blocks
+}
+
+var (
+	addedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("71"))
+	deletedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("160"))
+)
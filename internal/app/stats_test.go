@@ -0,0 +1,72 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"wing/internal/git"
+)
+
+func TestDirStatsSumIntoEveryFolder(t *testing.T) {
+	dirs := dirStats(map[string]git.DiffStat{
+		"cmd/wing/main.go":     {Added: 2, Deleted: 1},
+		"cmd/tool.go":          {Added: 5},
+		"assets/logo.png":      {Binary: true},
+		"assets/notes/todo.md": {Deleted: 4},
+		"README.md":            {Added: 1},
+	})
+	if dirs["cmd"] != (git.DiffStat{Added: 7, Deleted: 1}) || dirs["cmd/wing"] != (git.DiffStat{Added: 2, Deleted: 1}) {
+		t.Fatalf("unexpected cmd stats %+v / %+v", dirs["cmd"], dirs["cmd/wing"])
+	}
+	if dirs["assets"] != (git.DiffStat{Deleted: 4}) {
+		t.Fatalf("expected binary files to add nothing, got %+v", dirs["assets"])
+	}
+	if _, ok := dirs["."]; ok {
+		t.Fatalf("expected top-level files not to make a root folder")
+	}
+}
+
+func TestRenderStatBar(t *testing.T) {
+	cases := []struct {
+		stat git.DiffStat
+		want string
+	}{
+		{git.DiffStat{}, ""},
+		{git.DiffStat{Binary: true}, "bin"},
+		{git.DiffStat{Added: 2}, "+2 ■■□□□"},
+		{git.DiffStat{Added: 90, Deleted: 10}, "+90 -10 ■■■■■"},
+		{git.DiffStat{Added: 1, Deleted: 99}, "+1 -99 ■■■■■"},
+	}
+	for _, c := range cases {
+		if got := renderStat(c.stat, true); got != c.want {
+			t.Errorf("renderStat(%+v) = %q, want %q", c.stat, got, c.want)
+		}
+	}
+}
+
+func TestRowStatsFitTheRow(t *testing.T) {
+	m := New(Config{})
+	row := fileRow{Path: "internal/app/app.go", Name: "app.go", Status: "M", Depth: 2, Stat: git.DiffStat{Added: 120, Deleted: 4}}
+	if got := m.renderRow(row, false, 40); lipgloss.Width(got) != 40 || !strings.HasSuffix(got, "+120 -4 ■■■■■") {
+		t.Fatalf("expected the counts right-aligned with a bar, got %q", got)
+	}
+	if got := m.renderRow(row, false, 22); !strings.HasSuffix(got, "+120 -4") {
+		t.Fatalf("expected the bar to give way first, got %q", got)
+	}
+	if got := m.renderRow(row, false, 12); strings.Contains(got, "+120") {
+		t.Fatalf("expected no counts without room, got %q", got)
+	}
+}
+
+func TestRefreshLoadsStats(t *testing.T) {
+	h := newHarness(t, fakeRepo(), 100, 20)
+	h.press("m")
+	if h.model.stats["cmd/wing/main.go"] != (git.DiffStat{Added: 1, Deleted: 1}) {
+		t.Fatalf("unexpected stats %+v", h.model.stats)
+	}
+	if !strings.Contains(h.model.gitInfo, "+3 -1") {
+		t.Fatalf("expected totals in the git info, got %q", h.model.gitInfo)
+	}
+}
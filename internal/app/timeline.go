@@ -0,0 +1,249 @@
+package app
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"wing/internal/git"
+)
+
+// timelineRows is how many timeline entries the modal shows at once.
+const timelineRows = 12
+
+// checkpointState tracks automatic worktree snapshots and the timeline
+// modal. A change burst ends when a refresh sees the same fingerprint as the
+// one before it, at which point a checkpoint is taken.
+type checkpointState struct {
+	fingerprint string
+	pending     bool
+	busy        bool
+	list        []git.Checkpoint
+	// selected indexes the timeline, where 0 is the live worktree and i > 0
+	// is list[i-1]. mark is the "from" entry chosen with space, or -1.
+	selected int
+	mark     int
+	err      error
+	// restore is the checkpoint awaiting confirmation in the restore modal.
+	restore git.Checkpoint
+}
+
+type checkpointMsg struct {
+	created bool
+	err     error
+}
+
+type checkpointsMsg struct {
+	list []git.Checkpoint
+	err  error
+}
+
+type restoreMsg struct {
+	safety git.Checkpoint
+	err    error
+}
+
+// worktreeFingerprint hashes the status, size and mtime of every changed
+// file so edits to an already modified file are noticed too.
+func worktreeFingerprint(repoPath string, entries []git.StatusEntry) string {
+	hash := sha1.New()
+	for _, entry := range entries {
+		fmt.Fprintf(hash, "%s\x00%s\x00", entry.Path, entry.Status)
+		if info, err := os.Stat(filepath.Join(repoPath, entry.Path)); err == nil {
+			fmt.Fprintf(hash, "%d\x00%d\x00", info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// observeWorktree records the latest fingerprint and returns a checkpoint
+// command once a burst of changes has settled.
+func (m *Model) observeWorktree(fingerprint string) tea.Cmd {
+	if !m.config.Checkpoints || fingerprint == "" {
+		return nil
+	}
+	if fingerprint != m.checkpoints.fingerprint {
+		m.checkpoints.fingerprint = fingerprint
+		m.checkpoints.pending = true
+		return nil
+	}
+	if !m.checkpoints.pending || m.checkpoints.busy {
+		return nil
+	}
+	m.checkpoints.pending = false
+	m.checkpoints.busy = true
+	return m.checkpointCmd()
+}
+
+func (m Model) checkpointCmd() tea.Cmd {
+	return func() tea.Msg {
+		_, created, err := m.config.Backend.CreateCheckpoint()
+		return checkpointMsg{created: created, err: err}
+	}
+}
+
+func (m Model) checkpointsCmd() tea.Cmd {
+	return func() tea.Msg {
+		list, err := m.config.Backend.Checkpoints()
+		return checkpointsMsg{list: list, err: err}
+	}
+}
+
+func (m Model) restoreCmd(id string) tea.Cmd {
+	return func() tea.Msg {
+		safety, err := m.config.Backend.RestoreCheckpoint(id)
+		return restoreMsg{safety: safety, err: err}
+	}
+}
+
+func (m *Model) openTimelineModal() tea.Cmd {
+	m.modal = modalTimeline
+	m.modalErr = ""
+	m.checkpoints.selected = 0
+	m.checkpoints.mark = -1
+	return m.checkpointsCmd()
+}
+
+func (m Model) handleTimelineKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	total := len(m.checkpoints.list) + 1
+	switch msg.String() {
+	case "esc":
+		m.closeModal()
+	case "up", "k":
+		if m.checkpoints.selected > 0 {
+			m.checkpoints.selected--
+		}
+	case "down", "j":
+		if m.checkpoints.selected < total-1 {
+			m.checkpoints.selected++
+		}
+	case " ":
+		if m.checkpoints.mark == m.checkpoints.selected {
+			m.checkpoints.mark = -1
+		} else {
+			m.checkpoints.mark = m.checkpoints.selected
+		}
+	case "r":
+		if m.checkpoints.selected == 0 {
+			m.modalErr = "Select a checkpoint to restore."
+			return m, nil
+		}
+		m.checkpoints.restore = m.checkpoints.list[m.checkpoints.selected-1]
+		m.modal = modalRestore
+		m.modalErr = ""
+	case "s":
+		if !m.checkpoints.busy {
+			m.checkpoints.busy = true
+			return m, m.checkpointCmd()
+		}
+	case "enter":
+		base, problem := m.timelineBase()
+		if problem != "" {
+			m.modalErr = problem
+			return m, nil
+		}
+		m.diffOpts.Base = base
+		m.mode = modeDiff
+		m.diffOffset = 0
+		m.context.reset()
+		m.closeModal()
+		return m, m.refreshCmd()
+	}
+	return m, nil
+}
+
+// timelineBase turns the timeline selection into a compare base: the marked
+// entry (or the entry just before the selection) up to the selection. It
+// returns a message for the user instead when no such pair exists.
+func (m Model) timelineBase() (string, string) {
+	to := m.checkpoints.selected
+	from := m.checkpoints.mark
+	if from == -1 {
+		from = to + 1
+	}
+	if from < to {
+		from, to = to, from
+	}
+	if from == to {
+		return "", "Pick two different points to compare."
+	}
+	if from > len(m.checkpoints.list) {
+		return "", "No earlier checkpoint to compare against."
+	}
+	fromID := m.checkpoints.list[from-1].Short()
+	if to == 0 {
+		return fromID, ""
+	}
+	return fromID + ".." + m.checkpoints.list[to-1].Short(), ""
+}
+
+func (m Model) renderTimeline() []string {
+	body := []string{}
+	if m.checkpoints.err != nil {
+		body = append(body, fmt.Sprintf("Error: %s", m.checkpoints.err))
+		body = append(body, "")
+	}
+	if len(m.checkpoints.list) == 0 {
+		body = append(body, "No checkpoints yet. They are taken automatically")
+		body = append(body, "after each burst of changes, or press s.")
+		body = append(body, "")
+	}
+
+	selectedStyle := lipgloss.NewStyle().Background(lipgloss.Color("62"))
+	markStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("178"))
+	total := len(m.checkpoints.list) + 1
+	start := clampOffset(m.checkpoints.selected-timelineRows/2, total, timelineRows)
+	end := start + timelineRows
+	if end > total {
+		end = total
+	}
+	for i := start; i < end; i++ {
+		label := "  now       worktree"
+		if i > 0 {
+			checkpoint := m.checkpoints.list[i-1]
+			label = fmt.Sprintf("  %s  %s  %s", checkpoint.Time.Format("15:04:05"), checkpoint.Short(), checkpoint.Message)
+		}
+		if i == m.checkpoints.mark {
+			label = markStyle.Render("*" + label[1:])
+		}
+		if i == m.checkpoints.selected {
+			label = selectedStyle.Render(label)
+		}
+		body = append(body, label)
+	}
+	body = append(body, "")
+	body = append(body, "j/k to move, space to mark the start,")
+	body = append(body, "Enter to diff, s to snapshot now, r to restore,")
+	body = append(body, "Esc to close.")
+	return body
+}
+
+func (m Model) handleRestoreKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.modal = modalTimeline
+		m.modalErr = ""
+	case "enter":
+		m.modalErr = ""
+		return m, m.restoreCmd(m.checkpoints.restore.ID)
+	}
+	return m, nil
+}
+
+func (m Model) renderRestore() []string {
+	checkpoint := m.checkpoints.restore
+	return []string{
+		"Reset tracked and untracked files to checkpoint",
+		fmt.Sprintf("%s from %s (%s)?", checkpoint.Short(), checkpoint.Time.Format("15:04:05"), checkpoint.Message),
+		"",
+		"The current worktree is saved as a checkpoint first.",
+		"Ignored files and the index are left untouched.",
+		"",
+		"Enter to restore, Esc to go back.",
+	}
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"wing/internal/git"
+)
+
+func TestObserveWorktreeWaitsForQuietRefresh(t *testing.T) {
+	m := New(Config{Checkpoints: true})
+	if cmd := m.observeWorktree("a"); cmd != nil {
+		t.Fatalf("expected no checkpoint while changes are arriving")
+	}
+	if cmd := m.observeWorktree("b"); cmd != nil {
+		t.Fatalf("expected no checkpoint while changes are arriving")
+	}
+	if cmd := m.observeWorktree("b"); cmd == nil {
+		t.Fatalf("expected checkpoint once the worktree settles")
+	}
+	if cmd := m.observeWorktree("b"); cmd != nil {
+		t.Fatalf("expected a single checkpoint per burst")
+	}
+
+	disabled := New(Config{})
+	disabled.observeWorktree("a")
+	if cmd := disabled.observeWorktree("a"); cmd != nil {
+		t.Fatalf("expected no checkpoints when disabled")
+	}
+}
+
+func TestTimelineBase(t *testing.T) {
+	m := New(Config{})
+	m.checkpoints.list = []git.Checkpoint{
+		{ID: "cccccccccccc"},
+		{ID: "bbbbbbbbbbbb"},
+		{ID: "aaaaaaaaaaaa"},
+	}
+	m.checkpoints.mark = -1
+
+	m.checkpoints.selected = 0
+	if base, problem := m.timelineBase(); problem != "" || base != "cccccccc" {
+		t.Fatalf("expected worktree against latest checkpoint, got %q %q", base, problem)
+	}
+
+	m.checkpoints.selected = 2
+	if base, _ := m.timelineBase(); base != "aaaaaaaa..bbbbbbbb" {
+		t.Fatalf("expected step diff, got %q", base)
+	}
+
+	m.checkpoints.mark = 1
+	m.checkpoints.selected = 3
+	if base, _ := m.timelineBase(); base != "aaaaaaaa..cccccccc" {
+		t.Fatalf("expected marked range ordered oldest first, got %q", base)
+	}
+
+	m.checkpoints.mark = -1
+	if _, problem := m.timelineBase(); problem == "" {
+		t.Fatalf("expected the oldest checkpoint to have nothing before it")
+	}
+}
+
+func TestRestoreNeedsCheckpointSelection(t *testing.T) {
+	m := New(Config{})
+	m.modal = modalTimeline
+	m.checkpoints.list = []git.Checkpoint{{ID: "cccccccccccc", Message: "1 file: a.go"}}
+
+	next, _ := m.handleTimelineKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	m = next.(Model)
+	if m.modal != modalTimeline || m.modalErr == "" {
+		t.Fatalf("expected restore of the live worktree to be refused")
+	}
+
+	next, _ = m.handleTimelineKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")})
+	next, _ = next.(Model).handleTimelineKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	m = next.(Model)
+	if m.modal != modalRestore || m.checkpoints.restore.ID != "cccccccccccc" {
+		t.Fatalf("expected restore confirmation for the checkpoint, got modal %v", m.modal)
+	}
+
+	next, _ = m.handleRestoreKey(tea.KeyMsg{Type: tea.KeyEsc})
+	if next.(Model).modal != modalTimeline {
+		t.Fatalf("expected esc to return to the timeline")
+	}
+}
+
+func TestTimelineUsesTheBackend(t *testing.T) {
+	fake := fakeRepo()
+	h := newHarness(t, fake, 100, 30)
+	h.model.config.Checkpoints = true
+	h.send(h.model.checkpointCmd()())
+	h.press("t")
+	if len(h.model.checkpoints.list) != 1 || len(fake.Saved) != 1 {
+		t.Fatalf("expected the fake's checkpoint in the timeline, got %+v", h.model.checkpoints.list)
+	}
+	saved := h.model.checkpoints.list[0].ID
+	h.press("j", "r", "enter")
+	if len(fake.Restored) != 1 || fake.Restored[0] != saved {
+		t.Fatalf("expected the checkpoint restored through the backend, got %v", fake.Restored)
+	}
+}
@@ -0,0 +1,272 @@
+package app
+
+import (
+	"sort"
+	"strings"
+
+	"wing/internal/git"
+)
+
+// treeNode is a folder or file in the file tree. Folders keep their
+// children in path order and count the files below them, so a refresh only
+// touches the paths that changed and their ancestors.
+type treeNode struct {
+	name     string
+	path     string
+	parent   *treeNode
+	children []*treeNode
+	isDir    bool
+	depth    int
+	entry    git.StatusEntry
+	seen     uint64
+	// files and ignored count the files below a folder.
+	files   int
+	ignored int
+}
+
+// sortKey orders siblings the way a sorted path list would list them: a
+// folder sorts as its name plus a slash, ahead of "a0" and behind "a.go".
+func (n *treeNode) sortKey() string {
+	if n.isDir {
+		return n.name + "/"
+	}
+	return n.name
+}
+
+// fileTree is the persistent tree behind the Files pane. It mirrors the
+// last file list it was synced with; rows are flattened from it on demand,
+// descending only into expanded folders.
+type fileTree struct {
+	root   *treeNode
+	files  map[string]*treeNode
+	dirs   map[string]*treeNode
+	source []git.StatusEntry
+	pass   uint64
+}
+
+func newFileTree() *fileTree {
+	return &fileTree{
+		root:  &treeNode{isDir: true, depth: -1},
+		files: make(map[string]*treeNode),
+		dirs:  make(map[string]*treeNode),
+	}
+}
+
+// sync updates the tree to list exactly files, inserting, updating and
+// removing only the entries that differ. New folders start collapsed.
+func (t *fileTree) sync(files []git.StatusEntry, collapsed map[string]bool) {
+	if sameSlice(t.source, files) {
+		return
+	}
+	t.source = files
+	t.pass++
+	seen := 0
+	for _, entry := range files {
+		if entry.Path == "" {
+			continue
+		}
+		node, ok := t.files[entry.Path]
+		if !ok {
+			node = t.insert(entry, collapsed)
+		} else if node.entry != entry {
+			if node.entry.Ignored != entry.Ignored {
+				delta := 1
+				if !entry.Ignored {
+					delta = -1
+				}
+				for dir := node.parent; dir != nil; dir = dir.parent {
+					dir.ignored += delta
+				}
+			}
+			node.entry = entry
+		}
+		if node.seen != t.pass {
+			node.seen = t.pass
+			seen++
+		}
+	}
+	if seen == len(t.files) {
+		return
+	}
+	for path, node := range t.files {
+		if node.seen != t.pass {
+			t.remove(path, node)
+		}
+	}
+}
+
+// sameSlice reports whether a and b share their backing array, i.e. the
+// tree is already synced with this very file list.
+func sameSlice(a, b []git.StatusEntry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	return len(a) == 0 || &a[0] == &b[0]
+}
+
+func (t *fileTree) insert(entry git.StatusEntry, collapsed map[string]bool) *treeNode {
+	parent := t.root
+	rest := entry.Path
+	for {
+		name, tail, ok := strings.Cut(rest, "/")
+		if !ok {
+			break
+		}
+		dirPath := name
+		if parent != t.root {
+			dirPath = parent.path + "/" + name
+		}
+		dir, exists := t.dirs[dirPath]
+		if !exists {
+			dir = &treeNode{name: name, path: dirPath, parent: parent, isDir: true, depth: parent.depth + 1}
+			t.dirs[dirPath] = dir
+			parent.addChild(dir)
+			if _, ok := collapsed[dirPath]; !ok {
+				collapsed[dirPath] = true
+			}
+		}
+		parent = dir
+		rest = tail
+	}
+	node := &treeNode{name: rest, path: entry.Path, parent: parent, depth: parent.depth + 1, entry: entry}
+	parent.addChild(node)
+	t.files[entry.Path] = node
+	for dir := parent; dir != nil; dir = dir.parent {
+		dir.files++
+		if entry.Ignored {
+			dir.ignored++
+		}
+	}
+	return node
+}
+
+// addChild inserts child in order. Sorted input always appends.
+func (n *treeNode) addChild(child *treeNode) {
+	key := child.sortKey()
+	count := len(n.children)
+	if count == 0 || n.children[count-1].sortKey() < key {
+		n.children = append(n.children, child)
+		return
+	}
+	i := sort.Search(count, func(i int) bool { return n.children[i].sortKey() >= key })
+	n.children = append(n.children, nil)
+	copy(n.children[i+1:], n.children[i:])
+	n.children[i] = child
+}
+
+func (n *treeNode) removeChild(child *treeNode) {
+	key := child.sortKey()
+	i := sort.Search(len(n.children), func(i int) bool { return n.children[i].sortKey() >= key })
+	if i < len(n.children) && n.children[i] == child {
+		n.children = append(n.children[:i], n.children[i+1:]...)
+	}
+}
+
+// remove drops a file and any folders it leaves empty.
+func (t *fileTree) remove(path string, node *treeNode) {
+	delete(t.files, path)
+	for dir := node.parent; dir != nil; dir = dir.parent {
+		dir.files--
+		if node.entry.Ignored {
+			dir.ignored--
+		}
+	}
+	child := node
+	for parent := node.parent; parent != nil; parent = parent.parent {
+		parent.removeChild(child)
+		if parent == t.root || len(parent.children) > 0 {
+			break
+		}
+		delete(t.dirs, parent.path)
+		child = parent
+	}
+}
+
+// rows flattens the tree into the visible rows: descendants of collapsed
+// folders are skipped without being visited, and hidden files (with the
+// folders left empty by them) are left out. Siblings follow order, or path
+// order when it is nil.
+func (t *fileTree) rows(collapsed map[string]bool, hidden func(path string) bool, order *rowOrder) []fileRow {
+	var rows []fileRow
+	var walk func(dir *treeNode)
+	walk = func(dir *treeNode) {
+		for _, node := range order.sorted(dir.children) {
+			if !node.isDir {
+				if hidden != nil && hidden(node.path) {
+					continue
+				}
+				rows = append(rows, fileRow{
+					Path:      node.path,
+					OrigPath:  node.entry.OrigPath,
+					Name:      node.name,
+					Status:    node.entry.Status,
+					Ignored:   node.entry.Ignored,
+					IndexHash: node.entry.IndexHash,
+					Depth:     node.depth,
+				})
+				continue
+			}
+			if hidden != nil && !node.hasVisible(hidden) {
+				continue
+			}
+			rows = append(rows, fileRow{
+				Path:      node.path,
+				Name:      node.name,
+				IsDir:     true,
+				Collapsed: collapsed[node.path],
+				Ignored:   node.files > 0 && node.ignored == node.files,
+				Depth:     node.depth,
+			})
+			if !collapsed[node.path] {
+				walk(node)
+			}
+		}
+	}
+	walk(t.root)
+	return rows
+}
+
+// flatRows lists every file that is not hidden without folders, named by
+// its full path, in order or path order when it is nil.
+func (t *fileTree) flatRows(hidden func(path string) bool, order *rowOrder) []fileRow {
+	nodes := make([]*treeNode, 0, len(t.files))
+	var walk func(dir *treeNode)
+	walk = func(dir *treeNode) {
+		for _, node := range dir.children {
+			if node.isDir {
+				walk(node)
+			} else if hidden == nil || !hidden(node.path) {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	walk(t.root)
+	nodes = order.sorted(nodes)
+	rows := make([]fileRow, len(nodes))
+	for i, node := range nodes {
+		rows[i] = fileRow{
+			Path:      node.path,
+			OrigPath:  node.entry.OrigPath,
+			Name:      node.path,
+			Status:    node.entry.Status,
+			Ignored:   node.entry.Ignored,
+			IndexHash: node.entry.IndexHash,
+		}
+	}
+	return rows
+}
+
+// hasVisible reports whether any file below n is not hidden, stopping at
+// the first one.
+func (n *treeNode) hasVisible(hidden func(path string) bool) bool {
+	for _, child := range n.children {
+		if child.isDir {
+			if child.hasVisible(hidden) {
+				return true
+			}
+		} else if !hidden(child.path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"wing/internal/git"
+)
+
+func rowPaths(rows []fileRow) []string {
+	paths := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if row.IsDir {
+			paths = append(paths, row.Path+"/")
+		} else {
+			paths = append(paths, row.Path)
+		}
+	}
+	return paths
+}
+
+func expectPaths(t *testing.T, rows []fileRow, want ...string) {
+	t.Helper()
+	got := rowPaths(rows)
+	if len(got) != len(want) {
+		t.Fatalf("expected rows %q, got %q", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected rows %q, got %q", want, got)
+		}
+	}
+}
+
+func TestTreeOrdersFoldersLikeSortedPaths(t *testing.T) {
+	collapsed := map[string]bool{"a": false}
+	tree := newFileTree()
+	tree.sync([]git.StatusEntry{{Path: "a.go"}, {Path: "a/x.go"}, {Path: "a0"}}, collapsed)
+	expectPaths(t, tree.rows(collapsed, nil, nil), "a.go", "a/", "a/x.go", "a0")
+
+	// Out-of-order inserts land in the same place.
+	tree = newFileTree()
+	tree.sync([]git.StatusEntry{{Path: "a0"}, {Path: "a/x.go"}, {Path: "a.go"}}, collapsed)
+	expectPaths(t, tree.rows(collapsed, nil, nil), "a.go", "a/", "a/x.go", "a0")
+}
+
+func TestTreeSyncAppliesOnlyDeltas(t *testing.T) {
+	collapsed := map[string]bool{}
+	tree := newFileTree()
+	tree.sync([]git.StatusEntry{
+		{Path: "docs/a.md"},
+		{Path: "docs/old/b.md"},
+		{Path: "src/main.go", Status: "M"},
+	}, collapsed)
+	kept := tree.files["docs/a.md"]
+	if !collapsed["docs"] || !collapsed["docs/old"] {
+		t.Fatalf("expected new folders to start collapsed, got %v", collapsed)
+	}
+	expectPaths(t, tree.rows(collapsed, nil, nil), "docs/", "src/")
+
+	collapsed["docs"] = false
+	collapsed["src"] = false
+	tree.sync([]git.StatusEntry{
+		{Path: "docs/a.md"},
+		{Path: "src/main.go", Status: "A"},
+		{Path: "src/new.go", Status: "??"},
+	}, collapsed)
+	if tree.files["docs/a.md"] != kept {
+		t.Fatalf("expected an unchanged file to keep its node")
+	}
+	if _, ok := tree.dirs["docs/old"]; ok {
+		t.Fatalf("expected the emptied folder to be pruned")
+	}
+	rows := tree.rows(collapsed, nil, nil)
+	expectPaths(t, rows, "docs/", "docs/a.md", "src/", "src/main.go", "src/new.go")
+	if rows[3].Status != "A" {
+		t.Fatalf("expected the status update, got %+v", rows[3])
+	}
+	if tree.dirs["src"].files != 2 || tree.root.files != 3 {
+		t.Fatalf("expected file counts to follow, got src=%d root=%d", tree.dirs["src"].files, tree.root.files)
+	}
+}
+
+func TestTreeIgnoredFolderFollowsUpdates(t *testing.T) {
+	collapsed := map[string]bool{}
+	tree := newFileTree()
+	tree.sync([]git.StatusEntry{{Path: "out/a.o", Ignored: true}, {Path: "out/b.o"}}, collapsed)
+	if rows := tree.rows(collapsed, nil, nil); rows[0].Ignored {
+		t.Fatalf("expected out/ with a tracked file not to be ignored")
+	}
+	tree.sync([]git.StatusEntry{{Path: "out/a.o", Ignored: true}, {Path: "out/b.o", Ignored: true}}, collapsed)
+	if rows := tree.rows(collapsed, nil, nil); !rows[0].Ignored {
+		t.Fatalf("expected out/ to be ignored once every file is")
+	}
+}
+
+func TestTreeRowsHideFilesAndEmptyFolders(t *testing.T) {
+	collapsed := map[string]bool{}
+	tree := newFileTree()
+	tree.sync([]git.StatusEntry{{Path: "done/a.go"}, {Path: "todo/b.go"}, {Path: "top.go"}}, collapsed)
+	hidden := func(path string) bool { return path == "done/a.go" || path == "top.go" }
+	expectPaths(t, tree.rows(collapsed, hidden, nil), "todo/")
+}
+
+func TestFilesPaneRendersOnlyVisibleRows(t *testing.T) {
+	m := New(Config{})
+	m.width = 80
+	m.height = 12
+	for i := 0; i < 2000; i++ {
+		m.files = append(m.files, git.StatusEntry{Path: fmt.Sprintf("file%04d", i)})
+	}
+	m.rebuildRows()
+	m.selected = 1500
+	m.ensureSelectionVisible()
+	view := m.renderFiles(30, m.paneHeight())
+	if !strings.Contains(view, "file1500") || strings.Contains(view, "file0000") {
+		t.Fatalf("expected the window around the selection, got\n%s", view)
+	}
+}
@@ -0,0 +1,252 @@
+package app
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+
+	"wing/internal/git"
+	"wing/internal/git/gittest"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// cmdTimeout bounds how long the harness waits for a command. Timers fire
+// at once and cursors do not blink under the harness, so only a command
+// that hangs takes this long.
+const cmdTimeout = 10 * time.Second
+
+// harness drives a Model the way the bubbletea runtime would: every
+// message goes through Update and the commands it returns are run to
+// completion before the next step.
+type harness struct {
+	t     *testing.T
+	model Model
+}
+
+func newHarness(t *testing.T, backend git.Backend, width, height int) *harness {
+	t.Helper()
+	lipgloss.SetColorProfile(termenv.Ascii)
+	h := &harness{t: t, model: New(Config{
+		RepoPath:      t.TempDir(),
+		RefreshPeriod: time.Hour,
+		Backend:       backend,
+	})}
+	// Timers fire at once with their message, which run drops, and cursors
+	// stay still, so no command waits on the clock.
+	h.model.tick = func(_ time.Duration, fn func(time.Time) tea.Msg) tea.Cmd {
+		return func() tea.Msg { return fn(time.Now()) }
+	}
+	for _, input := range []*textinput.Model{&h.model.commitText, &h.model.baseText, &h.model.filterText, &h.model.commentText, &h.model.patchText} {
+		input.Cursor.SetMode(cursor.CursorStatic)
+	}
+	h.send(tea.WindowSizeMsg{Width: width, Height: height})
+	h.run(h.model.refreshCmd())
+	return h
+}
+
+func (h *harness) send(msg tea.Msg) {
+	h.t.Helper()
+	next, cmd := h.model.Update(msg)
+	h.model = next.(Model)
+	h.run(cmd)
+}
+
+func (h *harness) run(cmd tea.Cmd) {
+	h.t.Helper()
+	if cmd == nil {
+		return
+	}
+	done := make(chan tea.Msg, 1)
+	go func() { done <- cmd() }()
+	var msg tea.Msg
+	select {
+	case msg = <-done:
+	case <-time.After(cmdTimeout):
+		h.t.Fatalf("command did not return within %s", cmdTimeout)
+	}
+	switch msg := msg.(type) {
+	case nil, tea.QuitMsg, tickMsg:
+	case tea.BatchMsg:
+		for _, cmd := range msg {
+			h.run(cmd)
+		}
+	default:
+		h.send(msg)
+	}
+}
+
+// press sends keys by name ("enter", "esc", "tab", "up", "down") or as
+// typed text.
+func (h *harness) press(keys ...string) {
+	h.t.Helper()
+	named := map[string]tea.KeyType{
+		"enter": tea.KeyEnter,
+		"esc":   tea.KeyEsc,
+		"tab":   tea.KeyTab,
+		"up":    tea.KeyUp,
+		"down":  tea.KeyDown,
+		"space": tea.KeySpace,
+	}
+	for _, key := range keys {
+		if keyType, ok := named[key]; ok {
+			h.send(tea.KeyMsg{Type: keyType})
+			continue
+		}
+		h.send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	}
+}
+
+func (h *harness) resize(width, height int) {
+	h.t.Helper()
+	h.send(tea.WindowSizeMsg{Width: width, Height: height})
+}
+
+// expectGolden compares the rendered view with testdata/<name>.golden;
+// run the tests with -update to accept a new rendering.
+func (h *harness) expectGolden(name string) {
+	h.t.Helper()
+	view := h.model.View()
+	path := filepath.Join("testdata", name+".golden")
+	if *updateGolden {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			h.t.Fatalf("mkdir testdata: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(view), 0o644); err != nil {
+			h.t.Fatalf("write %s: %v", path, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		h.t.Fatalf("read %s: %v (run go test -update to create it)", path, err)
+	}
+	if view != string(want) {
+		h.t.Fatalf("view does not match %s:\n--- got\n%s\n--- want\n%s", path, view, want)
+	}
+}
+
+func fakeRepo() *gittest.Fake {
+	return &gittest.Fake{
+		Info: git.BranchInfo{Head: "main", Upstream: "origin/main", Ahead: 1},
+		Entries: []git.StatusEntry{
+			{Path: "cmd/wing/main.go", Status: "M"},
+			{Path: "internal/app/app.go", Status: "M"},
+			{Path: "notes.md", Status: "??"},
+		},
+		Tracked: []string{"README.md", "cmd/wing/main.go", "go.mod", "internal/app/app.go"},
+		Ignored: []string{"wing.log"},
+		Diffs: map[string]string{
+			"cmd/wing/main.go": strings.Join([]string{
+				"diff --git a/cmd/wing/main.go b/cmd/wing/main.go",
+				"--- a/cmd/wing/main.go",
+				"+++ b/cmd/wing/main.go",
+				"@@ -1,4 +1,4 @@",
+				" package main",
+				" ",
+				"-var version = \"dev\"",
+				"+var version = \"1.0\"",
+				" ",
+			}, "\n"),
+			"internal/app/app.go": strings.Join([]string{
+				"diff --git a/internal/app/app.go b/internal/app/app.go",
+				"--- a/internal/app/app.go",
+				"+++ b/internal/app/app.go",
+				"@@ -10,3 +10,4 @@ func New() {",
+				" \tm := Model{}",
+				"+\tm.ready = true",
+				" \treturn m",
+				" }",
+			}, "\n"),
+			"notes.md": strings.Join([]string{
+				"diff --git a/notes.md b/notes.md",
+				"new file mode 100644",
+				"--- /dev/null",
+				"+++ b/notes.md",
+				"@@ -0,0 +1 @@",
+				"+# Notes",
+			}, "\n"),
+		},
+		Files: map[string]string{
+			"README.md":           "# wing\n",
+			"cmd/wing/main.go":    "package main\n\nvar version = \"1.0\"\n",
+			"go.mod":              "module wing\n",
+			"internal/app/app.go": "package app\n",
+			"notes.md":            "# Notes\n",
+		},
+	}
+}
+
+func TestViewExplorer(t *testing.T) {
+	h := newHarness(t, fakeRepo(), 80, 16)
+	h.expectGolden("explorer")
+
+	h.press("i")
+	h.expectGolden("explorer_ignored")
+}
+
+func TestViewDiffNavigation(t *testing.T) {
+	h := newHarness(t, fakeRepo(), 90, 16)
+	h.press("m")
+	h.expectGolden("diff")
+
+	h.press("j", "j")
+	h.expectGolden("diff_second_file")
+
+	h.press("k", "k", "k", "space")
+	h.expectGolden("diff_collapsed")
+}
+
+func TestViewResize(t *testing.T) {
+	h := newHarness(t, fakeRepo(), 90, 16)
+	h.press("m")
+	h.resize(60, 10)
+	h.expectGolden("diff_narrow")
+}
+
+func TestViewHelpModal(t *testing.T) {
+	h := newHarness(t, fakeRepo(), 80, 40)
+	h.press("h")
+	h.expectGolden("help")
+	h.press("esc")
+	if h.model.modal != modalNone {
+		t.Fatalf("expected esc to close help")
+	}
+}
+
+func TestCommitAndPushFlow(t *testing.T) {
+	fake := fakeRepo()
+	h := newHarness(t, fake, 80, 16)
+	h.press("m", "enter")
+	h.press("tidy up")
+	h.expectGolden("commit_modal")
+
+	h.press("enter")
+	if len(fake.Commits) != 1 || fake.Commits[0] != "tidy up" {
+		t.Fatalf("expected one commit, got %q", fake.Commits)
+	}
+	if h.model.modal != modalPush {
+		t.Fatalf("expected push prompt after committing, got modal %v", h.model.modal)
+	}
+	h.press("enter")
+	if fake.Pushes != 1 {
+		t.Fatalf("expected a push, got %d", fake.Pushes)
+	}
+	h.expectGolden("committed")
+}
+
+func TestViewShowsBackendErrors(t *testing.T) {
+	fake := fakeRepo()
+	fake.Err = os.ErrPermission
+	h := newHarness(t, fake, 80, 12)
+	h.expectGolden("error")
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"wing/internal/git"
+)
+
+// rebuildRows syncs the tree with m.files and lays out the visible rows,
+// dropping viewed files when they are hidden and files the filter leaves
+// out, and flagging the rest with their viewed mark and line counts.
+func (m *Model) rebuildRows() {
+	if m.collapsed == nil {
+		m.collapsed = make(map[string]bool)
+	}
+	if m.tree == nil {
+		m.tree = newFileTree()
+	}
+	m.tree.sync(m.files, m.collapsed)
+	var hidden func(path string) bool
+	switch {
+	case m.hideViewed && m.filter.active():
+		hidden = func(path string) bool { return m.isViewed(path) || m.filteredOut(path) }
+	case m.hideViewed:
+		hidden = m.isViewed
+	case m.filter.active():
+		hidden = m.filteredOut
+	}
+	if m.flat {
+		m.rows = m.tree.flatRows(hidden, m.rowOrder())
+	} else {
+		m.rows = m.tree.rows(m.collapsed, hidden, m.rowOrder())
+	}
+	dirs := dirStats(m.stats)
+	for i := range m.rows {
+		if m.rows[i].IsDir {
+			m.rows[i].Stat = dirs[m.rows[i].Path]
+		} else {
+			m.rows[i].Viewed = m.isViewed(m.rows[i].Path)
+			m.rows[i].Stat = m.stats[m.rows[i].Path]
+		}
+	}
+	m.markRecentRows(time.Now())
+}
+
+func (m Model) isViewed(path string) bool {
+	hash, ok := m.hashes[path]
+	return ok && m.review.IsViewed(path, hash)
+}
+
+// toggleViewed flips the viewed mark of the selected changed file.
+func (m *Model) toggleViewed() tea.Cmd {
+	entry, ok := m.selectedEntry()
+	if !ok {
+		return nil
+	}
+	hash, ok := m.hashes[entry.Path]
+	if !ok || hash == "" {
+		m.notice = "Only changed files can be marked viewed."
+		return nil
+	}
+	m.review.SetViewed(entry.Path, hash, !m.isViewed(entry.Path))
+	m.refreshRowsKeepingIndex()
+	return m.saveReview()
+}
+
+func (m *Model) toggleHideViewed() {
+	m.hideViewed = !m.hideViewed
+	m.refreshRowsKeepingIndex()
+}
+
+// refreshRowsKeepingIndex rebuilds rows and keeps the selection on the same
+// row, or on the row that took its place when it was hidden.
+func (m *Model) refreshRowsKeepingIndex() {
+	selectedKey := m.selectedKey()
+	index := m.selected
+	m.rebuildRows()
+	m.selected = indexForKey(m.rows, selectedKey)
+	if rowKeyIndex(m.rows, selectedKey) == -1 {
+		m.selected = clampCursor(index, len(m.rows))
+	}
+	m.fileOffset = clampOffset(m.fileOffset, len(m.rows), m.filesVisibleHeight())
+	m.ensureSelectionVisible()
+}
+
+func rowKeyIndex(rows []fileRow, key string) int {
+	for i, row := range rows {
+		if rowKey(row) == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// viewedProgress summarises how many changed files have been reviewed.
+func (m Model) viewedProgress() string {
+	if len(m.hashes) == 0 {
+		return ""
+	}
+	viewed := 0
+	for path := range m.hashes {
+		if m.isViewed(path) {
+			viewed++
+		}
+	}
+	label := fmt.Sprintf("viewed %d/%d", viewed, len(m.hashes))
+	if m.hideViewed {
+		label += " (hidden)"
+	}
+	return label
+}
+
+// changedPaths lists the paths with a status, whose content hashes key the
+// viewed marks.
+func changedPaths(statuses []git.StatusEntry) []string {
+	paths := make([]string, 0, len(statuses))
+	for _, entry := range statuses {
+		if entry.Status != "" && !entry.Ignored {
+			paths = append(paths, entry.Path)
+		}
+	}
+	return paths
+}
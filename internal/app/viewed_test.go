@@ -0,0 +1,61 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"wing/internal/git"
+)
+
+func viewedTestModel() Model {
+	m := New(Config{})
+	m.width = 120
+	m.height = 30
+	m.mode = modeDiff
+	m.files = []git.StatusEntry{
+		{Path: "a.go", Status: "M"},
+		{Path: "b.go", Status: "M"},
+		{Path: "c.go", Status: "??"},
+	}
+	m.hashes = map[string]string{"a.go": "h1", "b.go": "h2", "c.go": "h3"}
+	m.rebuildRows()
+	return m
+}
+
+func TestToggleViewed(t *testing.T) {
+	m := viewedTestModel()
+	if cmd := m.toggleViewed(); cmd == nil {
+		t.Fatalf("expected viewed mark to be saved")
+	}
+	if !m.rows[0].Viewed || m.rows[1].Viewed {
+		t.Fatalf("expected only a.go viewed, got %+v", m.rows)
+	}
+	if got := m.viewedProgress(); got != "viewed 1/3" {
+		t.Fatalf("unexpected progress %q", got)
+	}
+	if !strings.Contains(m.renderStatusBar(), "viewed 1/3") {
+		t.Fatalf("expected progress in status bar")
+	}
+
+	m.hashes["a.go"] = "h1-changed"
+	m.rebuildRows()
+	if m.rows[0].Viewed {
+		t.Fatalf("expected viewed mark to reset when the file changes")
+	}
+}
+
+func TestHideViewedKeepsSelectionPosition(t *testing.T) {
+	m := viewedTestModel()
+	m.toggleViewed()
+	m.toggleHideViewed()
+	if len(m.rows) != 2 || m.rows[0].Path != "b.go" {
+		t.Fatalf("expected viewed a.go hidden, got %+v", m.rows)
+	}
+	if m.selected != 0 {
+		t.Fatalf("expected selection to move to the next file, got %d", m.selected)
+	}
+	m.toggleHideViewed()
+	if len(m.rows) != 3 {
+		t.Fatalf("expected all files back, got %+v", m.rows)
+	}
+}
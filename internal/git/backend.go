@@ -0,0 +1,157 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Backend is the set of repository operations wing refreshes and commits
+// with. Each backend is bound to one repository. Reads take a context so a
+// request the UI no longer needs can be abandoned mid-flight.
+type Backend interface {
+	Status(ctx context.Context) ([]StatusEntry, BranchInfo, error)
+	// StatusAgainst compares with base; worktree is what Status returned,
+	// whose untracked files are listed for a base that is not a range.
+	StatusAgainst(ctx context.Context, base string, worktree []StatusEntry) ([]StatusEntry, error)
+	ListFiles(ctx context.Context, includeIgnored bool) ([]StatusEntry, error)
+	Diff(ctx context.Context, entry StatusEntry, opts DiffOptions) (string, error)
+	FileContents(ctx context.Context, path string) (string, error)
+	BlobContents(ctx context.Context, rev, path string) (string, error)
+	ReadFile(ctx context.Context, path string, limit int64) (FileData, error)
+	ReadBlob(ctx context.Context, rev, path string, limit int64) (FileData, error)
+	HashFiles(ctx context.Context, paths []string) (map[string]string, error)
+	NumStat(ctx context.Context, base string, untracked []string) (map[string]DiffStat, error)
+	Commit(message string) error
+	Push() error
+	Branch(ctx context.Context) (string, error)
+	VerifyBase(base string) error
+	// ResolveBase returns the commit ids base names, for keying what was
+	// loaded against it.
+	ResolveBase(ctx context.Context, base string) (string, error)
+	Patch(base string, entries []StatusEntry) (string, error)
+	FormatPatch(subject, patch string, date time.Time) (string, error)
+	CheckPatch(patch string) (PatchCheck, error)
+	ApplyPatch(patch string, mode ApplyMode) error
+	CreateCheckpoint() (Checkpoint, bool, error)
+	Checkpoints() ([]Checkpoint, error)
+	RestoreCheckpoint(id string) (Checkpoint, error)
+}
+
+// Backend names accepted by NewBackend.
+const (
+	BackendExec  = "exec"
+	BackendGoGit = "go-git"
+)
+
+// NewBackend opens repoPath with the named backend; an empty name selects
+// the exec backend.
+func NewBackend(name, repoPath string) (Backend, error) {
+	switch strings.TrimSpace(name) {
+	case "", BackendExec:
+		return ExecBackend{RepoPath: repoPath}, nil
+	case BackendGoGit:
+		return OpenGoGit(repoPath)
+	}
+	return nil, fmt.Errorf("unknown git backend %q (want %s or %s)", name, BackendExec, BackendGoGit)
+}
+
+// ExecBackend runs the git binary for every operation.
+type ExecBackend struct {
+	RepoPath string
+}
+
+func (b ExecBackend) Status(ctx context.Context) ([]StatusEntry, BranchInfo, error) {
+	return StatusWithBranchContext(ctx, b.RepoPath)
+}
+
+func (b ExecBackend) StatusAgainst(ctx context.Context, base string, worktree []StatusEntry) ([]StatusEntry, error) {
+	return StatusAgainstWorktree(ctx, b.RepoPath, base, worktree)
+}
+
+func (b ExecBackend) ListFiles(ctx context.Context, includeIgnored bool) ([]StatusEntry, error) {
+	return ListFilesContext(ctx, b.RepoPath, includeIgnored)
+}
+
+func (b ExecBackend) Diff(ctx context.Context, entry StatusEntry, opts DiffOptions) (string, error) {
+	return DiffContext(ctx, b.RepoPath, entry, opts)
+}
+
+func (b ExecBackend) FileContents(ctx context.Context, path string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return FileContents(b.RepoPath, path)
+}
+
+func (b ExecBackend) BlobContents(ctx context.Context, rev, path string) (string, error) {
+	return BlobContentsContext(ctx, b.RepoPath, rev, path)
+}
+
+func (b ExecBackend) ReadFile(ctx context.Context, path string, limit int64) (FileData, error) {
+	if err := ctx.Err(); err != nil {
+		return FileData{}, err
+	}
+	return ReadFile(b.RepoPath, path, limit)
+}
+
+func (b ExecBackend) ReadBlob(ctx context.Context, rev, path string, limit int64) (FileData, error) {
+	return ReadBlobContext(ctx, b.RepoPath, rev, path, limit)
+}
+
+func (b ExecBackend) HashFiles(ctx context.Context, paths []string) (map[string]string, error) {
+	return HashFilesContext(ctx, b.RepoPath, paths)
+}
+
+func (b ExecBackend) NumStat(ctx context.Context, base string, untracked []string) (map[string]DiffStat, error) {
+	return NumStatContext(ctx, b.RepoPath, base, untracked)
+}
+
+func (b ExecBackend) Commit(message string) error {
+	return Commit(b.RepoPath, message)
+}
+
+func (b ExecBackend) Push() error {
+	return Push(b.RepoPath)
+}
+
+func (b ExecBackend) Branch(ctx context.Context) (string, error) {
+	return BranchContext(ctx, b.RepoPath)
+}
+
+func (b ExecBackend) VerifyBase(base string) error {
+	return VerifyBase(b.RepoPath, base)
+}
+
+func (b ExecBackend) ResolveBase(ctx context.Context, base string) (string, error) {
+	return ResolveBaseContext(ctx, b.RepoPath, base)
+}
+
+func (b ExecBackend) Patch(base string, entries []StatusEntry) (string, error) {
+	return Patch(b.RepoPath, base, entries)
+}
+
+func (b ExecBackend) FormatPatch(subject, patch string, date time.Time) (string, error) {
+	return FormatPatch(b.RepoPath, subject, patch, date)
+}
+
+func (b ExecBackend) CheckPatch(patch string) (PatchCheck, error) {
+	return CheckPatch(b.RepoPath, patch)
+}
+
+func (b ExecBackend) ApplyPatch(patch string, mode ApplyMode) error {
+	return ApplyPatch(b.RepoPath, patch, mode)
+}
+
+func (b ExecBackend) CreateCheckpoint() (Checkpoint, bool, error) {
+	return CreateCheckpoint(b.RepoPath)
+}
+
+func (b ExecBackend) Checkpoints() ([]Checkpoint, error) {
+	return Checkpoints(b.RepoPath)
+}
+
+func (b ExecBackend) RestoreCheckpoint(id string) (Checkpoint, error) {
+	return RestoreCheckpoint(b.RepoPath, id)
+}
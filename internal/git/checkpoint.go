@@ -0,0 +1,311 @@
+package git
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CheckpointRef is the hidden ref holding the chain of worktree snapshots.
+// Each checkpoint is a commit whose parent is the previous checkpoint, so the
+// snapshots stay reachable without touching branches or the real index.
+const CheckpointRef = "refs/wing/checkpoints"
+
+// maxCheckpoints bounds how many snapshots Checkpoints lists.
+const maxCheckpoints = 500
+
+var checkpointIdentity = []string{
+	"GIT_AUTHOR_NAME=wing",
+	"GIT_AUTHOR_EMAIL=wing@localhost",
+	"GIT_COMMITTER_NAME=wing",
+	"GIT_COMMITTER_EMAIL=wing@localhost",
+}
+
+type Checkpoint struct {
+	ID      string
+	Tree    string
+	Time    time.Time
+	Message string
+}
+
+// Short returns the abbreviated checkpoint commit id.
+func (c Checkpoint) Short() string {
+	if len(c.ID) > 8 {
+		return c.ID[:8]
+	}
+	return c.ID
+}
+
+// Snapshot writes the current worktree, including untracked files that are
+// not ignored, as a tree object and returns its id. It stages into a copy of
+// the index so the user's staging area is left alone.
+func Snapshot(repoPath string) (string, error) {
+	gitDir, err := GitDir(repoPath)
+	if err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp("", "wing-index-*")
+	if err != nil {
+		return "", err
+	}
+	indexPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(indexPath)
+
+	if err := copyFile(filepath.Join(gitDir, "index"), indexPath); err != nil {
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		os.Remove(indexPath)
+	}
+
+	env := []string{"GIT_INDEX_FILE=" + indexPath}
+	if _, err := runEnv(repoPath, env, "add", "-A"); err != nil {
+		return "", err
+	}
+	tree, err := runEnv(repoPath, env, "write-tree")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(tree), nil
+}
+
+// CreateCheckpoint snapshots the worktree and appends it to CheckpointRef.
+// It reports false without creating anything when the worktree matches the
+// latest checkpoint.
+func CreateCheckpoint(repoPath string) (Checkpoint, bool, error) {
+	return createCheckpoint(repoPath, "")
+}
+
+// createCheckpoint is CreateCheckpoint with an optional message; an empty
+// message summarises the files changed since the previous checkpoint.
+func createCheckpoint(repoPath, message string) (Checkpoint, bool, error) {
+	tree, err := Snapshot(repoPath)
+	if err != nil {
+		return Checkpoint{}, false, err
+	}
+	latest, ok, err := latestCheckpoint(repoPath)
+	if err != nil {
+		return Checkpoint{}, false, err
+	}
+	if ok && latest.Tree == tree {
+		return latest, false, nil
+	}
+
+	args := []string{"commit-tree", tree}
+	if ok {
+		args = append(args, "-p", latest.ID)
+	}
+	if message == "" {
+		message = "session start"
+		if ok {
+			message = changeSummary(repoPath, latest.Tree, tree)
+		}
+	}
+	args = append(args, "-m", message)
+	id, err := runEnv(repoPath, checkpointIdentity, args...)
+	if err != nil {
+		return Checkpoint{}, false, err
+	}
+	id = strings.TrimSpace(id)
+
+	updateArgs := []string{"update-ref", "-m", "wing: checkpoint", CheckpointRef, id}
+	if ok {
+		updateArgs = append(updateArgs, latest.ID)
+	}
+	if _, err := run(repoPath, updateArgs...); err != nil {
+		return Checkpoint{}, false, err
+	}
+	return Checkpoint{ID: id, Tree: tree, Time: time.Now(), Message: message}, true, nil
+}
+
+// Checkpoints lists snapshots newest first. A repo without checkpoints
+// returns an empty list.
+func Checkpoints(repoPath string) ([]Checkpoint, error) {
+	if _, err := run(repoPath, "rev-parse", "--verify", "--quiet", CheckpointRef); err != nil {
+		return nil, nil
+	}
+	out, err := run(repoPath, "log", fmt.Sprintf("--max-count=%d", maxCheckpoints), "--format=%H %T %ct %s", CheckpointRef)
+	if err != nil {
+		return nil, err
+	}
+	return parseCheckpoints(out), nil
+}
+
+// RestoreCheckpoint resets the worktree, tracked and untracked files alike,
+// to the snapshot in checkpoint id. The current state is saved as a safety
+// checkpoint first and returned. Ignored files and the index are left alone.
+func RestoreCheckpoint(repoPath, id string) (Checkpoint, error) {
+	target, err := run(repoPath, "rev-parse", "--verify", "--quiet", id+"^{tree}")
+	if err != nil {
+		return Checkpoint{}, fmt.Errorf("unknown checkpoint: %s", id)
+	}
+	target = strings.TrimSpace(target)
+
+	short := id
+	if len(short) > 8 {
+		short = short[:8]
+	}
+	safety, _, err := createCheckpoint(repoPath, "before restore to "+short)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	if safety.Tree == target {
+		return safety, nil
+	}
+
+	current, err := treeFiles(repoPath, safety.Tree)
+	if err != nil {
+		return safety, err
+	}
+	wanted, err := treeFiles(repoPath, target)
+	if err != nil {
+		return safety, err
+	}
+
+	tmp, err := os.CreateTemp("", "wing-index-*")
+	if err != nil {
+		return safety, err
+	}
+	indexPath := tmp.Name()
+	tmp.Close()
+	os.Remove(indexPath)
+	defer os.Remove(indexPath)
+
+	env := []string{"GIT_INDEX_FILE=" + indexPath}
+	if _, err := runEnv(repoPath, env, "read-tree", target); err != nil {
+		return safety, err
+	}
+	if _, err := runEnv(repoPath, env, "checkout-index", "--all", "--force"); err != nil {
+		return safety, err
+	}
+
+	root, err := filepath.Abs(repoPath)
+	if err != nil {
+		return safety, err
+	}
+	for path := range current {
+		if _, ok := wanted[path]; ok {
+			continue
+		}
+		fullPath := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+			return safety, err
+		}
+		removeEmptyParents(root, filepath.Dir(fullPath))
+	}
+	return safety, nil
+}
+
+func treeFiles(repoPath, tree string) (map[string]struct{}, error) {
+	out, err := run(repoPath, "ls-tree", "-r", "--name-only", "-z", tree)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]struct{})
+	for _, path := range splitNullPaths(out) {
+		if path != "" {
+			files[path] = struct{}{}
+		}
+	}
+	return files, nil
+}
+
+// removeEmptyParents deletes dir and its ancestors up to root while they
+// are empty.
+func removeEmptyParents(root, dir string) {
+	for dir != root && strings.HasPrefix(dir, root) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func latestCheckpoint(repoPath string) (Checkpoint, bool, error) {
+	if _, err := run(repoPath, "rev-parse", "--verify", "--quiet", CheckpointRef); err != nil {
+		return Checkpoint{}, false, nil
+	}
+	out, err := run(repoPath, "log", "--max-count=1", "--format=%H %T %ct %s", CheckpointRef)
+	if err != nil {
+		return Checkpoint{}, false, err
+	}
+	checkpoints := parseCheckpoints(out)
+	if len(checkpoints) == 0 {
+		return Checkpoint{}, false, nil
+	}
+	return checkpoints[0], true, nil
+}
+
+func parseCheckpoints(out string) []Checkpoint {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	checkpoints := make([]Checkpoint, 0, len(lines))
+	for _, line := range lines {
+		fields := strings.SplitN(line, " ", 4)
+		if len(fields) < 3 {
+			continue
+		}
+		seconds, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		checkpoint := Checkpoint{ID: fields[0], Tree: fields[1], Time: time.Unix(seconds, 0)}
+		if len(fields) == 4 {
+			checkpoint.Message = fields[3]
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	return checkpoints
+}
+
+// changeSummary describes the files that differ between two trees, e.g.
+// "2 files: a.go, b.go".
+func changeSummary(repoPath, fromTree, toTree string) string {
+	out, err := run(repoPath, "diff-tree", "-r", "--name-only", "-z", fromTree, toTree)
+	if err != nil {
+		return "checkpoint"
+	}
+	paths := make([]string, 0)
+	for _, path := range splitNullPaths(out) {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return "checkpoint"
+	}
+	noun := "files"
+	if len(paths) == 1 {
+		noun = "file"
+	}
+	shown := paths
+	if len(shown) > 3 {
+		shown = shown[:3]
+	}
+	summary := fmt.Sprintf("%d %s: %s", len(paths), noun, strings.Join(shown, ", "))
+	if len(paths) > len(shown) {
+		summary += ", …"
+	}
+	return summary
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,149 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateCheckpointChain(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init")
+	writeFile(t, repo, "a.txt", "one\n")
+	runGit(t, repo, "add", "-A")
+	commitAll(t, repo, "first")
+
+	first, created, err := CreateCheckpoint(repo)
+	if err != nil {
+		t.Fatalf("CreateCheckpoint error: %v", err)
+	}
+	if !created || first.Message != "session start" {
+		t.Fatalf("expected initial checkpoint, got %+v created=%v", first, created)
+	}
+
+	if _, created, err = CreateCheckpoint(repo); err != nil || created {
+		t.Fatalf("expected unchanged worktree to be skipped, created=%v err=%v", created, err)
+	}
+
+	writeFile(t, repo, "a.txt", "two\n")
+	writeFile(t, repo, "new.txt", "new\n")
+	second, created, err := CreateCheckpoint(repo)
+	if err != nil || !created {
+		t.Fatalf("expected second checkpoint, created=%v err=%v", created, err)
+	}
+	if second.Message != "2 files: a.txt, new.txt" {
+		t.Fatalf("unexpected summary %q", second.Message)
+	}
+
+	checkpoints, err := Checkpoints(repo)
+	if err != nil {
+		t.Fatalf("Checkpoints error: %v", err)
+	}
+	if len(checkpoints) != 2 || checkpoints[0].ID != second.ID || checkpoints[1].ID != first.ID {
+		t.Fatalf("expected newest-first checkpoints, got %+v", checkpoints)
+	}
+
+	entries, err := StatusAgainst(repo, first.ID+".."+second.ID)
+	if err != nil {
+		t.Fatalf("StatusAgainst error: %v", err)
+	}
+	if !hasPath(entries, "a.txt") || !hasPath(entries, "new.txt") {
+		t.Fatalf("expected checkpoint range to list changes, got %+v", entries)
+	}
+
+	status, err := Status(repo)
+	if err != nil {
+		t.Fatalf("Status error: %v", err)
+	}
+	if entry, ok := entryForPath(status, "new.txt"); !ok || entry.Status != "??" {
+		t.Fatalf("expected snapshot to leave the index alone, got %+v", status)
+	}
+}
+
+func TestCheckpointsEmptyRepo(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init")
+	checkpoints, err := Checkpoints(repo)
+	if err != nil || len(checkpoints) != 0 {
+		t.Fatalf("expected no checkpoints, got %v err=%v", checkpoints, err)
+	}
+
+	writeFile(t, repo, "a.txt", "one\n")
+	checkpoint, created, err := CreateCheckpoint(repo)
+	if err != nil || !created {
+		t.Fatalf("expected checkpoint without HEAD, created=%v err=%v", created, err)
+	}
+	if !strings.HasPrefix(checkpoint.ID, checkpoint.Short()) || len(checkpoint.Short()) != 8 {
+		t.Fatalf("unexpected short id %q", checkpoint.Short())
+	}
+}
+
+func TestRestoreCheckpoint(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init")
+	writeFile(t, repo, "a.txt", "one\n")
+	writeFile(t, repo, ".gitignore", "*.log\n")
+	runGit(t, repo, "add", "-A")
+	commitAll(t, repo, "first")
+	writeFile(t, repo, "notes.txt", "keep me\n")
+
+	good, _, err := CreateCheckpoint(repo)
+	if err != nil {
+		t.Fatalf("CreateCheckpoint error: %v", err)
+	}
+
+	writeFile(t, repo, "a.txt", "broken\n")
+	writeFile(t, repo, "junk/stray.txt", "stray\n")
+	writeFile(t, repo, "debug.log", "ignored\n")
+	if err := os.Remove(filepath.Join(repo, "notes.txt")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	safety, err := RestoreCheckpoint(repo, good.ID)
+	if err != nil {
+		t.Fatalf("RestoreCheckpoint error: %v", err)
+	}
+	if !strings.HasPrefix(safety.Message, "before restore to ") {
+		t.Fatalf("expected safety checkpoint, got %+v", safety)
+	}
+
+	for name, want := range map[string]string{"a.txt": "one\n", "notes.txt": "keep me\n", "debug.log": "ignored\n"} {
+		data, err := os.ReadFile(filepath.Join(repo, name))
+		if err != nil || string(data) != want {
+			t.Fatalf("expected %s to be %q, got %q (%v)", name, want, data, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(repo, "junk")); !os.IsNotExist(err) {
+		t.Fatalf("expected stray directory to be removed, got %v", err)
+	}
+
+	if _, err := RestoreCheckpoint(repo, safety.ID); err != nil {
+		t.Fatalf("restore safety error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(repo, "junk", "stray.txt"))
+	if err != nil || string(data) != "stray\n" {
+		t.Fatalf("expected safety checkpoint to bring back stray.txt, got %q (%v)", data, err)
+	}
+}
+
+func TestStatusAgainstCheckpointListsUntrackedOnce(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init")
+	writeFile(t, repo, "a.txt", "one\n")
+	runGit(t, repo, "add", "-A")
+	commitAll(t, repo, "first")
+	writeFile(t, repo, "u.txt", "untracked\n")
+
+	checkpoint, _, err := CreateCheckpoint(repo)
+	if err != nil {
+		t.Fatalf("CreateCheckpoint error: %v", err)
+	}
+	entries, err := StatusAgainst(repo, checkpoint.ID)
+	if err != nil {
+		t.Fatalf("StatusAgainst error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Path != "u.txt" || entries[0].Status != "??" {
+		t.Fatalf("expected u.txt once as untracked, got %+v", entries)
+	}
+}
@@ -0,0 +1,118 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// binarySniffLen is how much of a file is checked for NUL bytes, the same
+// heuristic and length git uses to decide a file is binary.
+const binarySniffLen = 8000
+
+// FileData is the start of a file, read up to a limit so huge files never
+// have to fit in memory.
+type FileData struct {
+	Data []byte
+	// Size is the full size of the file in bytes.
+	Size      int64
+	Binary    bool
+	Truncated bool
+}
+
+// IsBinary reports whether data looks binary to git: a NUL byte within
+// its first 8000 bytes.
+func IsBinary(data []byte) bool {
+	if len(data) > binarySniffLen {
+		data = data[:binarySniffLen]
+	}
+	return bytes.IndexByte(data, 0) != -1
+}
+
+func newFileData(data []byte, size int64) FileData {
+	return FileData{Data: data, Size: size, Binary: IsBinary(data), Truncated: int64(len(data)) < size}
+}
+
+// ReadFile reads at most limit bytes of a worktree file.
+func ReadFile(repoPath, path string, limit int64) (FileData, error) {
+	file, err := os.Open(filepath.Join(repoPath, path))
+	if err != nil {
+		return FileData{}, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return FileData{}, err
+	}
+	if info.IsDir() {
+		return FileData{}, fmt.Errorf("%s is a directory", path)
+	}
+	data, err := io.ReadAll(io.LimitReader(file, limit))
+	if err != nil {
+		return FileData{}, err
+	}
+	return newFileData(data, info.Size()), nil
+}
+
+// ReadBlob reads at most limit bytes of path at rev, or as staged in the
+// index when rev is empty.
+func ReadBlob(repoPath, rev, path string, limit int64) (FileData, error) {
+	return ReadBlobContext(context.Background(), repoPath, rev, path, limit)
+}
+
+func ReadBlobContext(ctx context.Context, repoPath, rev, path string, limit int64) (FileData, error) {
+	object := rev + ":" + path
+	out, err := runContext(ctx, repoPath, "cat-file", "-s", object)
+	if err != nil {
+		return FileData{}, err
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64)
+	if err != nil {
+		return FileData{}, fmt.Errorf("git cat-file -s %s: %w", object, err)
+	}
+	data, err := runHead(ctx, repoPath, limit, "cat-file", "blob", object)
+	if err != nil {
+		return FileData{}, err
+	}
+	return newFileData(data, size), nil
+}
+
+// runHead runs git and keeps at most limit bytes of its output, stopping
+// git once they have been read.
+func runHead(ctx context.Context, repoPath string, limit int64, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", repoPath}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	data, readErr := io.ReadAll(io.LimitReader(stdout, limit))
+	full := readErr == nil && int64(len(data)) < limit
+	if !full {
+		// Everything wanted has been read; git may still be writing.
+		cancel()
+	}
+	waitErr := cmd.Wait()
+	if err := ctx.Err(); err != nil && full {
+		return nil, err
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	if full && waitErr != nil {
+		return nil, &CommandError{Args: args, Err: waitErr, Stderr: strings.TrimSpace(stderr.String())}
+	}
+	return data, nil
+}
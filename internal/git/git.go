@@ -2,7 +2,9 @@ package git
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -14,12 +16,62 @@ type StatusEntry struct {
 	Path   string
 	Status string
 	Ignored bool
+	// OrigPath is the source path of a rename or copy.
+	OrigPath string
+	// Index and Worktree are the porcelain v2 XY states, '.' when unchanged.
+	Index    byte
+	Worktree byte
+	// Submodule is "N..." for regular files, or "S" followed by the commit,
+	// tracked and untracked change flags for submodules.
+	Submodule string
+	// HeadMode, IndexMode and WorktreeMode are the octal file modes.
+	HeadMode     string
+	IndexMode    string
+	WorktreeMode string
+	// IndexHash is the blob id staged in the index, empty when the path is
+	// not tracked.
+	IndexHash string
+	// Score is the rename or copy similarity, e.g. "R100".
+	Score      string
+	Conflicted bool
+}
+
+// IsSubmodule reports whether the entry is a submodule.
+func (e StatusEntry) IsSubmodule() bool {
+	return strings.HasPrefix(e.Submodule, "S")
+}
+
+// BranchInfo is the branch header reported by git status --branch.
+type BranchInfo struct {
+	OID      string
+	Head     string
+	Upstream string
+	Ahead    int
+	Behind   int
+	Detached bool
 }
 
 func Status(repoPath string) ([]StatusEntry, error) {
-	out, err := run(repoPath, "status", "--porcelain")
+	return StatusContext(context.Background(), repoPath)
+}
+
+func StatusContext(ctx context.Context, repoPath string) ([]StatusEntry, error) {
+	entries, _, err := StatusWithBranchContext(ctx, repoPath)
+	return entries, err
+}
+
+// StatusWithBranch parses git status --porcelain=v2 -z --branch into
+// entries and the branch header in a single git call.
+func StatusWithBranch(repoPath string) ([]StatusEntry, BranchInfo, error) {
+	return StatusWithBranchContext(context.Background(), repoPath)
+}
+
+// StatusWithBranchContext is StatusWithBranch with a context that kills
+// git when it is cancelled.
+func StatusWithBranchContext(ctx context.Context, repoPath string) ([]StatusEntry, BranchInfo, error) {
+	out, err := runContext(ctx, repoPath, "status", "--porcelain=v2", "-z", "--branch")
 	if err != nil {
-		return nil, err
+		return nil, BranchInfo{}, err
 	}
 
 	basePath := repoPath
@@ -30,53 +82,146 @@ func Status(repoPath string) ([]StatusEntry, error) {
 		}
 	}
 
-	lines := strings.Split(strings.TrimSpace(out), "\n")
-	entries := make([]StatusEntry, 0, len(lines))
-	for _, line := range lines {
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-		if len(line) < 3 {
-			continue
-		}
-		status := strings.TrimSpace(line[:2])
-		path := strings.TrimSpace(line[2:])
-		if idx := strings.LastIndex(path, " -> "); idx != -1 {
-			path = strings.TrimSpace(path[idx+4:])
-		}
-		if status == "??" {
+	entries, branch := parseStatusV2(out)
+	expanded := make([]StatusEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Status == "??" && strings.HasSuffix(entry.Path, "/") {
+			path := strings.TrimSuffix(entry.Path, "/")
 			fullPath := path
 			if !filepath.IsAbs(fullPath) {
 				fullPath = filepath.Join(basePath, fullPath)
 			}
 			info, err := os.Stat(fullPath)
 			if err == nil && info.IsDir() {
-				entries = append(entries, expandUntrackedDir(basePath, path)...)
+				expanded = append(expanded, expandUntrackedDir(basePath, path)...)
 				continue
 			}
 		}
-		entries = append(entries, StatusEntry{Path: path, Status: status})
+		expanded = append(expanded, entry)
 	}
 
-	sort.Slice(entries, func(i, j int) bool {
-		return entries[i].Path < entries[j].Path
+	sort.Slice(expanded, func(i, j int) bool {
+		return expanded[i].Path < expanded[j].Path
 	})
 
-	return entries, nil
+	return expanded, branch, nil
+}
+
+func parseStatusV2(out string) ([]StatusEntry, BranchInfo) {
+	var branch BranchInfo
+	records := splitNullPaths(out)
+	entries := make([]StatusEntry, 0, len(records))
+	for i := 0; i < len(records); i++ {
+		record := records[i]
+		if len(record) < 2 {
+			continue
+		}
+		switch record[0] {
+		case '#':
+			parseBranchHeader(record, &branch)
+		case '1':
+			fields := strings.SplitN(record, " ", 9)
+			if len(fields) != 9 {
+				continue
+			}
+			entry := changedEntry(fields)
+			entry.Path = fields[8]
+			entries = append(entries, entry)
+		case '2':
+			fields := strings.SplitN(record, " ", 10)
+			if len(fields) != 10 {
+				continue
+			}
+			entry := changedEntry(fields)
+			entry.Score = fields[8]
+			entry.Path = fields[9]
+			if i+1 < len(records) {
+				i++
+				entry.OrigPath = records[i]
+			}
+			entries = append(entries, entry)
+		case 'u':
+			fields := strings.SplitN(record, " ", 11)
+			if len(fields) != 11 {
+				continue
+			}
+			entry := StatusEntry{
+				Path:         fields[10],
+				Status:       fields[1],
+				Index:        fields[1][0],
+				Worktree:     fields[1][1],
+				Submodule:    fields[2],
+				WorktreeMode: fields[6],
+				Conflicted:   true,
+			}
+			entries = append(entries, entry)
+		case '?':
+			entries = append(entries, StatusEntry{Path: record[2:], Status: "??", Index: '?', Worktree: '?'})
+		case '!':
+			entries = append(entries, StatusEntry{Path: record[2:], Status: "!!", Index: '!', Worktree: '!', Ignored: true})
+		}
+	}
+	return entries, branch
+}
+
+func changedEntry(fields []string) StatusEntry {
+	xy := fields[1]
+	return StatusEntry{
+		Status:       strings.TrimSpace(strings.ReplaceAll(xy, ".", " ")),
+		Index:        xy[0],
+		Worktree:     xy[1],
+		Submodule:    fields[2],
+		HeadMode:     fields[3],
+		IndexMode:    fields[4],
+		WorktreeMode: fields[5],
+		IndexHash:    objectID(fields[7]),
+	}
+}
+
+// objectID returns id, or "" for git's all-zero id of a missing object.
+func objectID(id string) string {
+	if strings.Trim(id, "0") == "" {
+		return ""
+	}
+	return id
+}
+
+func parseBranchHeader(record string, branch *BranchInfo) {
+	key, value, _ := strings.Cut(strings.TrimPrefix(record, "# "), " ")
+	switch key {
+	case "branch.oid":
+		if value != "(initial)" {
+			branch.OID = value
+		}
+	case "branch.head":
+		if value == "(detached)" {
+			branch.Detached = true
+			value = "HEAD"
+		}
+		branch.Head = value
+	case "branch.upstream":
+		branch.Upstream = value
+	case "branch.ab":
+		fmt.Sscanf(value, "+%d -%d", &branch.Ahead, &branch.Behind)
+	}
 }
 
 func ListFiles(repoPath string, includeIgnored bool) ([]StatusEntry, error) {
-	tracked, err := run(repoPath, "ls-files", "-z")
+	return ListFilesContext(context.Background(), repoPath, includeIgnored)
+}
+
+func ListFilesContext(ctx context.Context, repoPath string, includeIgnored bool) ([]StatusEntry, error) {
+	tracked, err := runContext(ctx, repoPath, "ls-files", "-z")
 	if err != nil {
 		return nil, err
 	}
-	untracked, err := run(repoPath, "ls-files", "--others", "--exclude-standard", "-z")
+	untracked, err := runContext(ctx, repoPath, "ls-files", "--others", "--exclude-standard", "-z")
 	if err != nil {
 		return nil, err
 	}
 	ignored := ""
 	if includeIgnored {
-		ignored, err = run(repoPath, "ls-files", "--others", "-i", "--exclude-standard", "-z")
+		ignored, err = runContext(ctx, repoPath, "ls-files", "--others", "-i", "--exclude-standard", "-z")
 		if err != nil {
 			return nil, err
 		}
@@ -127,13 +272,259 @@ func ListFiles(repoPath string, includeIgnored bool) ([]StatusEntry, error) {
 	return entries, nil
 }
 
-func Diff(repoPath, path, status string) (string, error) {
+// StatusAgainst lists the files that differ between base and the worktree.
+// A range such as "A..B" compares two commits and skips untracked files.
+func StatusAgainst(repoPath, base string) ([]StatusEntry, error) {
+	return StatusAgainstContext(context.Background(), repoPath, base)
+}
+
+func StatusAgainstContext(ctx context.Context, repoPath, base string) ([]StatusEntry, error) {
+	var worktree []StatusEntry
+	if strings.TrimSpace(base) == "" || !IsRange(base) {
+		var err error
+		if worktree, err = StatusContext(ctx, repoPath); err != nil {
+			return nil, err
+		}
+	}
+	return StatusAgainstWorktree(ctx, repoPath, base, worktree)
+}
+
+// StatusAgainstWorktree is StatusAgainst for a caller that has already read
+// the worktree status, so git status does not run twice: the untracked
+// files are taken from worktree.
+func StatusAgainstWorktree(ctx context.Context, repoPath, base string, worktree []StatusEntry) ([]StatusEntry, error) {
+	if strings.TrimSpace(base) == "" {
+		return worktree, nil
+	}
+	if err := checkBase(base); err != nil {
+		return nil, err
+	}
+	out, err := runContext(ctx, repoPath, "diff", "--name-status", "-z", "--find-renames", "--find-copies-harder", base)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := splitNullPaths(out)
+	entries := make([]StatusEntry, 0, len(fields)/2)
+	for i := 0; i < len(fields); i++ {
+		code := fields[i]
+		if code == "" {
+			continue
+		}
+		status := code[:1]
+		origPath := ""
+		if status == "R" || status == "C" {
+			i++
+			if i < len(fields) {
+				origPath = fields[i]
+			}
+		}
+		i++
+		if i >= len(fields) {
+			break
+		}
+		entries = append(entries, StatusEntry{Path: fields[i], Status: status, OrigPath: origPath})
+	}
+
+	if !IsRange(base) {
+		// git diff walks the index, so a file base has that is untracked
+		// now (as in a checkpoint, which snapshots untracked files) shows
+		// as deleted; list it once, as untracked.
+		untracked := make(map[string]bool)
+		for _, entry := range worktree {
+			if entry.Status == "??" {
+				untracked[entry.Path] = true
+			}
+		}
+		kept := entries[:0]
+		for _, entry := range entries {
+			if entry.Status != "D" || !untracked[entry.Path] {
+				kept = append(kept, entry)
+			}
+		}
+		entries = kept
+		for _, entry := range worktree {
+			if entry.Status == "??" {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
+	return entries, nil
+}
+
+// IsRange reports whether base names two commits ("A..B" or "A...B")
+// rather than a single commit compared against the worktree.
+func IsRange(base string) bool {
+	return strings.Contains(base, "..")
+}
+
+// RangeEnd returns the right-hand revision of a range such as "A..B",
+// defaulting to HEAD when it is omitted.
+func RangeEnd(base string) string {
+	idx := strings.LastIndex(base, "..")
+	if idx == -1 {
+		return ""
+	}
+	end := strings.TrimSpace(base[idx+2:])
+	if end == "" {
+		return "HEAD"
+	}
+	return end
+}
+
+// VerifyBase checks that every revision named by base resolves to a commit.
+func VerifyBase(repoPath, base string) error {
+	if err := checkBase(base); err != nil {
+		return err
+	}
+	for _, rev := range baseRevisions(base) {
+		if _, err := run(repoPath, "rev-parse", "--verify", "--quiet", rev+"^{commit}"); err != nil {
+			return fmt.Errorf("unknown revision: %s", rev)
+		}
+	}
+	return nil
+}
+
+// ResolveBase returns the commit ids base names, one per end of a range
+// separated by a space, so a fetch that moves a branch changes the result.
+// An empty base resolves to "".
+func ResolveBase(repoPath, base string) (string, error) {
+	return ResolveBaseContext(context.Background(), repoPath, base)
+}
+
+// ResolveBaseContext is ResolveBase with a context that kills git when it
+// is cancelled.
+func ResolveBaseContext(ctx context.Context, repoPath, base string) (string, error) {
+	if err := checkBase(base); err != nil {
+		return "", err
+	}
+	revs := baseRevisions(base)
+	if len(revs) == 0 {
+		return "", nil
+	}
+	args := []string{"rev-parse"}
+	for _, rev := range revs {
+		args = append(args, rev+"^{commit}")
+	}
+	out, err := runContext(ctx, repoPath, args...)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(out), " "), nil
+}
+
+// baseRevisions splits base into the revisions it names: one for a
+// single commit, or both ends of a range, an omitted end being HEAD.
+func baseRevisions(base string) []string {
+	base = strings.TrimSpace(base)
+	if base == "" {
+		return nil
+	}
+	revs := []string{base}
+	if IsRange(base) {
+		sep := ".."
+		if strings.Contains(base, "...") {
+			sep = "..."
+		}
+		revs = strings.SplitN(base, sep, 2)
+	}
+	for i, rev := range revs {
+		if rev == "" {
+			revs[i] = "HEAD"
+		}
+	}
+	return revs
+}
+
+// checkBase refuses a base git would read as an option, such as
+// --output=FILE, since bases are passed to git diff ahead of "--".
+func checkBase(base string) error {
+	for _, rev := range baseRevisions(base) {
+		if strings.HasPrefix(rev, "-") {
+			return fmt.Errorf("bad revision: %s", rev)
+		}
+	}
+	return nil
+}
+
+// Whitespace selects how Diff treats whitespace-only changes.
+type Whitespace int
+
+const (
+	WhitespaceShow Whitespace = iota
+	WhitespaceIgnoreChange
+	WhitespaceIgnoreAll
+)
+
+// DefaultContext asks Diff for git's default number of context lines.
+const DefaultContext = -1
+
+// DiffOptions tunes the git diff invocation used by Diff.
+type DiffOptions struct {
+	// Base is the ref or range to compare against; empty compares the
+	// worktree with the index.
+	Base       string
+	Whitespace Whitespace
+	// Context is the number of context lines, 0 for none;
+	// DefaultContext keeps git's default.
+	Context int
+	// Algorithm is passed to --diff-algorithm when set, e.g. "patience",
+	// "histogram" or "minimal".
+	Algorithm string
+}
+
+func (o DiffOptions) args() []string {
+	args := []string{"diff", "--no-color"}
+	switch o.Whitespace {
+	case WhitespaceIgnoreChange:
+		args = append(args, "--ignore-space-change")
+	case WhitespaceIgnoreAll:
+		args = append(args, "--ignore-all-space")
+	}
+	if o.Context >= 0 {
+		args = append(args, fmt.Sprintf("--unified=%d", o.Context))
+	}
+	if o.Algorithm != "" {
+		args = append(args, "--diff-algorithm="+o.Algorithm)
+	}
+	return args
+}
+
+// Diff returns the unified diff for entry. Renames and copies are diffed
+// against HEAD (or the base) together with their source path so git can
+// report the similarity and only the content that actually changed.
+func Diff(repoPath string, entry StatusEntry, opts DiffOptions) (string, error) {
+	return DiffContext(context.Background(), repoPath, entry, opts)
+}
+
+func DiffContext(ctx context.Context, repoPath string, entry StatusEntry, opts DiffOptions) (string, error) {
+	path := entry.Path
 	if path == "" {
 		return "", nil
 	}
 
-	args := []string{"diff", "--no-color", "--", path}
-	if status == "??" {
+	if err := checkBase(opts.Base); err != nil {
+		return "", err
+	}
+	args := opts.args()
+	if entry.OrigPath != "" {
+		base := opts.Base
+		if base == "" {
+			base = "HEAD"
+		}
+		args = append(args, "--find-renames", "--find-copies-harder", base, "--", entry.OrigPath, path)
+	} else {
+		if opts.Base != "" {
+			args = append(args, opts.Base)
+		}
+		args = append(args, "--", path)
+	}
+	if entry.Status == "??" {
 		targetPath := path
 		if !filepath.IsAbs(targetPath) {
 			basePath := repoPath
@@ -149,10 +540,10 @@ func Diff(repoPath, path, status string) (string, error) {
 		if _, err := os.Stat(targetPath); err != nil {
 			return "", fmt.Errorf("untracked file not found: %s", targetPath)
 		}
-		args = []string{"diff", "--no-color", "--no-index", "--", "/dev/null", targetPath}
+		args = append(opts.args(), "--no-index", "--", "/dev/null", targetPath)
 	}
 
-	out, err := run(repoPath, args...)
+	out, err := runContext(ctx, repoPath, args...)
 	if err != nil {
 		return "", err
 	}
@@ -183,6 +574,23 @@ func FileContents(repoPath, path string) (string, error) {
 	return strings.TrimRight(string(data), "\n"), nil
 }
 
+// BlobContents returns path as stored at rev, or as staged in the index
+// when rev is empty.
+func BlobContents(repoPath, rev, path string) (string, error) {
+	return BlobContentsContext(context.Background(), repoPath, rev, path)
+}
+
+func BlobContentsContext(ctx context.Context, repoPath, rev, path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	out, err := runContext(ctx, repoPath, "show", rev+":"+path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(out, "\n"), nil
+}
+
 func Commit(repoPath, message string) error {
 	if strings.TrimSpace(message) == "" {
 		return fmt.Errorf("commit message is required")
@@ -199,8 +607,62 @@ func Push(repoPath string) error {
 	return err
 }
 
+// DeletedHash is the content hash HashFiles reports for missing files.
+const DeletedHash = "deleted"
+
+// HashFiles returns the git blob id of each worktree path in one git call.
+// Paths that no longer exist map to DeletedHash; paths containing a newline
+// cannot be passed to git on stdin and are left out.
+func HashFiles(repoPath string, paths []string) (map[string]string, error) {
+	return HashFilesContext(context.Background(), repoPath, paths)
+}
+
+func HashFilesContext(ctx context.Context, repoPath string, paths []string) (map[string]string, error) {
+	hashes := make(map[string]string, len(paths))
+	existing := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if strings.Contains(path, "\n") {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(repoPath, path))
+		if err != nil || info.IsDir() {
+			hashes[path] = DeletedHash
+			continue
+		}
+		existing = append(existing, path)
+	}
+	if len(existing) == 0 {
+		return hashes, nil
+	}
+	out, err := runInputContext(ctx, repoPath, strings.Join(existing, "\n")+"\n", "hash-object", "--stdin-paths")
+	if err != nil {
+		return nil, err
+	}
+	ids := strings.Fields(out)
+	if len(ids) != len(existing) {
+		return nil, fmt.Errorf("git hash-object: expected %d hashes, got %d", len(existing), len(ids))
+	}
+	for i, path := range existing {
+		hashes[path] = ids[i]
+	}
+	return hashes, nil
+}
+
+// GitDir returns the absolute path of the repository's git directory.
+func GitDir(repoPath string) (string, error) {
+	out, err := run(repoPath, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
 func Branch(repoPath string) (string, error) {
-	out, err := run(repoPath, "rev-parse", "--abbrev-ref", "HEAD")
+	return BranchContext(context.Background(), repoPath)
+}
+
+func BranchContext(ctx context.Context, repoPath string) (string, error) {
+	out, err := runContext(ctx, repoPath, "rev-parse", "--abbrev-ref", "HEAD")
 	if err != nil {
 		return "", err
 	}
@@ -208,22 +670,74 @@ func Branch(repoPath string) (string, error) {
 }
 
 func run(repoPath string, args ...string) (string, error) {
+	return runEnv(repoPath, nil, args...)
+}
+
+// runContext is run with git killed when ctx is cancelled.
+func runContext(ctx context.Context, repoPath string, args ...string) (string, error) {
+	return runCommand(ctx, repoPath, nil, nil, args...)
+}
+
+// runEnv is run with extra environment variables, e.g. GIT_INDEX_FILE.
+func runEnv(repoPath string, env []string, args ...string) (string, error) {
+	return runCommand(context.Background(), repoPath, env, nil, args...)
+}
+
+// runInput is run with input fed to git on stdin.
+func runInput(repoPath, input string, args ...string) (string, error) {
+	return runInputContext(context.Background(), repoPath, input, args...)
+}
+
+func runInputContext(ctx context.Context, repoPath, input string, args ...string) (string, error) {
+	return runCommand(ctx, repoPath, nil, strings.NewReader(input), args...)
+}
+
+// runCommand runs git in repoPath. When ctx is cancelled git is killed and
+// the context's error is returned, so callers can tell a superseded request
+// from a failed one.
+func runCommand(ctx context.Context, repoPath string, env []string, stdin io.Reader, args ...string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 	base := append([]string{"-C", repoPath}, args...)
-	cmd := exec.Command("git", base...)
+	cmd := exec.CommandContext(ctx, "git", base...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stdin = stdin
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
 		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
 			if stdout.Len() > 0 {
 				return stdout.String(), nil
 			}
 		}
-		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+		return "", &CommandError{Args: args, Err: err, Stderr: strings.TrimSpace(stderr.String())}
 	}
 	return stdout.String(), nil
 }
 
+// CommandError is a failed git invocation together with what git printed
+// on stderr.
+type CommandError struct {
+	Args   []string
+	Err    error
+	Stderr string
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("git %s: %s: %s", strings.Join(e.Args, " "), e.Err, e.Stderr)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
 func splitNullPaths(out string) []string {
 	return strings.Split(strings.TrimRight(out, "\x00"), "\x00")
 }
@@ -1,12 +1,15 @@
 package git
 
 import (
+	"context"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestSplitNullPaths(t *testing.T) {
@@ -141,3 +144,534 @@ func entryForPath(entries []StatusEntry, path string) (StatusEntry, bool) {
 	}
 	return StatusEntry{}, false
 }
+
+func TestStatusAgainstBase(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init")
+	writeFile(t, repo, "a.txt", "one\n")
+	writeFile(t, repo, "b.txt", "one\n")
+	runGit(t, repo, "add", "-A")
+	commitAll(t, repo, "first")
+
+	writeFile(t, repo, "a.txt", "two\n")
+	runGit(t, repo, "add", "-A")
+	commitAll(t, repo, "second")
+	writeFile(t, repo, "b.txt", "two\n")
+	writeFile(t, repo, "new.txt", "new\n")
+
+	entries, err := StatusAgainst(repo, "HEAD~1")
+	if err != nil {
+		t.Fatalf("StatusAgainst error: %v", err)
+	}
+	for _, path := range []string{"a.txt", "b.txt", "new.txt"} {
+		if !hasPath(entries, path) {
+			t.Fatalf("expected %s against HEAD~1, got %+v", path, entries)
+		}
+	}
+
+	// A worktree status the caller already read is used as is.
+	worktree := []StatusEntry{{Path: "b.txt", Status: "M"}, {Path: "read.txt", Status: "??"}}
+	entries, err = StatusAgainstWorktree(context.Background(), repo, "HEAD~1", worktree)
+	if err != nil {
+		t.Fatalf("StatusAgainstWorktree error: %v", err)
+	}
+	if !hasPath(entries, "read.txt") || hasPath(entries, "new.txt") {
+		t.Fatalf("expected the untracked files of the given worktree, got %+v", entries)
+	}
+
+	entries, err = StatusAgainst(repo, "HEAD~1..HEAD")
+	if err != nil {
+		t.Fatalf("StatusAgainst range error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Path != "a.txt" || entries[0].Status != "M" {
+		t.Fatalf("expected only a.txt in range, got %+v", entries)
+	}
+
+	diff, err := Diff(repo, StatusEntry{Path: "a.txt", Status: "M"}, DiffOptions{Base: "HEAD~1"})
+	if err != nil {
+		t.Fatalf("Diff error: %v", err)
+	}
+	if !strings.Contains(diff, "+two") {
+		t.Fatalf("expected diff against base, got %q", diff)
+	}
+}
+
+func TestVerifyBase(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init")
+	writeFile(t, repo, "a.txt", "one\n")
+	runGit(t, repo, "add", "-A")
+	commitAll(t, repo, "first")
+
+	for _, base := range []string{"", "HEAD", "HEAD..HEAD", "HEAD...HEAD"} {
+		if err := VerifyBase(repo, base); err != nil {
+			t.Fatalf("expected %q to verify, got %v", base, err)
+		}
+	}
+	for _, base := range []string{"nope", "HEAD..nope"} {
+		if err := VerifyBase(repo, base); err == nil {
+			t.Fatalf("expected %q to fail verification", base)
+		}
+	}
+}
+
+func TestOptionLikeBasesAreRefused(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init")
+	writeFile(t, repo, "a.txt", "one\n")
+	runGit(t, repo, "add", "-A")
+	commitAll(t, repo, "first")
+	writeFile(t, repo, "a.txt", "two\n")
+
+	out := filepath.Join(t.TempDir(), "out")
+	for _, base := range []string{"--output=" + out, "HEAD..--output=" + out} {
+		if err := VerifyBase(repo, base); err == nil || !strings.Contains(err.Error(), "bad revision") {
+			t.Fatalf("expected %q to be refused, got %v", base, err)
+		}
+		if _, err := StatusAgainst(repo, base); err == nil {
+			t.Fatalf("expected StatusAgainst to refuse %q", base)
+		}
+		if _, err := Diff(repo, StatusEntry{Path: "a.txt", Status: "M"}, DiffOptions{Base: base}); err == nil {
+			t.Fatalf("expected Diff to refuse %q", base)
+		}
+		if _, err := NumStat(repo, base, nil); err == nil {
+			t.Fatalf("expected NumStat to refuse %q", base)
+		}
+		if _, err := ResolveBase(repo, base); err == nil {
+			t.Fatalf("expected ResolveBase to refuse %q", base)
+		}
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Fatalf("expected git never to write %s, got %v", out, err)
+	}
+}
+
+func TestResolveBaseFollowsTheBranch(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init")
+	writeFile(t, repo, "a.txt", "one\n")
+	runGit(t, repo, "add", "-A")
+	commitAll(t, repo, "first")
+	first, _ := run(repo, "rev-parse", "HEAD")
+	first = strings.TrimSpace(first)
+	runGit(t, repo, "branch", "upstream")
+
+	if id, err := ResolveBase(repo, ""); err != nil || id != "" {
+		t.Fatalf("expected no base to resolve to nothing, got %q, %v", id, err)
+	}
+	if id, err := ResolveBase(repo, "upstream"); err != nil || id != first {
+		t.Fatalf("expected upstream to resolve to %s, got %q, %v", first, id, err)
+	}
+	writeFile(t, repo, "a.txt", "two\n")
+	runGit(t, repo, "add", "-A")
+	commitAll(t, repo, "second")
+	second, _ := run(repo, "rev-parse", "HEAD")
+	second = strings.TrimSpace(second)
+	runGit(t, repo, "branch", "-f", "upstream", "HEAD")
+	if id, err := ResolveBase(repo, "upstream"); err != nil || id != second {
+		t.Fatalf("expected a moved upstream to resolve to %s, got %q, %v", second, id, err)
+	}
+	if id, err := ResolveBase(repo, first+"..."); err != nil || id != first+" "+second {
+		t.Fatalf("expected both range ends resolved, got %q, %v", id, err)
+	}
+	if _, err := ResolveBase(repo, "missing"); err == nil {
+		t.Fatalf("expected an unknown base to fail")
+	}
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
+
+func commitAll(t *testing.T, dir, message string) {
+	t.Helper()
+	runGit(t, dir, "-c", "user.name=wing", "-c", "user.email=wing@example.com", "commit", "-q", "-m", message)
+}
+
+func TestDiffOptionsArgs(t *testing.T) {
+	got := DiffOptions{Context: DefaultContext}.args()
+	if strings.Join(got, " ") != "diff --no-color" {
+		t.Fatalf("unexpected default args: %v", got)
+	}
+	got = (DiffOptions{}).args()
+	if strings.Join(got, " ") != "diff --no-color --unified=0" {
+		t.Fatalf("expected no context lines for 0, got %v", got)
+	}
+	got = DiffOptions{Whitespace: WhitespaceIgnoreAll, Context: 8, Algorithm: "patience"}.args()
+	want := "diff --no-color --ignore-all-space --unified=8 --diff-algorithm=patience"
+	if strings.Join(got, " ") != want {
+		t.Fatalf("expected %q, got %q", want, strings.Join(got, " "))
+	}
+}
+
+func TestDiffIgnoreWhitespace(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init")
+	writeFile(t, repo, "a.txt", "one two\n")
+	runGit(t, repo, "add", "-A")
+	commitAll(t, repo, "first")
+	writeFile(t, repo, "a.txt", "one  two\n")
+
+	diff, err := Diff(repo, StatusEntry{Path: "a.txt", Status: "M"}, DiffOptions{})
+	if err != nil {
+		t.Fatalf("Diff error: %v", err)
+	}
+	if !strings.Contains(diff, "+one  two") {
+		t.Fatalf("expected whitespace change in diff, got %q", diff)
+	}
+	diff, err = Diff(repo, StatusEntry{Path: "a.txt", Status: "M"}, DiffOptions{Whitespace: WhitespaceIgnoreChange})
+	if err != nil {
+		t.Fatalf("Diff error: %v", err)
+	}
+	if strings.Contains(diff, "+one  two") {
+		t.Fatalf("expected whitespace change to be ignored, got %q", diff)
+	}
+}
+
+func TestBlobContents(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init")
+	writeFile(t, repo, "a.txt", "one\n")
+	runGit(t, repo, "add", "-A")
+	commitAll(t, repo, "first")
+	writeFile(t, repo, "a.txt", "two\n")
+	runGit(t, repo, "add", "-A")
+	writeFile(t, repo, "a.txt", "three\n")
+
+	for rev, want := range map[string]string{"HEAD": "one", "": "two"} {
+		got, err := BlobContents(repo, rev, "a.txt")
+		if err != nil {
+			t.Fatalf("BlobContents(%q) error: %v", rev, err)
+		}
+		if got != want {
+			t.Fatalf("BlobContents(%q) expected %q, got %q", rev, want, got)
+		}
+	}
+}
+
+func TestReadFileAndBlobLimits(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init")
+	writeFile(t, repo, "text.txt", strings.Repeat("line\n", 100))
+	writeFile(t, repo, "blob.bin", "\x89PNG\x00\x00data")
+	runGit(t, repo, "add", "-A")
+	commitAll(t, repo, "first")
+
+	data, err := ReadFile(repo, "text.txt", 10)
+	if err != nil {
+		t.Fatalf("ReadFile error: %v", err)
+	}
+	if string(data.Data) != "line\nline\n" || data.Size != 500 || !data.Truncated || data.Binary {
+		t.Fatalf("unexpected ReadFile result %+v", data)
+	}
+	if _, err := ReadFile(repo, ".git", 10); err == nil {
+		t.Fatalf("expected ReadFile to reject a directory")
+	}
+
+	blob, err := ReadBlob(repo, "HEAD", "text.txt", 7)
+	if err != nil {
+		t.Fatalf("ReadBlob error: %v", err)
+	}
+	if string(blob.Data) != "line\nli" || blob.Size != 500 || !blob.Truncated {
+		t.Fatalf("unexpected ReadBlob result %+v", blob)
+	}
+	blob, err = ReadBlob(repo, "", "blob.bin", 1024)
+	if err != nil {
+		t.Fatalf("ReadBlob error: %v", err)
+	}
+	if !blob.Binary || blob.Truncated || blob.Size != 10 {
+		t.Fatalf("expected a whole binary blob, got %+v", blob)
+	}
+	if _, err := ReadBlob(repo, "HEAD", "missing.txt", 10); err == nil {
+		t.Fatalf("expected ReadBlob to fail for a missing path")
+	}
+}
+
+func TestNumStat(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init")
+	writeFile(t, repo, "a.txt", "one\ntwo\nthree\n")
+	writeFile(t, repo, "logo.bin", "\x00\x01")
+	runGit(t, repo, "add", "-A")
+	commitAll(t, repo, "first")
+	writeFile(t, repo, "a.txt", "one\n2\nthree\nfour\n")
+	writeFile(t, repo, "logo.bin", "\x00\x02")
+	writeFile(t, repo, "new.txt", "x\ny")
+
+	stats, err := NumStat(repo, "", []string{"new.txt", "missing.txt"})
+	if err != nil {
+		t.Fatalf("NumStat error: %v", err)
+	}
+	want := map[string]DiffStat{
+		"a.txt":    {Added: 2, Deleted: 1},
+		"logo.bin": {Binary: true},
+		"new.txt":  {Added: 2},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("expected %v, got %v", want, stats)
+	}
+	for path, stat := range want {
+		if stats[path] != stat {
+			t.Fatalf("%s: expected %+v, got %+v", path, stat, stats[path])
+		}
+	}
+
+	runGit(t, repo, "add", "a.txt")
+	stats, err = NumStat(repo, "HEAD", nil)
+	if err != nil {
+		t.Fatalf("NumStat against HEAD error: %v", err)
+	}
+	if stats["a.txt"] != (DiffStat{Added: 2, Deleted: 1}) {
+		t.Fatalf("expected staged changes against HEAD, got %+v", stats["a.txt"])
+	}
+}
+
+func TestParseNumStatRename(t *testing.T) {
+	stats := parseNumStat("3\t1\t\x00old.go\x00new.go\x00-\t-\tlogo.png\x00")
+	if stats["new.go"] != (DiffStat{Added: 3, Deleted: 1}) || !stats["logo.png"].Binary || len(stats) != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestRangeEnd(t *testing.T) {
+	cases := map[string]string{
+		"main":         "",
+		"main..":       "HEAD",
+		"main..topic":  "topic",
+		"main...topic": "topic",
+	}
+	for base, want := range cases {
+		if got := RangeEnd(base); got != want {
+			t.Fatalf("RangeEnd(%q) expected %q, got %q", base, want, got)
+		}
+	}
+}
+
+func TestStatusAndDiffRename(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init")
+	writeFile(t, repo, "old.txt", "a\nb\nc\nd\ne\nf\n")
+	runGit(t, repo, "add", "-A")
+	commitAll(t, repo, "first")
+	runGit(t, repo, "mv", "old.txt", "new.txt")
+	writeFile(t, repo, "new.txt", "a\nb\nc\nd\ne\nF\n")
+
+	entries, err := Status(repo)
+	if err != nil {
+		t.Fatalf("Status error: %v", err)
+	}
+	entry, ok := entryForPath(entries, "new.txt")
+	if !ok || entry.OrigPath != "old.txt" || !strings.HasPrefix(entry.Status, "R") {
+		t.Fatalf("expected rename from old.txt, got %+v", entries)
+	}
+
+	diff, err := Diff(repo, entry, DiffOptions{})
+	if err != nil {
+		t.Fatalf("Diff error: %v", err)
+	}
+	for _, want := range []string{"similarity index", "rename from old.txt", "rename to new.txt", "+F"} {
+		if !strings.Contains(diff, want) {
+			t.Fatalf("expected %q in rename diff, got %q", want, diff)
+		}
+	}
+	if strings.Contains(diff, "-a") {
+		t.Fatalf("expected only real content changes, got %q", diff)
+	}
+
+	runGit(t, repo, "add", "-A")
+	commitAll(t, repo, "rename")
+	entries, err = StatusAgainst(repo, "HEAD~1")
+	if err != nil {
+		t.Fatalf("StatusAgainst error: %v", err)
+	}
+	entry, ok = entryForPath(entries, "new.txt")
+	if !ok || entry.Status != "R" || entry.OrigPath != "old.txt" {
+		t.Fatalf("expected rename against base, got %+v", entries)
+	}
+}
+
+func TestParseStatusV2(t *testing.T) {
+	out := strings.Join([]string{
+		"# branch.oid 0123456789abcdef",
+		"# branch.head main",
+		"# branch.upstream origin/main",
+		"# branch.ab +2 -1",
+		"1 .M N... 100644 100644 100644 aaaa bbbb  leading space.txt",
+		"1 A. N... 000000 100644 100644 0000 cccc line\nbreak.txt",
+		"2 R. N... 100644 100644 100644 dddd dddd R100 new name.txt",
+		"old name.txt",
+		"1 .M SC.. 160000 160000 160000 eeee eeee vendor/lib",
+		"u UU N... 100644 100644 100644 100644 ffff 1111 2222 conflict.txt",
+		"? héllo.txt",
+		"! build/out.o",
+	}, "\x00") + "\x00"
+
+	entries, branch := parseStatusV2(out)
+	if branch.Head != "main" || branch.Upstream != "origin/main" || branch.Ahead != 2 || branch.Behind != 1 {
+		t.Fatalf("unexpected branch info %+v", branch)
+	}
+	if branch.OID != "0123456789abcdef" {
+		t.Fatalf("unexpected oid %q", branch.OID)
+	}
+
+	byPath := map[string]StatusEntry{}
+	for _, entry := range entries {
+		byPath[entry.Path] = entry
+	}
+	if entry := byPath[" leading space.txt"]; entry.Status != "M" || entry.Index != '.' || entry.Worktree != 'M' {
+		t.Fatalf("unexpected leading space entry %+v", entry)
+	}
+	if entry := byPath["line\nbreak.txt"]; entry.Status != "A" || entry.IndexMode != "100644" {
+		t.Fatalf("unexpected newline entry %+v", entry)
+	}
+	if entry := byPath["new name.txt"]; entry.OrigPath != "old name.txt" || entry.Score != "R100" || entry.Status != "R" {
+		t.Fatalf("unexpected rename entry %+v", entry)
+	}
+	if entry := byPath["vendor/lib"]; !entry.IsSubmodule() || entry.Submodule != "SC.." {
+		t.Fatalf("unexpected submodule entry %+v", entry)
+	}
+	if entry := byPath["conflict.txt"]; !entry.Conflicted || entry.Status != "UU" {
+		t.Fatalf("unexpected conflict entry %+v", entry)
+	}
+	if entry := byPath["héllo.txt"]; entry.Status != "??" {
+		t.Fatalf("unexpected untracked entry %+v", entry)
+	}
+	if entry := byPath["build/out.o"]; !entry.Ignored || entry.Status != "!!" {
+		t.Fatalf("unexpected ignored entry %+v", entry)
+	}
+}
+
+func TestParseStatusV2Detached(t *testing.T) {
+	_, branch := parseStatusV2("# branch.oid (initial)\x00# branch.head (detached)\x00")
+	if !branch.Detached || branch.Head != "HEAD" || branch.OID != "" {
+		t.Fatalf("unexpected detached branch %+v", branch)
+	}
+}
+
+func TestStatusWithBranchUntrackedDir(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-b", "main")
+	writeFile(t, repo, "a.txt", "one\n")
+	runGit(t, repo, "add", "-A")
+	commitAll(t, repo, "first")
+	writeFile(t, repo, "newdir/x.txt", "x\n")
+	writeFile(t, repo, "newdir/sub/y.txt", "y\n")
+
+	entries, branch, err := StatusWithBranch(repo)
+	if err != nil {
+		t.Fatalf("StatusWithBranch error: %v", err)
+	}
+	if branch.Head != "main" {
+		t.Fatalf("expected branch main, got %+v", branch)
+	}
+	if !hasPath(entries, "newdir/x.txt") || !hasPath(entries, "newdir/sub/y.txt") {
+		t.Fatalf("expected untracked dir to be expanded, got %+v", entries)
+	}
+}
+
+func TestHashFiles(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init")
+	writeFile(t, repo, "a.txt", "one\n")
+	writeFile(t, repo, "dir/b.txt", "one\n")
+
+	hashes, err := HashFiles(repo, []string{"a.txt", "dir/b.txt", "gone.txt"})
+	if err != nil {
+		t.Fatalf("HashFiles error: %v", err)
+	}
+	if hashes["a.txt"] == "" || hashes["a.txt"] != hashes["dir/b.txt"] {
+		t.Fatalf("expected equal content to hash equally, got %v", hashes)
+	}
+	if hashes["gone.txt"] != DeletedHash {
+		t.Fatalf("expected missing file to be marked deleted, got %v", hashes)
+	}
+
+	writeFile(t, repo, "a.txt", "two\n")
+	changed, err := HashFiles(repo, []string{"a.txt"})
+	if err != nil {
+		t.Fatalf("HashFiles error: %v", err)
+	}
+	if changed["a.txt"] == hashes["a.txt"] {
+		t.Fatalf("expected hash to change with content")
+	}
+}
+
+func TestCancelledContextStopsGit(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init")
+	writeFile(t, repo, "a.txt", "one\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := DiffContext(ctx, repo, StatusEntry{Path: "a.txt", Status: "??"}, DiffOptions{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled diff, got %v", err)
+	}
+	if _, _, err := StatusWithBranchContext(ctx, repo); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled status, got %v", err)
+	}
+}
+
+func TestNumStatCountsUnchangedUntrackedFilesOnce(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init")
+	writeFile(t, repo, "new.txt", "a\nb\n")
+	path := filepath.Join(repo, "new.txt")
+	stamp := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(path, stamp, stamp); err != nil {
+		t.Fatal(err)
+	}
+	if stats, err := NumStat(repo, "", []string{"new.txt"}); err != nil || stats["new.txt"] != (DiffStat{Added: 2}) {
+		t.Fatalf("expected 2 added lines, got %+v, %v", stats, err)
+	}
+
+	// Same size and time: the file is not read again.
+	writeFile(t, repo, "new.txt", "a\n\n\n")
+	if err := os.Chtimes(path, stamp, stamp); err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ := NumStat(repo, "", []string{"new.txt"}); stats["new.txt"] != (DiffStat{Added: 2}) {
+		t.Fatalf("expected the cached count, got %+v", stats["new.txt"])
+	}
+
+	writeFile(t, repo, "new.txt", "a\nb\nc\n")
+	if stats, _ := NumStat(repo, "", []string{"new.txt"}); stats["new.txt"] != (DiffStat{Added: 3}) {
+		t.Fatalf("expected a recount after a change, got %+v", stats["new.txt"])
+	}
+
+	NumStat(repo, "", nil)
+	if _, ok := lineCounts.entries[path]; ok {
+		t.Fatalf("expected the count of a file no longer untracked to be dropped")
+	}
+}
+
+func TestCopiesOfUnchangedFilesAreFound(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init")
+	writeFile(t, repo, "a.txt", "one\ntwo\nthree\nfour\nfive\n")
+	runGit(t, repo, "add", "-A")
+	commitAll(t, repo, "first")
+	writeFile(t, repo, "b.txt", "one\ntwo\nthree\nfour\nfive\n")
+	runGit(t, repo, "add", "b.txt")
+
+	entries, err := StatusAgainst(repo, "HEAD")
+	if err != nil {
+		t.Fatalf("StatusAgainst error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Status != "C" || entries[0].OrigPath != "a.txt" {
+		t.Fatalf("expected b.txt as a copy of a.txt, got %+v", entries)
+	}
+	diff, err := Diff(repo, entries[0], DiffOptions{Base: "HEAD", Context: DefaultContext})
+	if err != nil {
+		t.Fatalf("Diff error: %v", err)
+	}
+	if !strings.Contains(diff, "copy from a.txt") {
+		t.Fatalf("expected a copy diff, got %q", diff)
+	}
+}
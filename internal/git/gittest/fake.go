@@ -0,0 +1,463 @@
+// Package gittest provides an in-memory git.Backend for tests that drive
+// the UI without a repository on disk.
+package gittest
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"wing/internal/git"
+)
+
+// Fake is a scripted git.Backend. Tests fill in the fields before handing
+// it to the model and may change them between steps; commits and pushes
+// are recorded instead of performed.
+type Fake struct {
+	mu sync.Mutex
+
+	// Entries is the working tree status reported by Status.
+	Entries []git.StatusEntry
+	Info    git.BranchInfo
+	// Against maps a base to the status StatusAgainst reports for it.
+	Against map[string][]git.StatusEntry
+	// Resolved maps a base to the commits ResolveBase reports for it; a
+	// base missing from it resolves to itself.
+	Resolved map[string]string
+	// Tracked lists clean tracked files, which ListFiles reports along
+	// with every entry; Ignored is only listed on request.
+	Tracked []string
+	Ignored []string
+	// Diffs maps a path to its diff.
+	Diffs map[string]string
+	// Files holds worktree contents and Blobs holds committed contents
+	// keyed by "rev:path".
+	Files map[string]string
+	Blobs map[string]string
+	// Err, when set, fails every operation.
+	Err error
+	// Wait makes Diff of a path block until its channel is closed or the
+	// request is cancelled, to stage slow loads.
+	Wait map[string]chan struct{}
+
+	Commits []string
+	Pushes  int
+	// Applied records the patches applied; Saved lists the checkpoints,
+	// newest first, and Restored the ids restored.
+	Applied  []string
+	Saved    []git.Checkpoint
+	Restored []string
+}
+
+func (f *Fake) Status(ctx context.Context) ([]git.StatusEntry, git.BranchInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx); err != nil {
+		return nil, git.BranchInfo{}, err
+	}
+	return sortedEntries(f.Entries), f.Info, nil
+}
+
+func (f *Fake) StatusAgainst(ctx context.Context, base string, worktree []git.StatusEntry) ([]git.StatusEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx); err != nil {
+		return nil, err
+	}
+	entries, ok := f.Against[base]
+	if !ok {
+		return nil, fmt.Errorf("unknown revision: %s", base)
+	}
+	return sortedEntries(entries), nil
+}
+
+func (f *Fake) ListFiles(ctx context.Context, includeIgnored bool) ([]git.StatusEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx); err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var files []git.StatusEntry
+	add := func(entry git.StatusEntry) {
+		if !seen[entry.Path] {
+			seen[entry.Path] = true
+			files = append(files, entry)
+		}
+	}
+	for _, path := range f.Tracked {
+		add(git.StatusEntry{Path: path})
+	}
+	for _, entry := range f.Entries {
+		if entry.Status == "??" {
+			add(entry)
+		} else {
+			add(git.StatusEntry{Path: entry.Path})
+		}
+	}
+	if includeIgnored {
+		for _, path := range f.Ignored {
+			add(git.StatusEntry{Path: path, Status: "!!", Ignored: true})
+		}
+	}
+	return sortedEntries(files), nil
+}
+
+func (f *Fake) Diff(ctx context.Context, entry git.StatusEntry, opts git.DiffOptions) (string, error) {
+	f.mu.Lock()
+	wait := f.Wait[entry.Path]
+	f.mu.Unlock()
+	if wait != nil {
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx); err != nil {
+		return "", err
+	}
+	return f.Diffs[entry.Path], nil
+}
+
+func (f *Fake) FileContents(ctx context.Context, path string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx); err != nil {
+		return "", err
+	}
+	text, ok := f.Files[path]
+	if !ok {
+		return "", fmt.Errorf("open %s: no such file", path)
+	}
+	return text, nil
+}
+
+func (f *Fake) BlobContents(ctx context.Context, rev, path string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx); err != nil {
+		return "", err
+	}
+	text, ok := f.Blobs[rev+":"+path]
+	if !ok {
+		return "", fmt.Errorf("%s does not exist in %s", path, rev)
+	}
+	return strings.TrimRight(text, "\n"), nil
+}
+
+func (f *Fake) ReadFile(ctx context.Context, path string, limit int64) (git.FileData, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx); err != nil {
+		return git.FileData{}, err
+	}
+	text, ok := f.Files[path]
+	if !ok {
+		return git.FileData{}, fmt.Errorf("open %s: no such file", path)
+	}
+	return fileData(text, limit), nil
+}
+
+func (f *Fake) ReadBlob(ctx context.Context, rev, path string, limit int64) (git.FileData, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx); err != nil {
+		return git.FileData{}, err
+	}
+	text, ok := f.Blobs[rev+":"+path]
+	if !ok {
+		return git.FileData{}, fmt.Errorf("%s does not exist in %s", path, rev)
+	}
+	return fileData(text, limit), nil
+}
+
+func fileData(text string, limit int64) git.FileData {
+	data := []byte(text)
+	if int64(len(data)) > limit {
+		data = data[:limit]
+	}
+	return git.FileData{
+		Data:      data,
+		Size:      int64(len(text)),
+		Binary:    git.IsBinary(data),
+		Truncated: len(data) < len(text),
+	}
+}
+
+// HashFiles hashes the worktree contents; the values only need to change
+// when the contents do.
+func (f *Fake) HashFiles(ctx context.Context, paths []string) (map[string]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx); err != nil {
+		return nil, err
+	}
+	hashes := make(map[string]string, len(paths))
+	for _, path := range paths {
+		text, ok := f.Files[path]
+		if !ok {
+			hashes[path] = git.DeletedHash
+			continue
+		}
+		sum := sha1.Sum([]byte(text))
+		hashes[path] = hex.EncodeToString(sum[:])
+	}
+	return hashes, nil
+}
+
+// NumStat counts the lines added and removed by the diff of each entry;
+// the base is not consulted.
+func (f *Fake) NumStat(ctx context.Context, base string, untracked []string) (map[string]git.DiffStat, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx); err != nil {
+		return nil, err
+	}
+	stats := make(map[string]git.DiffStat)
+	for _, entry := range f.Entries {
+		diff, ok := f.Diffs[entry.Path]
+		if !ok {
+			continue
+		}
+		var stat git.DiffStat
+		for _, line := range strings.Split(diff, "\n") {
+			switch {
+			case strings.HasPrefix(line, "Binary files "):
+				stat.Binary = true
+			case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			case strings.HasPrefix(line, "+"):
+				stat.Added++
+			case strings.HasPrefix(line, "-"):
+				stat.Deleted++
+			}
+		}
+		stats[entry.Path] = stat
+	}
+	return stats, nil
+}
+
+// Commit records message and leaves the worktree clean.
+func (f *Fake) Commit(message string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return f.Err
+	}
+	if strings.TrimSpace(message) == "" {
+		return fmt.Errorf("commit message is required")
+	}
+	f.Commits = append(f.Commits, message)
+	for _, entry := range f.Entries {
+		if entry.Status != "D" {
+			f.Tracked = append(f.Tracked, entry.Path)
+		}
+	}
+	f.Entries = nil
+	f.Diffs = nil
+	f.Info.Ahead++
+	return nil
+}
+
+func (f *Fake) Push() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return f.Err
+	}
+	f.Pushes++
+	f.Info.Ahead = 0
+	return nil
+}
+
+func (f *Fake) Branch(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx); err != nil {
+		return "", err
+	}
+	if f.Info.Detached {
+		return "HEAD", nil
+	}
+	return f.Info.Head, nil
+}
+
+// VerifyBase accepts an empty base and the bases Against knows.
+func (f *Fake) VerifyBase(base string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return f.Err
+	}
+	if _, ok := f.Against[base]; base != "" && !ok {
+		return fmt.Errorf("unknown revision: %s", base)
+	}
+	return nil
+}
+
+func (f *Fake) ResolveBase(ctx context.Context, base string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail(ctx); err != nil {
+		return "", err
+	}
+	if id, ok := f.Resolved[base]; ok {
+		return id, nil
+	}
+	return base, nil
+}
+
+// Patch joins the diffs of entries; the base is not consulted.
+func (f *Fake) Patch(base string, entries []git.StatusEntry) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return "", f.Err
+	}
+	var b strings.Builder
+	for _, entry := range entries {
+		if diff, ok := f.Diffs[entry.Path]; ok {
+			b.WriteString(strings.TrimRight(diff, "\n") + "\n")
+		}
+	}
+	return b.String(), nil
+}
+
+func (f *Fake) FormatPatch(subject, patch string, date time.Time) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return "", f.Err
+	}
+	return fmt.Sprintf("Date: %s\nSubject: [PATCH] %s\n\n---\n%s", date.Format(time.RFC1123Z), subject, patch), nil
+}
+
+// CheckPatch lists the files of patch with their line counts and reports
+// no conflicts.
+func (f *Fake) CheckPatch(patch string) (git.PatchCheck, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return git.PatchCheck{}, f.Err
+	}
+	var check git.PatchCheck
+	for _, line := range strings.Split(patch, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++ b/"):
+			check.Files = append(check.Files, git.PatchFile{Path: strings.TrimPrefix(line, "+++ b/")})
+		case len(check.Files) == 0, strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "+"):
+			check.Files[len(check.Files)-1].Added++
+		case strings.HasPrefix(line, "-"):
+			check.Files[len(check.Files)-1].Removed++
+		}
+	}
+	return check, nil
+}
+
+// ApplyPatch records patch without touching the entries.
+func (f *Fake) ApplyPatch(patch string, mode git.ApplyMode) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return f.Err
+	}
+	f.Applied = append(f.Applied, patch)
+	return nil
+}
+
+// CreateCheckpoint saves a checkpoint whenever the entries differ from the
+// latest one's.
+func (f *Fake) CreateCheckpoint() (git.Checkpoint, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.checkpoint("")
+}
+
+func (f *Fake) checkpoint(message string) (git.Checkpoint, bool, error) {
+	if f.Err != nil {
+		return git.Checkpoint{}, false, f.Err
+	}
+	tree := f.tree()
+	if len(f.Saved) > 0 && f.Saved[0].Tree == tree {
+		return f.Saved[0], false, nil
+	}
+	if message == "" {
+		message = fmt.Sprintf("%d files", len(f.Entries))
+	}
+	sum := sha1.Sum([]byte(fmt.Sprint(len(f.Saved), tree)))
+	checkpoint := git.Checkpoint{ID: hex.EncodeToString(sum[:]), Tree: tree, Time: time.Now(), Message: message}
+	f.Saved = append([]git.Checkpoint{checkpoint}, f.Saved...)
+	return checkpoint, true, nil
+}
+
+// tree fingerprints the entries and worktree contents.
+func (f *Fake) tree() string {
+	hash := sha1.New()
+	for _, entry := range sortedEntries(f.Entries) {
+		fmt.Fprintf(hash, "%s %s %s\n", entry.Status, entry.Path, f.Files[entry.Path])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (f *Fake) Checkpoints() ([]git.Checkpoint, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	return append([]git.Checkpoint(nil), f.Saved...), nil
+}
+
+// RestoreCheckpoint saves a safety checkpoint and records id; the entries
+// are left as they are.
+func (f *Fake) RestoreCheckpoint(id string) (git.Checkpoint, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	found := false
+	for _, checkpoint := range f.Saved {
+		found = found || checkpoint.ID == id
+	}
+	if !found {
+		return git.Checkpoint{}, fmt.Errorf("unknown checkpoint: %s", id)
+	}
+	safety, _, err := f.checkpoint("before restore to " + id[:min(len(id), 8)])
+	if err != nil {
+		return git.Checkpoint{}, err
+	}
+	f.Restored = append(f.Restored, id)
+	return safety, nil
+}
+
+// Update runs change with the fake locked, for tests that edit it while
+// commands may still be running.
+func (f *Fake) Update(change func(f *Fake)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	change(f)
+}
+
+// fail reports why a read should fail: a cancelled request or Err.
+func (f *Fake) fail(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return f.Err
+}
+
+func sortedEntries(entries []git.StatusEntry) []git.StatusEntry {
+	sorted := append([]git.StatusEntry(nil), entries...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Path < sorted[j].Path
+	})
+	return sorted
+}
+
+var _ git.Backend = (*Fake)(nil)
@@ -0,0 +1,680 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/utils/binary"
+	udiff "github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// GoGitBackend reads the repository in-process with go-git, so status,
+// listings and diffs against the index do not spawn git. Diff options it
+// cannot honour, renames, line counts, patches and checkpoints go through
+// the exec backend, as do commits and pushes, which must run the user's
+// hooks and credential helpers. go-git cannot interrupt a read in
+// progress, so contexts are checked between steps.
+type GoGitBackend struct {
+	repo *gogit.Repository
+	root string
+	exec ExecBackend
+}
+
+// OpenGoGit opens the repository containing repoPath, which may be a
+// linked worktree made with git worktree add.
+func OpenGoGit(repoPath string) (*GoGitBackend, error) {
+	repo, err := gogit.PlainOpenWithOptions(repoPath, &gogit.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: true})
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", repoPath, err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	root := worktree.Filesystem.Root()
+	return &GoGitBackend{repo: repo, root: root, exec: ExecBackend{RepoPath: root}}, nil
+}
+
+// Status reports changed files and the branch. Renames show up as a
+// deletion plus an added file, and Ahead and Behind are left at zero since
+// counting them needs a history walk on every refresh.
+func (b *GoGitBackend) Status(ctx context.Context) ([]StatusEntry, BranchInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, BranchInfo{}, err
+	}
+	worktree, err := b.repo.Worktree()
+	if err != nil {
+		return nil, BranchInfo{}, err
+	}
+	// go-git reads .gitignore and info/exclude itself, but not the
+	// user's core.excludesFile.
+	worktree.Excludes = excludesPatterns(excludesFile(b.repo))
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, BranchInfo{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, BranchInfo{}, err
+	}
+	index, err := b.repo.Storer.Index()
+	if err != nil {
+		return nil, BranchInfo{}, err
+	}
+	entries := make([]StatusEntry, 0, len(status))
+	for path, file := range status {
+		if file.Staging == gogit.Unmodified && file.Worktree == gogit.Unmodified {
+			continue
+		}
+		entry := goGitEntry(filepath.ToSlash(path), file)
+		if staged, err := index.Entry(entry.Path); err == nil && entry.Status != "??" {
+			entry.IndexHash = staged.Hash.String()
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return entries, b.branchInfo(), nil
+}
+
+func goGitEntry(path string, file *gogit.FileStatus) StatusEntry {
+	if file.Staging == gogit.Untracked || file.Worktree == gogit.Untracked {
+		return StatusEntry{Path: path, Status: "??", Index: '?', Worktree: '?'}
+	}
+	code := func(status gogit.StatusCode) byte {
+		if status == gogit.Unmodified {
+			return '.'
+		}
+		return byte(status)
+	}
+	entry := StatusEntry{Path: path, Index: code(file.Staging), Worktree: code(file.Worktree)}
+	entry.Status = strings.TrimSpace(strings.ReplaceAll(string([]byte{entry.Index, entry.Worktree}), ".", " "))
+	entry.Conflicted = file.Staging == gogit.UpdatedButUnmerged || file.Worktree == gogit.UpdatedButUnmerged
+	if file.Staging == gogit.Renamed || file.Staging == gogit.Copied {
+		entry.OrigPath = file.Extra
+	}
+	return entry
+}
+
+func (b *GoGitBackend) branchInfo() BranchInfo {
+	var info BranchInfo
+	head, err := b.repo.Head()
+	if err != nil {
+		// An unborn branch still names the branch HEAD points at.
+		if ref, refErr := b.repo.Storer.Reference(plumbing.HEAD); refErr == nil && ref.Type() == plumbing.SymbolicReference {
+			info.Head = ref.Target().Short()
+		}
+		return info
+	}
+	info.OID = head.Hash().String()
+	if !head.Name().IsBranch() {
+		info.Head = "HEAD"
+		info.Detached = true
+		return info
+	}
+	info.Head = head.Name().Short()
+	if cfg, err := b.repo.Config(); err == nil {
+		if branch, ok := cfg.Branches[info.Head]; ok && branch.Remote != "" && branch.Merge.IsBranch() {
+			info.Upstream = branch.Remote + "/" + branch.Merge.Short()
+		}
+	}
+	return info
+}
+
+// StatusAgainst compares with a base through git, which detects renames
+// across the whole tree.
+func (b *GoGitBackend) StatusAgainst(ctx context.Context, base string, worktree []StatusEntry) ([]StatusEntry, error) {
+	return b.exec.StatusAgainst(ctx, base, worktree)
+}
+
+// NumStat counts lines through git, as go-git has no equivalent of a
+// whole-tree numstat.
+func (b *GoGitBackend) NumStat(ctx context.Context, base string, untracked []string) (map[string]DiffStat, error) {
+	return b.exec.NumStat(ctx, base, untracked)
+}
+
+// ListFiles lists tracked files from the index plus untracked files, and
+// ignored ones when includeIgnored is set, by walking the worktree.
+func (b *GoGitBackend) ListFiles(ctx context.Context, includeIgnored bool) ([]StatusEntry, error) {
+	index, err := b.repo.Storer.Index()
+	if err != nil {
+		return nil, err
+	}
+	tracked := make(map[string]struct{}, len(index.Entries))
+	entries := make([]StatusEntry, 0, len(index.Entries))
+	for _, entry := range index.Entries {
+		if _, ok := tracked[entry.Name]; ok {
+			continue
+		}
+		tracked[entry.Name] = struct{}{}
+		entries = append(entries, StatusEntry{Path: entry.Name})
+	}
+
+	worktree, err := b.repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	// The user's excludes file ranks below .git/info/exclude, which ranks
+	// below the .gitignore files.
+	patterns := excludesPatterns(excludesFile(b.repo))
+	if storage, ok := b.repo.Storer.(*filesystem.Storage); ok {
+		infoExclude := filepath.Join(storage.Filesystem().Root(), "info", "exclude")
+		patterns = append(patterns, excludesPatterns(infoExclude)...)
+	}
+	worktreePatterns, _ := gitignore.ReadPatterns(worktree.Filesystem, nil)
+	matcher := gitignore.NewMatcher(append(patterns, worktreePatterns...))
+	err = filepath.WalkDir(b.root, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil || path == b.root {
+			return nil
+		}
+		rel, err := filepath.Rel(b.root, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		parts := strings.Split(rel, "/")
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+				// A nested repository or submodule is not ours to list.
+				return filepath.SkipDir
+			}
+			if !includeIgnored && matcher.Match(parts, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, ok := tracked[rel]; ok {
+			return nil
+		}
+		if matcher.Match(parts, false) {
+			if includeIgnored {
+				entries = append(entries, StatusEntry{Path: rel, Status: "!!", Ignored: true})
+			}
+			return nil
+		}
+		entries = append(entries, StatusEntry{Path: rel, Status: "??"})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return entries, nil
+}
+
+// excludesFile returns the path of core.excludesFile from the repository,
+// global or system config, or git's default of $XDG_CONFIG_HOME/git/ignore.
+func excludesFile(repo *gogit.Repository) string {
+	loaders := []func() (*config.Config, error){
+		repo.Config,
+		func() (*config.Config, error) { return config.LoadConfig(config.GlobalScope) },
+		func() (*config.Config, error) { return config.LoadConfig(config.SystemScope) },
+	}
+	home, _ := os.UserHomeDir()
+	for _, load := range loaders {
+		cfg, err := load()
+		if err != nil || cfg.Raw == nil || !cfg.Raw.HasSection("core") {
+			continue
+		}
+		if path := cfg.Raw.Section("core").Option("excludesfile"); path != "" {
+			if rest, ok := strings.CutPrefix(path, "~/"); ok && home != "" {
+				path = filepath.Join(home, rest)
+			}
+			return path
+		}
+	}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "git", "ignore")
+	}
+	if home == "" {
+		return ""
+	}
+	return filepath.Join(home, ".config", "git", "ignore")
+}
+
+// excludesPatterns reads the patterns of an excludes file, which apply
+// throughout the worktree. A missing file has none.
+func excludesPatterns(path string) []gitignore.Pattern {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var patterns []gitignore.Pattern
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, gitignore.ParsePattern(line, nil))
+	}
+	return patterns
+}
+
+// Diff builds the unified diff of entry in-process. Whitespace handling,
+// diff algorithms, renames, diffs without context and merge-base ranges
+// ("A...B") are left to git itself through the exec backend.
+func (b *GoGitBackend) Diff(ctx context.Context, entry StatusEntry, opts DiffOptions) (string, error) {
+	if entry.Path == "" {
+		return "", nil
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if opts.Whitespace != WhitespaceShow || opts.Algorithm != "" || opts.Context == 0 || entry.OrigPath != "" || strings.Contains(opts.Base, "...") {
+		return b.exec.Diff(ctx, entry, opts)
+	}
+
+	var from, to *diffFile
+	var err error
+	switch {
+	case IsRange(opts.Base):
+		start, _, _ := strings.Cut(opts.Base, "..")
+		if start == "" {
+			start = "HEAD"
+		}
+		if from, err = b.revisionFile(start, entry.Path); err != nil {
+			return "", err
+		}
+		if to, err = b.revisionFile(RangeEnd(opts.Base), entry.Path); err != nil {
+			return "", err
+		}
+	case entry.Status == "??":
+		to, err = b.worktreeFile(entry.Path)
+	case opts.Base != "":
+		if from, err = b.revisionFile(opts.Base, entry.Path); err == nil {
+			to, err = b.worktreeFile(entry.Path)
+		}
+	default:
+		if from, err = b.indexFile(entry.Path); err == nil {
+			to, err = b.worktreeFile(entry.Path)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if (from == nil && to == nil) || (from != nil && to != nil && from.hash == to.hash && from.mode == to.mode) {
+		return "", nil
+	}
+
+	context := opts.Context
+	if context < 0 {
+		context = 3
+	}
+	var out bytes.Buffer
+	if err := fdiff.NewUnifiedEncoder(&out, context).Encode(newFilePatch(from, to)); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(out.String(), "\n"), nil
+}
+
+func (b *GoGitBackend) worktreeFile(path string) (*diffFile, error) {
+	fullPath := filepath.Join(b.root, filepath.FromSlash(path))
+	info, err := os.Lstat(fullPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	mode := filemode.Regular
+	var data []byte
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		mode = filemode.Symlink
+		target, err := os.Readlink(fullPath)
+		if err != nil {
+			return nil, err
+		}
+		data = []byte(target)
+	case info.IsDir():
+		return nil, nil
+	default:
+		if info.Mode()&0o111 != 0 {
+			mode = filemode.Executable
+		}
+		if data, err = os.ReadFile(fullPath); err != nil {
+			return nil, err
+		}
+	}
+	return &diffFile{path: path, mode: mode, data: data, size: int64(len(data)), hash: plumbing.ComputeHash(plumbing.BlobObject, data)}, nil
+}
+
+func (b *GoGitBackend) indexFile(path string) (*diffFile, error) {
+	hash, mode, ok, err := b.lookup("", path)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return b.blobFile(path, hash, mode, -1)
+}
+
+func (b *GoGitBackend) revisionFile(rev, path string) (*diffFile, error) {
+	hash, mode, ok, err := b.lookup(rev, path)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return b.blobFile(path, hash, mode, -1)
+}
+
+// lookup finds the blob of path at rev, or in the index when rev is empty,
+// reporting false when the path does not exist there.
+func (b *GoGitBackend) lookup(rev, path string) (plumbing.Hash, filemode.FileMode, bool, error) {
+	if rev == "" {
+		index, err := b.repo.Storer.Index()
+		if err != nil {
+			return plumbing.ZeroHash, 0, false, err
+		}
+		entry, err := index.Entry(path)
+		if err != nil {
+			return plumbing.ZeroHash, 0, false, nil
+		}
+		return entry.Hash, entry.Mode, true, nil
+	}
+	hash, err := b.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return plumbing.ZeroHash, 0, false, fmt.Errorf("resolve %s: %w", rev, err)
+	}
+	commit, err := b.repo.CommitObject(*hash)
+	if err != nil {
+		return plumbing.ZeroHash, 0, false, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return plumbing.ZeroHash, 0, false, err
+	}
+	entry, err := tree.FindEntry(path)
+	if err != nil {
+		return plumbing.ZeroHash, 0, false, nil
+	}
+	return entry.Hash, entry.Mode, true, nil
+}
+
+// blobFile reads a blob, stopping after limit bytes unless limit is
+// negative.
+func (b *GoGitBackend) blobFile(path string, hash plumbing.Hash, mode filemode.FileMode, limit int64) (*diffFile, error) {
+	if mode == filemode.Submodule {
+		data := []byte("Subproject commit " + hash.String() + "\n")
+		return &diffFile{path: path, mode: mode, hash: hash, data: data, size: int64(len(data))}, nil
+	}
+	blob, err := b.repo.BlobObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	var source io.Reader = reader
+	if limit >= 0 {
+		source = io.LimitReader(reader, limit)
+	}
+	var data bytes.Buffer
+	if _, err := data.ReadFrom(source); err != nil {
+		return nil, err
+	}
+	return &diffFile{path: path, mode: mode, hash: hash, data: data.Bytes(), size: blob.Size}, nil
+}
+
+// ReadFile reads at most limit bytes of a worktree file.
+func (b *GoGitBackend) ReadFile(ctx context.Context, path string, limit int64) (FileData, error) {
+	if err := ctx.Err(); err != nil {
+		return FileData{}, err
+	}
+	return ReadFile(b.root, path, limit)
+}
+
+// ReadBlob reads at most limit bytes of path at rev, or from the index
+// when rev is empty.
+func (b *GoGitBackend) ReadBlob(ctx context.Context, rev, path string, limit int64) (FileData, error) {
+	if err := ctx.Err(); err != nil {
+		return FileData{}, err
+	}
+	hash, mode, ok, err := b.lookup(rev, path)
+	if err != nil {
+		return FileData{}, err
+	}
+	if !ok {
+		return FileData{}, fmt.Errorf("%s does not exist in %s", path, describeRev(rev))
+	}
+	file, err := b.blobFile(path, hash, mode, limit)
+	if err != nil {
+		return FileData{}, err
+	}
+	return newFileData(file.data, file.size), nil
+}
+
+func describeRev(rev string) string {
+	if rev == "" {
+		return "the index"
+	}
+	return rev
+}
+
+// FileContents reads path from the worktree, like the exec backend.
+func (b *GoGitBackend) FileContents(ctx context.Context, path string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return FileContents(b.root, path)
+}
+
+// BlobContents reads path at rev, or from the index when rev is empty.
+func (b *GoGitBackend) BlobContents(ctx context.Context, rev, path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	var file *diffFile
+	var err error
+	if rev == "" {
+		file, err = b.indexFile(path)
+	} else {
+		file, err = b.revisionFile(rev, path)
+	}
+	if err != nil {
+		return "", err
+	}
+	if file == nil {
+		return "", fmt.Errorf("%s does not exist in %s", path, describeRev(rev))
+	}
+	return strings.TrimRight(string(file.data), "\n"), nil
+}
+
+// HashFiles hashes worktree files as blobs, like git hash-object but
+// without running clean filters.
+func (b *GoGitBackend) HashFiles(ctx context.Context, paths []string) (map[string]string, error) {
+	hashes := make(map[string]string, len(paths))
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if strings.Contains(path, "\n") {
+			continue
+		}
+		file, err := b.worktreeFile(path)
+		if err != nil || file == nil {
+			hashes[path] = DeletedHash
+			continue
+		}
+		hashes[path] = file.hash.String()
+	}
+	return hashes, nil
+}
+
+// Commit runs git commit so hooks, signing and the rest of the user's
+// config apply, as they would outside wing.
+func (b *GoGitBackend) Commit(message string) error {
+	return b.exec.Commit(message)
+}
+
+// Push runs git push so credential helpers and hooks keep working.
+func (b *GoGitBackend) Push() error {
+	return b.exec.Push()
+}
+
+// VerifyBase resolves base through git, which knows every revision
+// syntax a user may type.
+func (b *GoGitBackend) VerifyBase(base string) error {
+	return b.exec.VerifyBase(base)
+}
+
+// ResolveBase goes through git, which StatusAgainst already runs for any
+// refresh with a base.
+func (b *GoGitBackend) ResolveBase(ctx context.Context, base string) (string, error) {
+	return b.exec.ResolveBase(ctx, base)
+}
+
+// Patch builds the patch through git, since go-git cannot write binary
+// patches or detect renames in a diff.
+func (b *GoGitBackend) Patch(base string, entries []StatusEntry) (string, error) {
+	return b.exec.Patch(base, entries)
+}
+
+func (b *GoGitBackend) FormatPatch(subject, patch string, date time.Time) (string, error) {
+	return b.exec.FormatPatch(subject, patch, date)
+}
+
+// CheckPatch and ApplyPatch run git apply; go-git cannot apply patches.
+func (b *GoGitBackend) CheckPatch(patch string) (PatchCheck, error) {
+	return b.exec.CheckPatch(patch)
+}
+
+func (b *GoGitBackend) ApplyPatch(patch string, mode ApplyMode) error {
+	return b.exec.ApplyPatch(patch, mode)
+}
+
+// CreateCheckpoint, Checkpoints and RestoreCheckpoint go through git,
+// which stages snapshots into a scratch index.
+func (b *GoGitBackend) CreateCheckpoint() (Checkpoint, bool, error) {
+	return b.exec.CreateCheckpoint()
+}
+
+func (b *GoGitBackend) Checkpoints() ([]Checkpoint, error) {
+	return b.exec.Checkpoints()
+}
+
+func (b *GoGitBackend) RestoreCheckpoint(id string) (Checkpoint, error) {
+	return b.exec.RestoreCheckpoint(id)
+}
+
+func (b *GoGitBackend) Branch(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	head, err := b.repo.Head()
+	if err != nil {
+		return "", err
+	}
+	if !head.Name().IsBranch() {
+		return "HEAD", nil
+	}
+	return head.Name().Short(), nil
+}
+
+// diffFile is one side of an in-process diff.
+type diffFile struct {
+	path string
+	mode filemode.FileMode
+	hash plumbing.Hash
+	data []byte
+	size int64
+}
+
+func (f *diffFile) Hash() plumbing.Hash     { return f.hash }
+func (f *diffFile) Mode() filemode.FileMode { return f.mode }
+func (f *diffFile) Path() string            { return f.path }
+
+// filePatch adapts two diffFiles to go-git's unified encoder.
+type filePatch struct {
+	from, to *diffFile
+	binary   bool
+	chunks   []fdiff.Chunk
+}
+
+type chunk struct {
+	content string
+	op      fdiff.Operation
+}
+
+func (c chunk) Content() string           { return c.content }
+func (c chunk) Type() fdiff.Operation     { return c.op }
+func (p filePatch) IsBinary() bool        { return p.binary }
+func (p filePatch) Chunks() []fdiff.Chunk { return p.chunks }
+func (p filePatch) FilePatches() []fdiff.FilePatch {
+	return []fdiff.FilePatch{p}
+}
+func (p filePatch) Message() string { return "" }
+
+func (p filePatch) Files() (fdiff.File, fdiff.File) {
+	var from, to fdiff.File
+	if p.from != nil {
+		from = p.from
+	}
+	if p.to != nil {
+		to = p.to
+	}
+	return from, to
+}
+
+func newFilePatch(from, to *diffFile) filePatch {
+	patch := filePatch{from: from, to: to}
+	var src, dst []byte
+	if from != nil {
+		src = from.data
+	}
+	if to != nil {
+		dst = to.data
+	}
+	if isBinary(src) || isBinary(dst) {
+		patch.binary = true
+		return patch
+	}
+	for _, d := range udiff.Do(string(src), string(dst)) {
+		op := fdiff.Equal
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			op = fdiff.Add
+		case diffmatchpatch.DiffDelete:
+			op = fdiff.Delete
+		}
+		patch.chunks = append(patch.chunks, chunk{content: d.Text, op: op})
+	}
+	return patch
+}
+
+func isBinary(data []byte) bool {
+	binary, err := binary.IsBinary(bytes.NewReader(data))
+	return err == nil && binary
+}
@@ -0,0 +1,285 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGoGitMatchesExec(t *testing.T) {
+	// A global excludes file hides editor droppings from both backends.
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	writeFile(t, home, "ignore", ".DS_Store\n")
+	writeFile(t, home, ".gitconfig", "[core]\n\texcludesFile = "+filepath.Join(home, "ignore")+"\n")
+
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-q", "-b", "main")
+	writeFile(t, repo, ".gitignore", "*.log\n")
+	writeFile(t, repo, "a.txt", "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\n")
+	writeFile(t, repo, "b.txt", "keep\n")
+	writeFile(t, repo, "gone.txt", "bye\n")
+	runGit(t, repo, "add", "-A")
+	commitAll(t, repo, "first")
+
+	writeFile(t, repo, "a.txt", "one\ntwo\n3\nfour\nfive\nsix\nseven\neight\nnine\n")
+	writeFile(t, repo, "staged.txt", "staged\n")
+	runGit(t, repo, "add", "staged.txt")
+	writeFile(t, repo, "dir/new.txt", "new\n")
+	writeFile(t, repo, "debug.log", "noise\n")
+	writeFile(t, repo, ".DS_Store", "noise\n")
+	runGit(t, repo, "rm", "-q", "gone.txt")
+
+	backend, err := NewBackend(BackendGoGit, repo)
+	if err != nil {
+		t.Fatalf("NewBackend error: %v", err)
+	}
+	exec := ExecBackend{RepoPath: repo}
+	ctx := context.Background()
+
+	want, wantBranch, err := exec.Status(ctx)
+	if err != nil {
+		t.Fatalf("exec Status error: %v", err)
+	}
+	got, gotBranch, err := backend.Status(ctx)
+	if err != nil {
+		t.Fatalf("go-git Status error: %v", err)
+	}
+	if statusSummary(got) != statusSummary(want) {
+		t.Fatalf("status mismatch:\n go-git %s\n exec   %s", statusSummary(got), statusSummary(want))
+	}
+	if strings.Contains(statusSummary(got), ".DS_Store") {
+		t.Fatalf("expected the global excludes to hide .DS_Store, got %s", statusSummary(got))
+	}
+	wantStats, err := exec.NumStat(ctx, "", []string{"dir/new.txt"})
+	if err != nil {
+		t.Fatalf("exec NumStat error: %v", err)
+	}
+	gotStats, err := backend.NumStat(ctx, "", []string{"dir/new.txt"})
+	if err != nil || !reflect.DeepEqual(gotStats, wantStats) || len(gotStats) == 0 {
+		t.Fatalf("numstat mismatch: go-git %v (%v), exec %v", gotStats, err, wantStats)
+	}
+	for i := range want {
+		if got[i].IndexHash != want[i].IndexHash {
+			t.Fatalf("index hash mismatch for %s: go-git %q, exec %q", want[i].Path, got[i].IndexHash, want[i].IndexHash)
+		}
+	}
+	if gotBranch.Head != wantBranch.Head || gotBranch.OID != wantBranch.OID {
+		t.Fatalf("branch mismatch: go-git %+v, exec %+v", gotBranch, wantBranch)
+	}
+
+	for _, ignored := range []bool{false, true} {
+		want, err := exec.ListFiles(ctx, ignored)
+		if err != nil {
+			t.Fatalf("exec ListFiles error: %v", err)
+		}
+		got, err := backend.ListFiles(ctx, ignored)
+		if err != nil {
+			t.Fatalf("go-git ListFiles error: %v", err)
+		}
+		if statusSummary(got) != statusSummary(want) {
+			t.Fatalf("ListFiles(%v) mismatch:\n go-git %s\n exec   %s", ignored, statusSummary(got), statusSummary(want))
+		}
+	}
+
+	paths := []string{"a.txt", "dir/new.txt", "gone.txt"}
+	wantHashes, err := exec.HashFiles(ctx, paths)
+	if err != nil {
+		t.Fatalf("exec HashFiles error: %v", err)
+	}
+	gotHashes, err := backend.HashFiles(ctx, paths)
+	if err != nil {
+		t.Fatalf("go-git HashFiles error: %v", err)
+	}
+	for _, path := range paths {
+		if gotHashes[path] != wantHashes[path] {
+			t.Fatalf("hash mismatch for %s: go-git %q, exec %q", path, gotHashes[path], wantHashes[path])
+		}
+	}
+	for _, blob := range [][2]string{{"HEAD", "a.txt"}, {"", "staged.txt"}} {
+		want, err := exec.BlobContents(ctx, blob[0], blob[1])
+		if err != nil {
+			t.Fatalf("exec BlobContents %v error: %v", blob, err)
+		}
+		if got, err := backend.BlobContents(ctx, blob[0], blob[1]); err != nil || got != want {
+			t.Fatalf("BlobContents %v mismatch: go-git %q (%v), exec %q", blob, got, err, want)
+		}
+	}
+	for _, blob := range [][2]string{{"HEAD", "a.txt"}, {"", "staged.txt"}} {
+		want, err := exec.ReadBlob(ctx, blob[0], blob[1], 3)
+		if err != nil {
+			t.Fatalf("exec ReadBlob %v error: %v", blob, err)
+		}
+		got, err := backend.ReadBlob(ctx, blob[0], blob[1], 3)
+		if err != nil || string(got.Data) != string(want.Data) || got.Size != want.Size || got.Truncated != want.Truncated {
+			t.Fatalf("ReadBlob %v mismatch: go-git %+v (%v), exec %+v", blob, got, err, want)
+		}
+	}
+
+	for _, tc := range []struct {
+		entry StatusEntry
+		opts  DiffOptions
+	}{
+		{StatusEntry{Path: "a.txt", Status: "M"}, DiffOptions{Context: DefaultContext}},
+		{StatusEntry{Path: "a.txt", Status: "M"}, DiffOptions{Context: 1}},
+		{StatusEntry{Path: "a.txt", Status: "M"}, DiffOptions{Context: 0}},
+		{StatusEntry{Path: "a.txt", Status: "M"}, DiffOptions{Base: "HEAD", Context: DefaultContext}},
+		{StatusEntry{Path: "staged.txt", Status: "A"}, DiffOptions{Base: "HEAD", Context: DefaultContext}},
+		{StatusEntry{Path: "gone.txt", Status: "D"}, DiffOptions{Base: "HEAD", Context: DefaultContext}},
+		{StatusEntry{Path: "dir/new.txt", Status: "??"}, DiffOptions{Context: DefaultContext}},
+		{StatusEntry{Path: "b.txt", Status: ""}, DiffOptions{Context: DefaultContext}},
+	} {
+		want, err := exec.Diff(ctx, tc.entry, tc.opts)
+		if err != nil {
+			t.Fatalf("exec Diff %s error: %v", tc.entry.Path, err)
+		}
+		got, err := backend.Diff(ctx, tc.entry, tc.opts)
+		if err != nil {
+			t.Fatalf("go-git Diff %s error: %v", tc.entry.Path, err)
+		}
+		if hunks(got) != hunks(want) {
+			t.Fatalf("diff mismatch for %s %+v:\n go-git:\n%s\n exec:\n%s", tc.entry.Path, tc.opts, got, want)
+		}
+	}
+}
+
+func TestGoGitCommit(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-q", "-b", "main")
+	runGit(t, repo, "config", "user.name", "wing")
+	runGit(t, repo, "config", "user.email", "wing@example.com")
+	writeFile(t, repo, "a.txt", "one\n")
+
+	backend, err := OpenGoGit(repo)
+	if err != nil {
+		t.Fatalf("OpenGoGit error: %v", err)
+	}
+	ctx := context.Background()
+	if _, branch, err := backend.Status(ctx); err != nil || branch.Head != "main" || branch.OID != "" {
+		t.Fatalf("expected unborn main branch, got %+v (%v)", branch, err)
+	}
+	if err := backend.Commit("first"); err != nil {
+		t.Fatalf("Commit error: %v", err)
+	}
+	entries, branch, err := backend.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status error: %v", err)
+	}
+	if len(entries) != 0 || branch.OID == "" {
+		t.Fatalf("expected a clean tree after commit, got %+v %+v", entries, branch)
+	}
+	if name, err := backend.Branch(ctx); err != nil || name != "main" {
+		t.Fatalf("expected branch main, got %q (%v)", name, err)
+	}
+}
+
+func TestNewBackendRejectsUnknownName(t *testing.T) {
+	if _, err := NewBackend("libgit2", t.TempDir()); err == nil {
+		t.Fatalf("expected an unknown backend to fail")
+	}
+	backend, err := NewBackend("", "repo")
+	if err != nil || backend != (ExecBackend{RepoPath: "repo"}) {
+		t.Fatalf("expected the exec backend by default, got %#v (%v)", backend, err)
+	}
+}
+
+func statusSummary(entries []StatusEntry) string {
+	parts := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		parts = append(parts, entry.Status+":"+entry.Path)
+	}
+	return strings.Join(parts, " ")
+}
+
+// hunks drops the file header lines, whose index abbreviations and
+// mode lines differ between backends.
+func hunks(diff string) string {
+	if idx := strings.Index(diff, "@@"); idx >= 0 {
+		return diff[idx:]
+	}
+	return diff
+}
+
+func TestGoGitListFilesHonoursExcludes(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-q", "-b", "main")
+	excludes := filepath.Join(t.TempDir(), "ignore")
+	writeFile(t, filepath.Dir(excludes), "ignore", "*.tmp\n")
+	runGit(t, repo, "config", "core.excludesFile", excludes)
+	writeFile(t, repo, ".git/info/exclude", "local/\n")
+	writeFile(t, repo, "keep.txt", "keep\n")
+	writeFile(t, repo, "scratch.tmp", "scratch\n")
+	writeFile(t, repo, "local/notes.txt", "notes\n")
+
+	backend, err := NewBackend(BackendGoGit, repo)
+	if err != nil {
+		t.Fatalf("NewBackend error: %v", err)
+	}
+	exec := ExecBackend{RepoPath: repo}
+	ctx := context.Background()
+	for _, ignored := range []bool{false, true} {
+		want, err := exec.ListFiles(ctx, ignored)
+		if err != nil {
+			t.Fatalf("exec ListFiles error: %v", err)
+		}
+		got, err := backend.ListFiles(ctx, ignored)
+		if err != nil {
+			t.Fatalf("go-git ListFiles error: %v", err)
+		}
+		if statusSummary(got) != statusSummary(want) {
+			t.Fatalf("ListFiles(%v) mismatch:\n go-git %s\n exec   %s", ignored, statusSummary(got), statusSummary(want))
+		}
+	}
+}
+
+func TestGoGitOpensLinkedWorktrees(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-q", "-b", "main")
+	writeFile(t, repo, "a.txt", "one\n")
+	runGit(t, repo, "add", "-A")
+	commitAll(t, repo, "first")
+	linked := filepath.Join(t.TempDir(), "linked")
+	runGit(t, repo, "worktree", "add", "-q", "-b", "agent", linked)
+	writeFile(t, linked, "a.txt", "two\n")
+
+	backend, err := OpenGoGit(linked)
+	if err != nil {
+		t.Fatalf("OpenGoGit error: %v", err)
+	}
+	ctx := context.Background()
+	entries, branch, err := backend.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status error: %v", err)
+	}
+	if branch.Head != "agent" || branch.OID == "" || statusSummary(entries) != "M:a.txt" {
+		t.Fatalf("expected a.txt modified on agent, got %s %+v", statusSummary(entries), branch)
+	}
+	diff, err := backend.Diff(ctx, entries[0], DiffOptions{Base: "HEAD", Context: DefaultContext})
+	if err != nil || !strings.Contains(diff, "-one") || !strings.Contains(diff, "+two") {
+		t.Fatalf("expected the diff against HEAD, got %q (%v)", diff, err)
+	}
+}
+
+func TestGoGitCommitRunsHooks(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-q", "-b", "main")
+	runGit(t, repo, "config", "user.name", "wing")
+	runGit(t, repo, "config", "user.email", "wing@example.com")
+	writeFile(t, repo, ".git/hooks/pre-commit", "#!/bin/sh\necho rejected >&2\nexit 1\n")
+	if err := os.Chmod(filepath.Join(repo, ".git", "hooks", "pre-commit"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, repo, "a.txt", "one\n")
+
+	backend, err := OpenGoGit(repo)
+	if err != nil {
+		t.Fatalf("OpenGoGit error: %v", err)
+	}
+	if err := backend.Commit("first"); err == nil || !strings.Contains(err.Error(), "rejected") {
+		t.Fatalf("expected the pre-commit hook to reject the commit, got %v", err)
+	}
+}
@@ -0,0 +1,256 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FileDiff is a single-file unified diff split into its header and hunks.
+type FileDiff struct {
+	Header []string
+	Hunks  []Hunk
+}
+
+// Hunk is one "@@" section of a unified diff. Origins lists the indices of
+// the parsed hunks it was built from, which only differs from its own index
+// once expansion has merged neighbouring hunks.
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Section  string
+	Lines    []string
+	Origins  []int
+}
+
+// ParseDiff splits the output of Diff for a single file into hunks. Text
+// that contains no hunks is returned as header only.
+func ParseDiff(text string) FileDiff {
+	var diff FileDiff
+	if text == "" {
+		return diff
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "@@ ") {
+			hunk, ok := parseHunkHeader(line)
+			if ok {
+				hunk.Origins = []int{len(diff.Hunks)}
+				diff.Hunks = append(diff.Hunks, hunk)
+				continue
+			}
+		}
+		if len(diff.Hunks) == 0 {
+			diff.Header = append(diff.Header, line)
+			continue
+		}
+		last := &diff.Hunks[len(diff.Hunks)-1]
+		last.Lines = append(last.Lines, line)
+	}
+	return diff
+}
+
+func parseHunkHeader(line string) (Hunk, bool) {
+	rest := strings.TrimPrefix(line, "@@ ")
+	end := strings.Index(rest, " @@")
+	if end == -1 {
+		return Hunk{}, false
+	}
+	ranges := strings.Fields(rest[:end])
+	if len(ranges) != 2 || !strings.HasPrefix(ranges[0], "-") || !strings.HasPrefix(ranges[1], "+") {
+		return Hunk{}, false
+	}
+	oldStart, oldLines, ok := parseRange(ranges[0][1:])
+	if !ok {
+		return Hunk{}, false
+	}
+	newStart, newLines, ok := parseRange(ranges[1][1:])
+	if !ok {
+		return Hunk{}, false
+	}
+	return Hunk{
+		OldStart: oldStart,
+		OldLines: oldLines,
+		NewStart: newStart,
+		NewLines: newLines,
+		Section:  strings.TrimPrefix(rest[end+3:], " "),
+	}, true
+}
+
+func parseRange(text string) (int, int, bool) {
+	startText, countText, hasCount := strings.Cut(text, ",")
+	start, err := strconv.Atoi(startText)
+	if err != nil {
+		return 0, 0, false
+	}
+	count := 1
+	if hasCount {
+		count, err = strconv.Atoi(countText)
+		if err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, count, true
+}
+
+// HeaderLine renders the "@@ -a,b +c,d @@" line for the hunk.
+func (h Hunk) HeaderLine() string {
+	line := fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+	if h.Section != "" {
+		line += " " + h.Section
+	}
+	return line
+}
+
+// Lines renders the diff back to unified diff lines.
+func (d FileDiff) Lines() []string {
+	out := append([]string(nil), d.Header...)
+	for _, hunk := range d.Hunks {
+		out = append(out, hunk.HeaderLine())
+		out = append(out, hunk.Lines...)
+	}
+	return out
+}
+
+func (d FileDiff) String() string {
+	return strings.Join(d.Lines(), "\n")
+}
+
+// Expansion is the number of extra context lines to show above and below a
+// hunk.
+type Expansion struct {
+	Above int
+	Below int
+}
+
+// Expand adds context lines around hunks, keyed by the index of the hunk as
+// returned by ParseDiff. Context is read from source, which holds the new
+// side of the file, or the old side when fromOld is set (e.g. for deleted
+// files). Hunks that meet after expansion are merged.
+func (d FileDiff) Expand(source []string, fromOld bool, expand map[int]Expansion) FileDiff {
+	if len(d.Hunks) == 0 || len(expand) == 0 {
+		return d
+	}
+	out := FileDiff{Header: d.Header}
+	prevEnd := 0
+	for i, hunk := range d.Hunks {
+		hunk.Lines = append([]string(nil), hunk.Lines...)
+		hunk.Origins = append([]int(nil), hunk.Origins...)
+		first, last := hunk.span(fromOld)
+
+		grow := expand[i]
+		above := clampInt(grow.Above, 0, first-1-prevEnd)
+		if above > 0 {
+			context := contextLines(source, first-above, first-1)
+			above = len(context)
+			hunk.Lines = append(context, hunk.Lines...)
+			hunk.shiftStart(above)
+			first -= above
+		}
+
+		below := clampInt(grow.Below, 0, len(source)-last)
+		if next := i + 1; next < len(d.Hunks) {
+			nextFirst, _ := d.Hunks[next].span(fromOld)
+			below = clampInt(below, 0, nextFirst-1-last)
+		}
+		if below > 0 {
+			context := contextLines(source, last+1, last+below)
+			hunk.Lines = append(hunk.Lines, context...)
+			hunk.OldLines += len(context)
+			hunk.NewLines += len(context)
+			last += len(context)
+		}
+
+		if n := len(out.Hunks); n > 0 && first == prevEnd+1 {
+			merged := &out.Hunks[n-1]
+			merged.Lines = append(merged.Lines, hunk.Lines...)
+			merged.OldLines += hunk.OldLines
+			merged.NewLines += hunk.NewLines
+			merged.Origins = append(merged.Origins, hunk.Origins...)
+		} else {
+			out.Hunks = append(out.Hunks, hunk)
+		}
+		prevEnd = last
+	}
+	return out
+}
+
+// Overlapping returns the diff cut down to the hunks that share new-side
+// lines with any of hs. A side without lines counts as the lines around it,
+// so a pure deletion still finds the hunk it sits in.
+func (d FileDiff) Overlapping(hs ...Hunk) FileDiff {
+	out := FileDiff{Header: d.Header}
+	for _, hunk := range d.Hunks {
+		start, end := hunk.span(false)
+		if start > end {
+			start, end = end, start
+		}
+		for _, h := range hs {
+			first, last := h.span(false)
+			if first > last {
+				first, last = last, first
+			}
+			if start <= last && first <= end {
+				out.Hunks = append(out.Hunks, hunk)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// span returns the first and last line the hunk covers on one side of the
+// diff. An empty side sits just after its start line.
+func (h Hunk) span(old bool) (int, int) {
+	first, count := h.NewStart, h.NewLines
+	if old {
+		first, count = h.OldStart, h.OldLines
+	}
+	if count == 0 {
+		first++
+	}
+	return first, first + count - 1
+}
+
+func (h *Hunk) shiftStart(lines int) {
+	if h.OldLines == 0 {
+		h.OldStart++
+	}
+	if h.NewLines == 0 {
+		h.NewStart++
+	}
+	h.OldStart -= lines
+	h.NewStart -= lines
+	h.OldLines += lines
+	h.NewLines += lines
+}
+
+// contextLines returns source lines from..to (1-based, inclusive) as diff
+// context lines.
+func contextLines(source []string, from, to int) []string {
+	if from < 1 {
+		from = 1
+	}
+	if to > len(source) {
+		to = len(source)
+	}
+	if from > to {
+		return nil
+	}
+	out := make([]string, 0, to-from+1)
+	for _, line := range source[from-1 : to] {
+		out = append(out, " "+line)
+	}
+	return out
+}
+
+func clampInt(value, min, max int) int {
+	if value > max {
+		value = max
+	}
+	if value < min {
+		value = min
+	}
+	return value
+}
@@ -0,0 +1,139 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const sampleDiff = `diff --git a/f.txt b/f.txt
+index 1111111..2222222 100644
+--- a/f.txt
++++ b/f.txt
+@@ -4,3 +4,3 @@ func main() {
+ l4
+-l5
++L5
+ l6
+@@ -12,3 +12,3 @@
+ l12
+-l13
++L13
+ l14`
+
+func sampleSource() []string {
+	lines := make([]string, 0, 20)
+	for i := 1; i <= 20; i++ {
+		line := fmt.Sprintf("l%d", i)
+		if i == 5 || i == 13 {
+			line = fmt.Sprintf("L%d", i)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestParseDiffRoundTrip(t *testing.T) {
+	diff := ParseDiff(sampleDiff)
+	if len(diff.Header) != 4 {
+		t.Fatalf("expected 4 header lines, got %d", len(diff.Header))
+	}
+	if len(diff.Hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %d", len(diff.Hunks))
+	}
+	first := diff.Hunks[0]
+	if first.OldStart != 4 || first.OldLines != 3 || first.NewStart != 4 || first.NewLines != 3 {
+		t.Fatalf("unexpected first hunk range: %+v", first)
+	}
+	if first.Section != "func main() {" {
+		t.Fatalf("unexpected section %q", first.Section)
+	}
+	if diff.String() != sampleDiff {
+		t.Fatalf("expected round trip, got:\n%s", diff.String())
+	}
+}
+
+func TestExpandAboveAndBelow(t *testing.T) {
+	diff := ParseDiff(sampleDiff)
+	out := diff.Expand(sampleSource(), false, map[int]Expansion{0: {Above: 2, Below: 1}})
+	if len(out.Hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %d", len(out.Hunks))
+	}
+	hunk := out.Hunks[0]
+	if hunk.HeaderLine() != "@@ -2,6 +2,6 @@ func main() {" {
+		t.Fatalf("unexpected header %q", hunk.HeaderLine())
+	}
+	if hunk.Lines[0] != " l2" || hunk.Lines[len(hunk.Lines)-1] != " l7" {
+		t.Fatalf("unexpected expanded lines: %v", hunk.Lines)
+	}
+}
+
+func TestExpandClampsAtFileStart(t *testing.T) {
+	diff := ParseDiff(sampleDiff)
+	out := diff.Expand(sampleSource(), false, map[int]Expansion{0: {Above: 50}})
+	if out.Hunks[0].NewStart != 1 || out.Hunks[0].Lines[0] != " l1" {
+		t.Fatalf("expected expansion to stop at line 1, got %+v", out.Hunks[0])
+	}
+}
+
+func TestExpandMergesHunks(t *testing.T) {
+	diff := ParseDiff(sampleDiff)
+	source := sampleSource()
+	out := diff.Expand(source, false, map[int]Expansion{0: {Below: 5}})
+	if len(out.Hunks) != 1 {
+		t.Fatalf("expected hunks to merge, got %d", len(out.Hunks))
+	}
+	merged := out.Hunks[0]
+	if merged.HeaderLine() != "@@ -4,11 +4,11 @@ func main() {" {
+		t.Fatalf("unexpected merged header %q", merged.HeaderLine())
+	}
+	if len(merged.Origins) != 2 {
+		t.Fatalf("expected merged origins, got %v", merged.Origins)
+	}
+
+	full := diff.Expand(source, false, map[int]Expansion{0: {Above: len(source), Below: len(source)}, 1: {Above: len(source), Below: len(source)}})
+	if len(full.Hunks) != 1 {
+		t.Fatalf("expected full file hunk, got %d", len(full.Hunks))
+	}
+	lines := full.Hunks[0].Lines
+	if lines[0] != " l1" || lines[len(lines)-1] != " l20" {
+		t.Fatalf("expected full file, got %s", strings.Join(lines, "|"))
+	}
+	if full.Hunks[0].NewLines != 20 || full.Hunks[0].OldLines != 20 {
+		t.Fatalf("unexpected full file counts %+v", full.Hunks[0])
+	}
+}
+
+func TestExpandPureAddition(t *testing.T) {
+	text := "@@ -2,0 +3,2 @@\n+a\n+b"
+	source := []string{"x", "y", "a", "b", "z"}
+	out := ParseDiff(text).Expand(source, false, map[int]Expansion{0: {Above: 1, Below: 1}})
+	hunk := out.Hunks[0]
+	if hunk.HeaderLine() != "@@ -2,2 +2,4 @@" {
+		t.Fatalf("unexpected header %q", hunk.HeaderLine())
+	}
+	if strings.Join(hunk.Lines, "|") != " y|+a|+b| z" {
+		t.Fatalf("unexpected lines %v", hunk.Lines)
+	}
+}
+
+func TestOverlappingPicksHunksAtTheSameLines(t *testing.T) {
+	diff := ParseDiff(sampleDiff)
+	got := diff.Overlapping(Hunk{NewStart: 13, NewLines: 1})
+	if len(got.Hunks) != 1 || got.Hunks[0].NewStart != 12 || len(got.Header) != 4 {
+		t.Fatalf("expected the second hunk with the header, got %+v", got)
+	}
+	// A pure deletion after line 6 sits at the end of the first hunk.
+	if got := diff.Overlapping(Hunk{NewStart: 6, NewLines: 0}); len(got.Hunks) != 1 || got.Hunks[0].NewStart != 4 {
+		t.Fatalf("expected the first hunk for a deletion, got %+v", got.Hunks)
+	}
+	if got := diff.Overlapping(Hunk{NewStart: 1, NewLines: 20}); len(got.Hunks) != 2 {
+		t.Fatalf("expected an expanded hunk to take both, got %+v", got.Hunks)
+	}
+	if got := diff.Overlapping(Hunk{NewStart: 9, NewLines: 1}); len(got.Hunks) != 0 {
+		t.Fatalf("expected no hunk between them, got %+v", got.Hunks)
+	}
+	if got := diff.Overlapping(Hunk{NewStart: 13, NewLines: 1}, Hunk{NewStart: 6, NewLines: 0}); len(got.Hunks) != 2 || got.Hunks[0].NewStart != 4 {
+		t.Fatalf("expected both hunks in diff order, got %+v", got.Hunks)
+	}
+}
@@ -0,0 +1,210 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// emptyTree is the id of the empty tree, used as the base of a repository
+// without commits.
+const emptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// ApplyMode selects where ApplyPatch writes the patch.
+type ApplyMode int
+
+const (
+	// ApplyWorktree changes the files in the worktree only.
+	ApplyWorktree ApplyMode = iota
+	// ApplyIndex stages the patch without touching the worktree.
+	ApplyIndex
+	// ApplyThreeWay falls back to a three-way merge, leaving conflict
+	// markers where hunks do not apply cleanly.
+	ApplyThreeWay
+)
+
+// PatchFile is one file touched by a patch. Added and Removed are -1 for
+// binary changes.
+type PatchFile struct {
+	Path     string
+	OrigPath string
+	Added    int
+	Removed  int
+}
+
+// PatchCheck is the dry-run result of a patch: the files it touches and
+// the problems git reports when applying it to the worktree or the index.
+type PatchCheck struct {
+	Files             []PatchFile
+	WorktreeConflicts []string
+	IndexConflicts    []string
+}
+
+// Patch returns an applicable unified diff, with binary changes, of entries
+// against base. An empty base means HEAD, so staged and unstaged changes are
+// both included; untracked files are added as new files unless base is a
+// range.
+func Patch(repoPath, base string, entries []StatusEntry) (string, error) {
+	if err := checkBase(base); err != nil {
+		return "", err
+	}
+	if base == "" {
+		base = "HEAD"
+		if _, err := run(repoPath, "rev-parse", "--verify", "--quiet", "HEAD^{commit}"); err != nil {
+			base = emptyTree
+		}
+	}
+	tracked := []string{}
+	untracked := []string{}
+	for _, entry := range entries {
+		switch {
+		case entry.Ignored || entry.Path == "":
+		case entry.Status == "??":
+			if !IsRange(base) {
+				untracked = append(untracked, entry.Path)
+			}
+		default:
+			if entry.OrigPath != "" {
+				tracked = append(tracked, entry.OrigPath)
+			}
+			tracked = append(tracked, entry.Path)
+		}
+	}
+
+	var b strings.Builder
+	if len(tracked) > 0 {
+		args := []string{"diff", "--no-color", "--binary", "--find-renames", base, "--"}
+		out, err := run(repoPath, append(args, tracked...)...)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(out)
+	}
+	for _, path := range untracked {
+		out, err := run(repoPath, "diff", "--no-color", "--binary", "--no-index", "--", "/dev/null", path)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(out)
+	}
+	return b.String(), nil
+}
+
+// FormatPatch wraps patch in a git format-patch style mail with the user's
+// identity, subject and a diffstat, so it can be applied with git am.
+func FormatPatch(repoPath, subject, patch string, date time.Time) (string, error) {
+	stat, err := runInput(repoPath, patch, "apply", "--stat", "-")
+	if err != nil {
+		return "", err
+	}
+	name := configValue(repoPath, "user.name", "wing")
+	email := configValue(repoPath, "user.email", "wing@localhost")
+
+	var b strings.Builder
+	b.WriteString("From 0000000000000000000000000000000000000000 Mon Sep 17 00:00:00 2001\n")
+	fmt.Fprintf(&b, "From: %s <%s>\n", name, email)
+	fmt.Fprintf(&b, "Date: %s\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Subject: [PATCH] %s\n\n", subject)
+	b.WriteString("---\n")
+	b.WriteString(stat)
+	b.WriteString("\n")
+	b.WriteString(patch)
+	if !strings.HasSuffix(patch, "\n") {
+		b.WriteString("\n")
+	}
+	b.WriteString("-- \nwing\n")
+	return b.String(), nil
+}
+
+func configValue(repoPath, key, fallback string) string {
+	out, err := run(repoPath, "config", "--get", key)
+	if value := strings.TrimSpace(out); err == nil && value != "" {
+		return value
+	}
+	return fallback
+}
+
+// CheckPatch lists the files patch touches and dry-runs it against the
+// worktree and the index without changing either.
+func CheckPatch(repoPath, patch string) (PatchCheck, error) {
+	out, err := runInput(repoPath, patch, "apply", "--numstat", "-z", "-")
+	if err != nil {
+		return PatchCheck{}, err
+	}
+	check := PatchCheck{Files: parseNumstat(out)}
+	if check.WorktreeConflicts, err = applyProblems(repoPath, patch, "--check"); err != nil {
+		return check, err
+	}
+	if check.IndexConflicts, err = applyProblems(repoPath, patch, "--check", "--cached"); err != nil {
+		return check, err
+	}
+	return check, nil
+}
+
+// applyProblems runs git apply with args and returns the errors it reports
+// about individual files.
+func applyProblems(repoPath, patch string, args ...string) ([]string, error) {
+	args = append(append([]string{"apply"}, args...), "-")
+	_, err := runInput(repoPath, patch, args...)
+	if err == nil {
+		return nil, nil
+	}
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		return nil, err
+	}
+	problems := []string{}
+	for _, line := range strings.Split(cmdErr.Stderr, "\n") {
+		if problem, ok := strings.CutPrefix(line, "error: "); ok {
+			problems = append(problems, problem)
+		}
+	}
+	if len(problems) == 0 {
+		return nil, err
+	}
+	return problems, nil
+}
+
+// ApplyPatch applies patch to the worktree, the index, or both with a
+// three-way merge.
+func ApplyPatch(repoPath, patch string, mode ApplyMode) error {
+	args := []string{"apply"}
+	switch mode {
+	case ApplyIndex:
+		args = append(args, "--cached")
+	case ApplyThreeWay:
+		args = append(args, "--3way")
+	}
+	_, err := runInput(repoPath, patch, append(args, "-")...)
+	return err
+}
+
+// parseNumstat reads git apply --numstat -z output.
+func parseNumstat(out string) []PatchFile {
+	files := []PatchFile{}
+	fields := strings.Split(out, "\x00")
+	for i := 0; i < len(fields); i++ {
+		parts := strings.SplitN(fields[i], "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		file := PatchFile{Added: numstatCount(parts[0]), Removed: numstatCount(parts[1]), Path: parts[2]}
+		if file.Path == "" && i+2 < len(fields) {
+			file.OrigPath = fields[i+1]
+			file.Path = fields[i+2]
+			i += 2
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+func numstatCount(field string) int {
+	count, err := strconv.Atoi(field)
+	if err != nil {
+		return -1
+	}
+	return count
+}
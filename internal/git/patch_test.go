@@ -0,0 +1,114 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPatchRoundTrip(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-q")
+	writeFile(t, repo, "a.txt", "one\ntwo\n")
+	writeFile(t, repo, "gone.txt", "bye\n")
+	runGit(t, repo, "add", "-A")
+	commitAll(t, repo, "init")
+
+	clone := t.TempDir()
+	runGit(t, clone, "clone", "-q", repo, ".")
+
+	writeFile(t, repo, "a.txt", "one\n2\n")
+	runGit(t, repo, "add", "a.txt")
+	writeFile(t, repo, "dir/new.txt", "new\n")
+	if err := os.Remove(filepath.Join(repo, "gone.txt")); err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := Status(repo)
+	if err != nil {
+		t.Fatalf("Status error: %v", err)
+	}
+	patch, err := Patch(repo, "", statuses)
+	if err != nil {
+		t.Fatalf("Patch error: %v", err)
+	}
+	for _, want := range []string{"+2\n", "+++ b/dir/new.txt\n", "deleted file mode"} {
+		if !strings.Contains(patch, want) {
+			t.Fatalf("expected %q in patch:\n%s", want, patch)
+		}
+	}
+
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mail, err := FormatPatch(repo, "Update files", patch, date)
+	if err != nil {
+		t.Fatalf("FormatPatch error: %v", err)
+	}
+	for _, want := range []string{"Subject: [PATCH] Update files\n", "Date: Wed, 01 May 2024 12:00:00 +0000\n", "3 files changed"} {
+		if !strings.Contains(mail, want) {
+			t.Fatalf("expected %q in mail:\n%s", want, mail)
+		}
+	}
+
+	check, err := CheckPatch(clone, mail)
+	if err != nil {
+		t.Fatalf("CheckPatch error: %v", err)
+	}
+	if len(check.Files) != 3 || len(check.WorktreeConflicts) != 0 || len(check.IndexConflicts) != 0 {
+		t.Fatalf("unexpected check %+v", check)
+	}
+	if check.Files[0].Path != "a.txt" || check.Files[0].Added != 1 || check.Files[0].Removed != 1 {
+		t.Fatalf("unexpected numstat %+v", check.Files[0])
+	}
+	if err := ApplyPatch(clone, mail, ApplyWorktree); err != nil {
+		t.Fatalf("ApplyPatch error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(clone, "dir", "new.txt"))
+	if err != nil || string(data) != "new\n" {
+		t.Fatalf("expected new file applied, got %q (%v)", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(clone, "gone.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected gone.txt deleted")
+	}
+}
+
+func TestCheckPatchReportsConflicts(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-q")
+	writeFile(t, repo, "a.txt", "one\ntwo\n")
+	runGit(t, repo, "add", "-A")
+	commitAll(t, repo, "init")
+	writeFile(t, repo, "a.txt", "one\n2\n")
+	patch, err := Patch(repo, "", []StatusEntry{{Path: "a.txt", Status: "M"}})
+	if err != nil {
+		t.Fatalf("Patch error: %v", err)
+	}
+	writeFile(t, repo, "a.txt", "something else\n")
+
+	check, err := CheckPatch(repo, patch)
+	if err != nil {
+		t.Fatalf("CheckPatch error: %v", err)
+	}
+	if len(check.WorktreeConflicts) == 0 || !strings.Contains(strings.Join(check.WorktreeConflicts, "\n"), "a.txt") {
+		t.Fatalf("expected worktree conflict on a.txt, got %+v", check)
+	}
+	if len(check.IndexConflicts) != 0 {
+		t.Fatalf("expected patch to apply to the index, got %+v", check.IndexConflicts)
+	}
+	if err := ApplyPatch(repo, patch, ApplyWorktree); err == nil {
+		t.Fatalf("expected apply to fail")
+	}
+	if _, err := CheckPatch(repo, "not a patch\n"); err == nil {
+		t.Fatalf("expected an error for input without a patch")
+	}
+}
+
+func TestParseNumstat(t *testing.T) {
+	files := parseNumstat("1\t2\ta.txt\x00-\t-\tbin.png\x000\t0\t\x00old.txt\x00new.txt\x00")
+	if len(files) != 3 {
+		t.Fatalf("unexpected files %+v", files)
+	}
+	if files[1].Added != -1 || files[2].OrigPath != "old.txt" || files[2].Path != "new.txt" {
+		t.Fatalf("unexpected files %+v", files)
+	}
+}
@@ -0,0 +1,184 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DiffStat counts the lines a file's diff adds and removes.
+type DiffStat struct {
+	Added   int
+	Deleted int
+	// Binary is set when git does not count lines for the file.
+	Binary bool
+}
+
+// Add sums two stats; the sum is binary only when both are.
+func (s DiffStat) Add(other DiffStat) DiffStat {
+	return DiffStat{Added: s.Added + other.Added, Deleted: s.Deleted + other.Deleted, Binary: s.Binary && other.Binary}
+}
+
+// NumStat counts added and removed lines per path for the diff against
+// base, as the diff pane shows it. Untracked files are not part of any
+// git diff, so their lines are counted as added.
+func NumStat(repoPath, base string, untracked []string) (map[string]DiffStat, error) {
+	return NumStatContext(context.Background(), repoPath, base, untracked)
+}
+
+func NumStatContext(ctx context.Context, repoPath, base string, untracked []string) (map[string]DiffStat, error) {
+	if err := checkBase(base); err != nil {
+		return nil, err
+	}
+	args := []string{"diff", "--no-color", "--numstat", "-z"}
+	if base != "" {
+		args = append(args, base)
+	}
+	out, err := runContext(ctx, repoPath, args...)
+	if err != nil {
+		return nil, err
+	}
+	stats := parseNumStat(out)
+	seen := make(map[string]bool, len(untracked))
+	for _, path := range untracked {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		full := filepath.Join(repoPath, path)
+		seen[full] = true
+		if stat, err := lineCounts.count(full); err == nil {
+			stats[path] = stat
+		}
+	}
+	lineCounts.prune(repoPath, seen)
+	return stats, nil
+}
+
+// lineCounts remembers the counts of untracked files between refreshes, so
+// only files that changed since are read again.
+var lineCounts = &lineCountCache{entries: make(map[string]countedFile)}
+
+type lineCountCache struct {
+	mu      sync.Mutex
+	entries map[string]countedFile
+}
+
+// countedFile is a count together with the size and modification time the
+// file had when it was read, as git's own index checks them.
+type countedFile struct {
+	size    int64
+	modTime time.Time
+	stat    DiffStat
+}
+
+// count returns the lines of the file at path, reading it only when its
+// size or modification time differs from the last count.
+func (c *lineCountCache) count(path string) (DiffStat, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return DiffStat{}, err
+	}
+	c.mu.Lock()
+	cached, ok := c.entries[path]
+	c.mu.Unlock()
+	if ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.stat, nil
+	}
+	stat, err := countLines(path)
+	if err != nil {
+		return DiffStat{}, err
+	}
+	c.mu.Lock()
+	c.entries[path] = countedFile{size: info.Size(), modTime: info.ModTime(), stat: stat}
+	c.mu.Unlock()
+	return stat, nil
+}
+
+// prune forgets the files of repoPath that are no longer untracked.
+func (c *lineCountCache) prune(repoPath string, keep map[string]bool) {
+	prefix := filepath.Clean(repoPath) + string(filepath.Separator)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for path := range c.entries {
+		if strings.HasPrefix(path, prefix) && !keep[path] {
+			delete(c.entries, path)
+		}
+	}
+}
+
+// parseNumStat reads `git diff --numstat -z` output. Each record is
+// "added\tdeleted\tpath", or "added\tdeleted\t" followed by the old and
+// new path as two more fields for a rename; binary files count "-".
+func parseNumStat(out string) map[string]DiffStat {
+	stats := make(map[string]DiffStat)
+	fields := strings.Split(out, "\x00")
+	for i := 0; i < len(fields); i++ {
+		added, rest, ok := strings.Cut(fields[i], "\t")
+		if !ok {
+			continue
+		}
+		deleted, path, ok := strings.Cut(rest, "\t")
+		if !ok {
+			continue
+		}
+		if path == "" && i+2 < len(fields) {
+			path = fields[i+2]
+			i += 2
+		}
+		if path == "" {
+			continue
+		}
+		if added == "-" || deleted == "-" {
+			stats[path] = DiffStat{Binary: true}
+			continue
+		}
+		a, errA := strconv.Atoi(added)
+		d, errD := strconv.Atoi(deleted)
+		if errA != nil || errD != nil {
+			continue
+		}
+		stats[path] = DiffStat{Added: a, Deleted: d}
+	}
+	return stats
+}
+
+// countLines counts the lines of a new file the way numstat would,
+// reading it in chunks so large files are not held in memory.
+func countLines(path string) (DiffStat, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return DiffStat{}, err
+	}
+	defer file.Close()
+	buf := make([]byte, 32<<10)
+	lines := 0
+	first := true
+	var last byte
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			if first && IsBinary(buf[:n]) {
+				return DiffStat{Binary: true}, nil
+			}
+			first = false
+			lines += bytes.Count(buf[:n], []byte{'\n'})
+			last = buf[n-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return DiffStat{}, err
+		}
+	}
+	if !first && last != '\n' {
+		lines++
+	}
+	return DiffStat{Added: lines}, nil
+}
@@ -0,0 +1,35 @@
+package graphics
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// halfBlocks draws two pixel rows per cell row: the upper half block takes
+// the top pixel as its foreground and the bottom one as its background.
+// Colors go through lipgloss, so they degrade with the color profile.
+func halfBlocks(img *image.RGBA, cols, rows int) []string {
+	out := make([]string, rows)
+	var b strings.Builder
+	for row := 0; row < rows; row++ {
+		b.Reset()
+		for col := 0; col < cols; col++ {
+			top := flatten(img.RGBAAt(col, row*2))
+			bottom := flatten(img.RGBAAt(col, row*2+1))
+			b.WriteString(lipgloss.NewStyle().
+				Foreground(lipgloss.Color(hexColor(top))).
+				Background(lipgloss.Color(hexColor(bottom))).
+				Render("▀"))
+		}
+		out[row] = b.String()
+	}
+	return out
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -0,0 +1,255 @@
+// Package graphics draws pictures in a terminal pane: with the kitty
+// graphics protocol or sixel where the terminal supports them, and with
+// Unicode half blocks everywhere else.
+package graphics
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"strings"
+	"sync/atomic"
+)
+
+// Protocol is how pictures are sent to the terminal.
+type Protocol int
+
+const (
+	HalfBlocks Protocol = iota
+	Kitty
+	Sixel
+)
+
+// Protocol names accepted by Parse.
+const (
+	NameAuto   = "auto"
+	NameBlocks = "blocks"
+	NameKitty  = "kitty"
+	NameSixel  = "sixel"
+)
+
+func (p Protocol) String() string {
+	switch p {
+	case Kitty:
+		return NameKitty
+	case Sixel:
+		return NameSixel
+	default:
+		return NameBlocks
+	}
+}
+
+// Parse turns a protocol name into a Protocol, detecting it from the
+// environment for "auto" or "".
+func Parse(name string, getenv func(string) string) (Protocol, error) {
+	switch name {
+	case "", NameAuto:
+		return Detect(getenv), nil
+	case NameBlocks:
+		return HalfBlocks, nil
+	case NameKitty:
+		return Kitty, nil
+	case NameSixel:
+		return Sixel, nil
+	}
+	return HalfBlocks, fmt.Errorf("unknown graphics protocol %q (want %s, %s, %s or %s)", name, NameAuto, NameKitty, NameSixel, NameBlocks)
+}
+
+// Detect guesses the best protocol the terminal supports. Multiplexers
+// need passthrough for either protocol, so they get half blocks. So do
+// sixel terminals: a sixel is drawn once, in its first row, and a repaint of
+// any row below it blanks that part of the picture, so sixel is only used
+// when asked for.
+func Detect(getenv func(string) string) Protocol {
+	if getenv("TMUX") != "" || strings.HasPrefix(getenv("TERM"), "screen") {
+		return HalfBlocks
+	}
+	term := getenv("TERM")
+	if getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty" || term == "xterm-ghostty" || getenv("TERM_PROGRAM") == "ghostty" {
+		return Kitty
+	}
+	return HalfBlocks
+}
+
+// maxThumbnail bounds the pixels kept of a decoded picture; no pane is
+// wide enough to show more.
+const maxThumbnail = 640
+
+// maxPixels bounds the size of a picture Decode accepts. Decoding holds
+// the whole picture in memory, so a small file claiming huge dimensions
+// is refused before any pixel is read.
+const maxPixels = 1 << 25
+
+// cellWidth and cellHeight are the assumed size of a terminal cell in
+// pixels, used to size sixel output. Cells are about twice as tall as
+// they are wide.
+const (
+	cellWidth  = 10
+	cellHeight = 20
+)
+
+var lastID atomic.Uint32
+
+// Image is a decoded picture, scaled down to what a pane can show.
+type Image struct {
+	// Width and Height are the size of the original in pixels.
+	Width  int
+	Height int
+	// Format is the name of the decoder, e.g. "png".
+	Format string
+	thumb  *image.RGBA
+	id     uint32
+}
+
+// Decode reads a PNG, JPEG or GIF picture of at most maxPixels pixels.
+func Decode(data []byte) (*Image, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if int64(config.Width)*int64(config.Height) > maxPixels {
+		return nil, fmt.Errorf("%d×%d %s image is too large to show", config.Width, config.Height, format)
+	}
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	bounds := src.Bounds()
+	if bounds.Empty() {
+		return nil, fmt.Errorf("empty %s image", format)
+	}
+	return &Image{
+		Width:  bounds.Dx(),
+		Height: bounds.Dy(),
+		Format: format,
+		thumb:  thumbnail(src, maxThumbnail),
+		id:     lastID.Add(1) & 0xffffff,
+	}, nil
+}
+
+// Footprint returns the bytes of memory the decoded picture holds.
+func (img *Image) Footprint() int {
+	return len(img.thumb.Pix)
+}
+
+// Fit returns the cells the picture takes when drawn at most cols wide
+// and rows high, keeping its aspect ratio.
+func (img *Image) Fit(cols, rows int) (int, int) {
+	if cols < 1 || rows < 1 {
+		return 0, 0
+	}
+	// Half-block rows hold two pixels, which is also the cell aspect.
+	fitRows := (cols*img.Height + img.Width - 1) / img.Width / 2
+	if fitRows < 1 {
+		fitRows = 1
+	}
+	if fitRows <= rows {
+		return cols, fitRows
+	}
+	fitCols := rows * 2 * img.Width / img.Height
+	if fitCols < 1 {
+		fitCols = 1
+	}
+	return fitCols, rows
+}
+
+// Picture is an image laid out in cells.
+type Picture struct {
+	// Setup must be written before the first visible row; it transmits
+	// the image to terminals that place it by reference.
+	Setup string
+	// Rows are exactly cols cells wide each.
+	Rows []string
+}
+
+// Render lays the image out in cols×rows cells with protocol p.
+func (img *Image) Render(p Protocol, cols, rows int) Picture {
+	if cols < 1 || rows < 1 {
+		return Picture{}
+	}
+	switch p {
+	case Kitty:
+		return img.kitty(cols, rows)
+	case Sixel:
+		return img.sixel(cols, rows)
+	}
+	return Picture{Rows: halfBlocks(scale(img.thumb, cols, rows*2), cols, rows)}
+}
+
+// thumbnail shrinks src so neither side exceeds limit, reading it in place
+// rather than copying the full-size picture first.
+func thumbnail(src image.Image, limit int) *image.RGBA {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > limit || height > limit {
+		if width >= height {
+			height = height * limit / width
+			width = limit
+		} else {
+			width = width * limit / height
+			height = limit
+		}
+	}
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	return scale(src, width, height)
+}
+
+// scale resizes src to width×height by averaging the pixels each target
+// pixel covers, or repeating them when growing.
+func scale(src image.Image, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	at := func(x, y int) color.RGBA {
+		return color.RGBAModel.Convert(src.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.RGBA)
+	}
+	if rgba, ok := src.(*image.RGBA); ok {
+		at = func(x, y int) color.RGBA {
+			return rgba.RGBAAt(bounds.Min.X+x, bounds.Min.Y+y)
+		}
+	}
+	for y := 0; y < height; y++ {
+		y0 := y * srcH / height
+		y1 := max((y+1)*srcH/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := x * srcW / width
+			x1 := max((x+1)*srcW/width, x0+1)
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := at(sx, sy)
+					r += uint32(c.R)
+					g += uint32(c.G)
+					b += uint32(c.B)
+					a += uint32(c.A)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), uint8(a / n)})
+		}
+	}
+	return dst
+}
+
+// flatten blends a premultiplied pixel onto black, the usual terminal
+// background.
+func flatten(c color.RGBA) color.RGBA {
+	return color.RGBA{c.R, c.G, c.B, 0xff}
+}
+
+func blankRows(cols, rows int) []string {
+	out := make([]string, rows)
+	for i := range out {
+		out[i] = strings.Repeat(" ", cols)
+	}
+	return out
+}
@@ -0,0 +1,186 @@
+package graphics
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/termenv"
+)
+
+// testPNG encodes a width×height image, red on top and blue below.
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{0xff, 0, 0, 0xff}
+			if y >= height/2 {
+				c = color.RGBA{0, 0, 0xff, 0xff}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// hugePNG is a PNG header claiming a width×height picture with no pixel
+// data behind it.
+func hugePNG(width, height uint32) []byte {
+	ihdr := make([]byte, 17)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], width)
+	binary.BigEndian.PutUint32(ihdr[8:], height)
+	ihdr[12] = 8 // bit depth
+	ihdr[13] = 6 // RGBA
+	out := []byte("\x89PNG\r\n\x1a\n")
+	out = binary.BigEndian.AppendUint32(out, 13)
+	out = append(out, ihdr...)
+	return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(ihdr))
+}
+
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func TestParseAndDetect(t *testing.T) {
+	cases := []struct {
+		name string
+		vars map[string]string
+		want Protocol
+	}{
+		{"auto", map[string]string{"TERM": "xterm-kitty"}, Kitty},
+		{"", map[string]string{"TERM": "foot"}, HalfBlocks},
+		{"auto", map[string]string{"TERM_PROGRAM": "WezTerm"}, HalfBlocks},
+		{"auto", map[string]string{"TERM": "xterm-kitty", "TMUX": "/tmp/tmux"}, HalfBlocks},
+		{"auto", map[string]string{"TERM": "xterm-256color"}, HalfBlocks},
+		{"sixel", map[string]string{"TERM": "xterm-kitty"}, Sixel},
+		{"blocks", nil, HalfBlocks},
+	}
+	for _, c := range cases {
+		got, err := Parse(c.name, env(c.vars))
+		if err != nil || got != c.want {
+			t.Errorf("Parse(%q, %v) = %v, %v; want %v", c.name, c.vars, got, err, c.want)
+		}
+	}
+	if _, err := Parse("ascii", env(nil)); err == nil {
+		t.Fatalf("expected an unknown protocol to be rejected")
+	}
+}
+
+func TestDecodeAndFit(t *testing.T) {
+	img, err := Decode(testPNG(t, 1000, 500))
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if img.Width != 1000 || img.Height != 500 || img.Format != "png" {
+		t.Fatalf("unexpected image %dx%d %s", img.Width, img.Height, img.Format)
+	}
+	if b := img.thumb.Bounds(); b.Dx() != maxThumbnail || b.Dy() != maxThumbnail/2 {
+		t.Fatalf("expected a %dx%d thumbnail, got %v", maxThumbnail, maxThumbnail/2, b)
+	}
+	if cols, rows := img.Fit(40, 30); cols != 40 || rows != 10 {
+		t.Fatalf("expected 40x10 cells, got %dx%d", cols, rows)
+	}
+	if cols, rows := img.Fit(40, 5); cols != 20 || rows != 5 {
+		t.Fatalf("expected the height to bound the fit, got %dx%d", cols, rows)
+	}
+	if _, err := Decode([]byte("not an image")); err == nil {
+		t.Fatalf("expected Decode to reject garbage")
+	}
+}
+
+func TestDecodeRefusesHugePictures(t *testing.T) {
+	_, err := Decode(hugePNG(100000, 100000))
+	if err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Fatalf("expected a 100000×100000 picture to be refused, got %v", err)
+	}
+}
+
+func TestThumbnailReadsAnyImage(t *testing.T) {
+	palette := color.Palette{color.RGBA{0xff, 0, 0, 0xff}, color.RGBA{0, 0, 0xff, 0xff}}
+	src := image.NewPaletted(image.Rect(10, 10, 14, 12), palette)
+	for x := 10; x < 14; x++ {
+		src.SetColorIndex(x, 11, 1)
+	}
+	thumb := thumbnail(src, 2)
+	if b := thumb.Bounds(); b.Dx() != 2 || b.Dy() != 1 {
+		t.Fatalf("expected a 2x1 thumbnail, got %v", b)
+	}
+	if c := thumb.RGBAAt(0, 0); c != (color.RGBA{0x7f, 0, 0x7f, 0xff}) {
+		t.Fatalf("expected red and blue averaged, got %v", c)
+	}
+}
+
+func TestHalfBlocks(t *testing.T) {
+	lipgloss.SetColorProfile(termenv.TrueColor)
+	defer lipgloss.SetColorProfile(termenv.Ascii)
+	img, err := Decode(testPNG(t, 4, 4))
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	picture := img.Render(HalfBlocks, 4, 2)
+	if picture.Setup != "" || len(picture.Rows) != 2 {
+		t.Fatalf("unexpected picture %+v", picture)
+	}
+	for i, row := range picture.Rows {
+		if ansi.StringWidth(row) != 4 || strings.Count(row, "▀") != 4 {
+			t.Fatalf("row %d is not 4 half blocks: %q", i, row)
+		}
+	}
+	if !strings.Contains(picture.Rows[0], "38;2;255;0;0") || !strings.Contains(picture.Rows[1], "48;2;0;0;255") {
+		t.Fatalf("expected red on top and blue below, got %q", picture.Rows)
+	}
+}
+
+func TestKitty(t *testing.T) {
+	img, err := Decode(testPNG(t, 300, 300))
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	picture := img.Render(Kitty, 8, 4)
+	if !strings.HasPrefix(picture.Setup, "\x1b_Ga=T,U=1,f=100,q=2,") || !strings.HasSuffix(picture.Setup, "\x1b\\") {
+		t.Fatalf("unexpected transmission %.60q", picture.Setup)
+	}
+	if len(picture.Rows) != 4 {
+		t.Fatalf("expected 4 rows, got %d", len(picture.Rows))
+	}
+	for i, row := range picture.Rows {
+		if ansi.StringWidth(row) != 8 || strings.Count(row, string(kittyPlaceholder)) != 8 {
+			t.Fatalf("row %d is not 8 placeholders: %q", i, row)
+		}
+		if !strings.ContainsRune(row, kittyDiacritics[i]) {
+			t.Fatalf("row %d does not name its row", i)
+		}
+	}
+}
+
+func TestSixel(t *testing.T) {
+	img, err := Decode(testPNG(t, 8, 8))
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	picture := img.Render(Sixel, 2, 1)
+	row := picture.Rows[0]
+	if !strings.HasPrefix(row, "\x1b7\x1bPq\"1;1;20;20") || !strings.Contains(row, "\x1b\\\x1b8") {
+		t.Fatalf("unexpected sixel row %.60q", row)
+	}
+	if ansi.StringWidth(row) != 2 {
+		t.Fatalf("expected the sixel row to take 2 cells, got %d", ansi.StringWidth(row))
+	}
+	// Red (#180) fills the top half of the first band, blue (#5) the rest.
+	if !strings.Contains(row, "#180!20N") || !strings.Contains(row, "#5!20o") {
+		t.Fatalf("unexpected sixel data %q", row)
+	}
+}
@@ -0,0 +1,77 @@
+package graphics
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"strings"
+)
+
+// kittyChunk is the most base64 payload one kitty escape may carry.
+const kittyChunk = 4096
+
+// kittyPlaceholder stands in for one cell of an image placed with Unicode
+// placeholders; its foreground color names the image and combining
+// diacritics its row and column.
+const kittyPlaceholder = '\U0010EEEE'
+
+// kittyDiacritics are the first row and column numbers from kitty's
+// rowcolumn-diacritics table.
+var kittyDiacritics = []rune{
+	0x0305, 0x030D, 0x030E, 0x0310, 0x0312, 0x033D, 0x033E, 0x033F,
+	0x0346, 0x034A, 0x034B, 0x034C, 0x0350, 0x0351, 0x0352, 0x0357,
+	0x035B, 0x0363, 0x0364, 0x0365, 0x0366, 0x0367, 0x0368, 0x0369,
+	0x036A, 0x036B, 0x036C, 0x036D, 0x036E, 0x036F, 0x0483, 0x0484,
+}
+
+// kitty transmits the image once as a PNG with a virtual placement, and
+// fills the cells with placeholders the terminal replaces by the image.
+// The placeholders are ordinary text, so the picture scrolls and clips
+// with the rest of the pane.
+func (img *Image) kitty(cols, rows int) Picture {
+	if rows > len(kittyDiacritics) {
+		rows = len(kittyDiacritics)
+	}
+	var data bytes.Buffer
+	if err := png.Encode(&data, img.thumb); err != nil {
+		return Picture{Rows: blankRows(cols, rows)}
+	}
+	payload := base64.StdEncoding.EncodeToString(data.Bytes())
+	var setup strings.Builder
+	for first := true; first || payload != ""; first = false {
+		chunk := payload
+		if len(chunk) > kittyChunk {
+			chunk = chunk[:kittyChunk]
+		}
+		payload = payload[len(chunk):]
+		more := 0
+		if payload != "" {
+			more = 1
+		}
+		if first {
+			// q=2 keeps the terminal from answering on stdin.
+			fmt.Fprintf(&setup, "\x1b_Ga=T,U=1,f=100,q=2,i=%d,c=%d,r=%d,m=%d;%s\x1b\\", img.id, cols, rows, more, chunk)
+		} else {
+			fmt.Fprintf(&setup, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+
+	color := fmt.Sprintf("\x1b[38;2;%d;%d;%dm", img.id>>16&0xff, img.id>>8&0xff, img.id&0xff)
+	out := make([]string, rows)
+	for row := range out {
+		var b strings.Builder
+		b.WriteString(color)
+		// The first cell names its row and column; the rest of the row
+		// follows on from it.
+		b.WriteRune(kittyPlaceholder)
+		b.WriteRune(kittyDiacritics[row])
+		b.WriteRune(kittyDiacritics[0])
+		for col := 1; col < cols; col++ {
+			b.WriteRune(kittyPlaceholder)
+		}
+		b.WriteString("\x1b[39m")
+		out[row] = b.String()
+	}
+	return Picture{Setup: setup.String(), Rows: out}
+}
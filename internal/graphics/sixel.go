@@ -0,0 +1,92 @@
+package graphics
+
+import (
+	"fmt"
+	"image"
+	"strings"
+)
+
+// sixelLevels is the number of levels per channel of the fixed sixel
+// palette, a 6×6×6 color cube.
+const sixelLevels = 6
+
+// sixel draws the image into the first row, saving and restoring the
+// cursor around it so the rows below are left for the terminal to paint
+// over; the rest of the rows are blank. A renderer that repaints one of
+// those rows on its own writes its blanks over the picture, which is why
+// Detect never picks sixel.
+func (img *Image) sixel(cols, rows int) Picture {
+	pixels := scale(img.thumb, cols*cellWidth, rows*cellHeight)
+	out := blankRows(cols, rows)
+	out[0] = "\x1b7" + encodeSixel(pixels) + "\x1b8" + out[0]
+	return Picture{Rows: out}
+}
+
+// encodeSixel writes img as a sixel image with a 216-color palette.
+func encodeSixel(img *image.RGBA) string {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	var b strings.Builder
+	fmt.Fprintf(&b, "\x1bPq\"1;1;%d;%d", width, height)
+	for i := 0; i < sixelLevels*sixelLevels*sixelLevels; i++ {
+		r, g, bl := i/(sixelLevels*sixelLevels), i/sixelLevels%sixelLevels, i%sixelLevels
+		fmt.Fprintf(&b, "#%d;2;%d;%d;%d", i, r*100/(sixelLevels-1), g*100/(sixelLevels-1), bl*100/(sixelLevels-1))
+	}
+	level := func(v uint8) int { return (int(v)*(sixelLevels-1) + 127) / 255 }
+	index := make([]int, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := flatten(img.RGBAAt(x, y))
+			index[y*width+x] = level(c.R)*sixelLevels*sixelLevels + level(c.G)*sixelLevels + level(c.B)
+		}
+	}
+	bits := make([]byte, width)
+	for band := 0; band < height; band += 6 {
+		used := map[int]bool{}
+		var order []int
+		for y := band; y < band+6 && y < height; y++ {
+			for x := 0; x < width; x++ {
+				if color := index[y*width+x]; !used[color] {
+					used[color] = true
+					order = append(order, color)
+				}
+			}
+		}
+		for i, color := range order {
+			for x := range bits {
+				bits[x] = 0
+				for dy := 0; dy < 6 && band+dy < height; dy++ {
+					if index[(band+dy)*width+x] == color {
+						bits[x] |= 1 << dy
+					}
+				}
+			}
+			if i > 0 {
+				b.WriteByte('$')
+			}
+			fmt.Fprintf(&b, "#%d", color)
+			writeSixelRuns(&b, bits)
+		}
+		b.WriteByte('-')
+	}
+	b.WriteString("\x1b\\")
+	return b.String()
+}
+
+// writeSixelRuns writes one color's sixels for a band, run-length encoded.
+func writeSixelRuns(b *strings.Builder, bits []byte) {
+	for x := 0; x < len(bits); {
+		run := 1
+		for x+run < len(bits) && bits[x+run] == bits[x] {
+			run++
+		}
+		char := byte('?' + bits[x])
+		if run > 3 {
+			fmt.Fprintf(b, "!%d%c", run, char)
+		} else {
+			for i := 0; i < run; i++ {
+				b.WriteByte(char)
+			}
+		}
+		x += run
+	}
+}
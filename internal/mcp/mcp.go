@@ -0,0 +1,331 @@
+// Package mcp serves wing's view of a repository to coding agents over the
+// Model Context Protocol, speaking newline-delimited JSON-RPC on stdio.
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"wing/internal/git"
+	"wing/internal/review"
+)
+
+// protocolVersion is the MCP revision the server implements.
+const protocolVersion = "2024-11-05"
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+type content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type toolResult struct {
+	Content []content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Server answers MCP requests about one repository.
+type Server struct {
+	RepoPath string
+	Version  string
+}
+
+// Serve handles requests from in until it is closed, writing one response
+// per line to out.
+func (s Server) Serve(in io.Reader, out io.Writer) error {
+	reader := bufio.NewReader(in)
+	encoder := json.NewEncoder(out)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if resp, ok := s.handle(line); ok {
+				if encodeErr := encoder.Encode(resp); encodeErr != nil {
+					return encodeErr
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handle answers one message; notifications get no response.
+func (s Server) handle(line []byte) (response, bool) {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		if len(bytes.TrimSpace(line)) == 0 {
+			return response{}, false
+		}
+		return errorResponse(json.RawMessage("null"), codeParseError, err.Error()), true
+	}
+	if len(req.ID) == 0 {
+		return response{}, false
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, codeInvalidRequest, "invalid request"), true
+	}
+	switch req.Method {
+	case "initialize":
+		return resultResponse(req.ID, map[string]any{
+			"protocolVersion": protocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "wing", "version": s.Version},
+		}), true
+	case "ping":
+		return resultResponse(req.ID, map[string]any{}), true
+	case "tools/list":
+		return resultResponse(req.ID, map[string]any{"tools": tools}), true
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return errorResponse(req.ID, codeInvalidParams, err.Error()), true
+		}
+		result, err := s.callTool(params.Name, params.Arguments)
+		if err != nil {
+			return resultResponse(req.ID, toolResult{Content: []content{{Type: "text", Text: err.Error()}}, IsError: true}), true
+		}
+		return resultResponse(req.ID, toolResult{Content: []content{{Type: "text", Text: result}}}), true
+	}
+	return errorResponse(req.ID, codeMethodNotFound, "method not found: "+req.Method), true
+}
+
+func resultResponse(id json.RawMessage, result any) response {
+	return response{JSONRPC: "2.0", ID: id, Result: result}
+}
+
+func errorResponse(id json.RawMessage, code int, message string) response {
+	return response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}}
+}
+
+func objectSchema(properties map[string]any, required ...string) map[string]any {
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+var baseProperty = map[string]any{
+	"type":        "string",
+	"description": "Compare base such as HEAD, main or A..B. Empty compares the working tree against the index, like git diff, so staged changes are left out; pass HEAD to include them.",
+}
+
+var tools = []tool{
+	{
+		Name:        "list_changed_files",
+		Description: "List the files changed in the repository with their git status, whether the reviewer marked them viewed and how many review comments they have.",
+		InputSchema: objectSchema(map[string]any{"base": baseProperty}),
+	},
+	{
+		Name:        "get_diff",
+		Description: "Get the unified diff of one changed file.",
+		InputSchema: objectSchema(map[string]any{
+			"path": map[string]any{"type": "string", "description": "Path relative to the repository root."},
+			"base": baseProperty,
+		}, "path"),
+	},
+	{
+		Name:        "get_review_comments",
+		Description: "Get the reviewer's comments on diff lines, optionally for one path only. Set format to markdown for the same feedback document wing exports.",
+		InputSchema: objectSchema(map[string]any{
+			"path":   map[string]any{"type": "string", "description": "Only return comments on this path."},
+			"format": map[string]any{"type": "string", "enum": []string{"json", "markdown"}},
+		}),
+	},
+	{
+		Name:        "get_viewed_state",
+		Description: "Report which changed files the reviewer has marked as viewed. A mark lapses when the file changes again.",
+		InputSchema: objectSchema(map[string]any{}),
+	},
+}
+
+type toolArgs struct {
+	Path   string `json:"path"`
+	Base   string `json:"base"`
+	Format string `json:"format"`
+}
+
+type fileInfo struct {
+	Path     string `json:"path"`
+	Status   string `json:"status"`
+	OrigPath string `json:"orig_path,omitempty"`
+	Viewed   bool   `json:"viewed"`
+	Comments int    `json:"comments"`
+}
+
+type viewedInfo struct {
+	Path   string `json:"path"`
+	Viewed bool   `json:"viewed"`
+}
+
+func (s Server) callTool(name string, raw json.RawMessage) (string, error) {
+	var args toolArgs
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+	switch name {
+	case "list_changed_files":
+		statuses, err := s.status(args.Base)
+		if err != nil {
+			return "", err
+		}
+		store, viewed, err := s.viewed(statuses)
+		if err != nil {
+			return "", err
+		}
+		files := make([]fileInfo, 0, len(statuses))
+		for _, entry := range statuses {
+			files = append(files, fileInfo{
+				Path:     entry.Path,
+				Status:   entry.Status,
+				OrigPath: entry.OrigPath,
+				Viewed:   viewed[entry.Path],
+				Comments: len(store.CommentsFor(entry.Path)),
+			})
+		}
+		return encode(files)
+	case "get_diff":
+		if args.Path == "" {
+			return "", fmt.Errorf("path is required")
+		}
+		statuses, err := s.status(args.Base)
+		if err != nil {
+			return "", err
+		}
+		entry := git.StatusEntry{Path: args.Path}
+		for _, candidate := range statuses {
+			if candidate.Path == args.Path {
+				entry = candidate
+				break
+			}
+		}
+		diff, err := git.Diff(s.RepoPath, entry, git.DiffOptions{Base: args.Base, Context: git.DefaultContext})
+		if err != nil {
+			return "", err
+		}
+		if diff == "" {
+			return "No changes in " + args.Path + ".", nil
+		}
+		return diff, nil
+	case "get_review_comments":
+		store, err := review.Load(s.RepoPath)
+		if err != nil {
+			return "", err
+		}
+		comments := store.Comments
+		if args.Path != "" {
+			comments = store.CommentsFor(args.Path)
+		}
+		if args.Format == "markdown" {
+			return review.ExportMarkdown(comments), nil
+		}
+		if comments == nil {
+			comments = []review.Comment{}
+		}
+		return encode(comments)
+	case "get_viewed_state":
+		statuses, err := s.status("")
+		if err != nil {
+			return "", err
+		}
+		_, viewed, err := s.viewed(statuses)
+		if err != nil {
+			return "", err
+		}
+		state := make([]viewedInfo, 0, len(viewed))
+		for path, isViewed := range viewed {
+			state = append(state, viewedInfo{Path: path, Viewed: isViewed})
+		}
+		sort.Slice(state, func(i, j int) bool { return state[i].Path < state[j].Path })
+		return encode(state)
+	}
+	return "", fmt.Errorf("unknown tool: %s", name)
+}
+
+func (s Server) status(base string) ([]git.StatusEntry, error) {
+	if base != "" {
+		if err := git.VerifyBase(s.RepoPath, base); err != nil {
+			return nil, err
+		}
+		return git.StatusAgainst(s.RepoPath, base)
+	}
+	statuses, _, err := git.StatusWithBranch(s.RepoPath)
+	return statuses, err
+}
+
+// viewed loads the review state and reports, for each changed file, whether
+// its viewed mark matches the file's current content.
+func (s Server) viewed(statuses []git.StatusEntry) (review.Store, map[string]bool, error) {
+	store, err := review.Load(s.RepoPath)
+	if err != nil {
+		return store, nil, err
+	}
+	paths := make([]string, 0, len(statuses))
+	for _, entry := range statuses {
+		if entry.Status != "" && !entry.Ignored {
+			paths = append(paths, entry.Path)
+		}
+	}
+	hashes, err := git.HashFiles(s.RepoPath, paths)
+	if err != nil {
+		return store, nil, err
+	}
+	viewed := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		viewed[path] = store.IsViewed(path, hashes[path])
+	}
+	return store, viewed, nil
+}
+
+func encode(value any) (string, error) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,146 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"wing/internal/git"
+	"wing/internal/review"
+)
+
+func runGit(t *testing.T, repo string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=wing", "-c", "user.email=wing@example.com"}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v (%s)", args, err, out)
+	}
+}
+
+func testRepo(t *testing.T) string {
+	t.Helper()
+	repo := t.TempDir()
+	runGit(t, repo, "init")
+	if err := os.WriteFile(filepath.Join(repo, "a.txt"), []byte("one\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, repo, "add", ".")
+	runGit(t, repo, "commit", "-m", "init")
+	if err := os.WriteFile(filepath.Join(repo, "a.txt"), []byte("one\ntwo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "new.txt"), []byte("new\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+// call sends requests through Serve and returns the decoded responses.
+func call(t *testing.T, server Server, requests ...string) []map[string]any {
+	t.Helper()
+	var out bytes.Buffer
+	if err := server.Serve(strings.NewReader(strings.Join(requests, "\n")+"\n"), &out); err != nil {
+		t.Fatalf("Serve error: %v", err)
+	}
+	responses := []map[string]any{}
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var resp map[string]any
+		if err := decoder.Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+func toolText(t *testing.T, resp map[string]any) (string, bool) {
+	t.Helper()
+	result, ok := resp["result"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected a result, got %v", resp)
+	}
+	items := result["content"].([]any)
+	isError, _ := result["isError"].(bool)
+	return items[0].(map[string]any)["text"].(string), isError
+}
+
+func TestHandshakeAndToolList(t *testing.T) {
+	responses := call(t, Server{RepoPath: t.TempDir(), Version: "1.2.3"},
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"nope"}`,
+		`not json`,
+	)
+	if len(responses) != 4 {
+		t.Fatalf("expected 4 responses, got %v", responses)
+	}
+	init := responses[0]["result"].(map[string]any)
+	if init["protocolVersion"] != protocolVersion || init["serverInfo"].(map[string]any)["version"] != "1.2.3" {
+		t.Fatalf("unexpected initialize result %v", init)
+	}
+	names := []string{}
+	for _, item := range responses[1]["result"].(map[string]any)["tools"].([]any) {
+		names = append(names, item.(map[string]any)["name"].(string))
+	}
+	if strings.Join(names, ",") != "list_changed_files,get_diff,get_review_comments,get_viewed_state" {
+		t.Fatalf("unexpected tools %v", names)
+	}
+	if code := responses[2]["error"].(map[string]any)["code"].(float64); code != codeMethodNotFound {
+		t.Fatalf("expected method not found, got %v", responses[2])
+	}
+	if code := responses[3]["error"].(map[string]any)["code"].(float64); code != codeParseError {
+		t.Fatalf("expected parse error, got %v", responses[3])
+	}
+}
+
+func TestToolsReflectReviewState(t *testing.T) {
+	repo := testRepo(t)
+	store, err := review.Load(repo)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	hashes, err := git.HashFiles(repo, []string{"a.txt"})
+	if err != nil {
+		t.Fatalf("HashFiles error: %v", err)
+	}
+	store.SetViewed("a.txt", hashes["a.txt"], true)
+	store.AddComment(review.Comment{Path: "a.txt", Line: 2, Snippet: []string{"+two"}, Body: "why two?"})
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+
+	responses := call(t, Server{RepoPath: repo},
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"list_changed_files"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_diff","arguments":{"path":"new.txt"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"get_review_comments","arguments":{"format":"markdown"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"get_viewed_state"}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"get_diff","arguments":{}}}`,
+	)
+
+	text, _ := toolText(t, responses[0])
+	var files []fileInfo
+	if err := json.Unmarshal([]byte(text), &files); err != nil {
+		t.Fatalf("decode files: %v", err)
+	}
+	if len(files) != 2 || files[0].Path != "a.txt" || !files[0].Viewed || files[0].Comments != 1 || files[1].Viewed {
+		t.Fatalf("unexpected files %+v", files)
+	}
+	if text, _ := toolText(t, responses[1]); !strings.Contains(text, "+new") {
+		t.Fatalf("expected untracked diff, got %q", text)
+	}
+	if text, _ := toolText(t, responses[2]); !strings.Contains(text, "why two?") {
+		t.Fatalf("expected comment in export, got %q", text)
+	}
+	if text, _ := toolText(t, responses[3]); !strings.Contains(text, `"path": "a.txt",`+"\n"+`    "viewed": true`) {
+		t.Fatalf("unexpected viewed state %q", text)
+	}
+	if _, isError := toolText(t, responses[4]); !isError {
+		t.Fatalf("expected a tool error without path")
+	}
+}
@@ -0,0 +1,239 @@
+// Package review keeps the reviewer's notes on a change set, persisted per
+// repository under the git directory so they survive restarts.
+package review
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"wing/internal/git"
+)
+
+// Comment is a note attached to a diff line, or to a whole hunk when Hunk is
+// set. Line is the new-side line number and OldLine the old-side one; a
+// removed line only has OldLine.
+type Comment struct {
+	ID      int       `json:"id"`
+	Path    string    `json:"path"`
+	Line    int       `json:"line,omitempty"`
+	OldLine int       `json:"old_line,omitempty"`
+	Hunk    bool      `json:"hunk,omitempty"`
+	Snippet []string  `json:"snippet"`
+	Body    string    `json:"body"`
+	Created time.Time `json:"created"`
+}
+
+// Location describes where the comment sits, e.g. "line 12" or
+// "lines 10-18".
+func (c Comment) Location() string {
+	line := c.Line
+	if line == 0 {
+		line = c.OldLine
+	}
+	if c.Hunk {
+		end := line + countSide(c.Snippet, c.Line == 0) - 1
+		if end > line {
+			return fmt.Sprintf("lines %d-%d", line, end)
+		}
+	}
+	if c.Line == 0 {
+		return fmt.Sprintf("old line %d", line)
+	}
+	return fmt.Sprintf("line %d", line)
+}
+
+// countSide counts the snippet lines present on the new side, or the old
+// side when old is set.
+func countSide(snippet []string, old bool) int {
+	count := 0
+	for _, line := range snippet {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			if !old {
+				count++
+			}
+		case strings.HasPrefix(line, "-"):
+			if old {
+				count++
+			}
+		case strings.HasPrefix(line, " "):
+			count++
+		}
+	}
+	return count
+}
+
+// Store is the review state of one repository.
+type Store struct {
+	path     string
+	Comments []Comment `json:"comments"`
+	NextID   int       `json:"next_id"`
+	// Viewed maps a path to the content hash it had when it was marked as
+	// viewed, so the mark lapses once the file changes again.
+	Viewed map[string]string `json:"viewed,omitempty"`
+}
+
+// StorePath returns where the review state of repoPath is kept.
+func StorePath(repoPath string) (string, error) {
+	gitDir, err := git.GitDir(repoPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(gitDir, "wing", "review.json"), nil
+}
+
+// Load reads the review state of repoPath. A repo without saved state
+// returns an empty store. State that cannot be read returns an empty store
+// that refuses to save, so the file is not replaced.
+func Load(repoPath string) (Store, error) {
+	path, err := StorePath(repoPath)
+	if err != nil {
+		return Store{}, err
+	}
+	store := Store{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return Store{}, err
+	}
+	if err := json.Unmarshal(data, &store); err != nil {
+		return Store{}, fmt.Errorf("read %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// Save writes the store back to the file it was loaded from.
+func (s Store) Save() error {
+	if s.path == "" {
+		return errors.New("review store has no path")
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// AddComment stores comment with a fresh ID and returns it.
+func (s *Store) AddComment(comment Comment) Comment {
+	s.NextID++
+	comment.ID = s.NextID
+	if comment.Created.IsZero() {
+		comment.Created = time.Now()
+	}
+	comments := make([]Comment, 0, len(s.Comments)+1)
+	comments = append(comments, s.Comments...)
+	s.Comments = append(comments, comment)
+	return comment
+}
+
+// DeleteComment removes the comment with id and reports whether it existed.
+func (s *Store) DeleteComment(id int) bool {
+	comments := make([]Comment, 0, len(s.Comments))
+	for _, comment := range s.Comments {
+		if comment.ID != id {
+			comments = append(comments, comment)
+		}
+	}
+	found := len(comments) != len(s.Comments)
+	s.Comments = comments
+	return found
+}
+
+// IsViewed reports whether path was marked viewed with content hash.
+func (s Store) IsViewed(path, hash string) bool {
+	if hash == "" {
+		return false
+	}
+	viewed, ok := s.Viewed[path]
+	return ok && viewed == hash
+}
+
+// SetViewed marks path as viewed at content hash, or clears the mark when
+// viewed is false.
+func (s *Store) SetViewed(path, hash string, viewed bool) {
+	next := make(map[string]string, len(s.Viewed)+1)
+	for key, value := range s.Viewed {
+		next[key] = value
+	}
+	if viewed && hash != "" {
+		next[path] = hash
+	} else {
+		delete(next, path)
+	}
+	s.Viewed = next
+}
+
+// CommentsFor returns the comments on path in line order.
+func (s Store) CommentsFor(path string) []Comment {
+	out := make([]Comment, 0)
+	for _, comment := range s.Comments {
+		if comment.Path == path {
+			out = append(out, comment)
+		}
+	}
+	sortComments(out)
+	return out
+}
+
+func sortComments(comments []Comment) {
+	sort.SliceStable(comments, func(i, j int) bool {
+		a, b := comments[i], comments[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		lineA, lineB := a.Line, b.Line
+		if lineA == 0 {
+			lineA = a.OldLine
+		}
+		if lineB == 0 {
+			lineB = b.OldLine
+		}
+		return lineA < lineB
+	})
+}
+
+// ExportMarkdown renders comments as a feedback document that can be
+// pasted into a coding agent.
+func ExportMarkdown(comments []Comment) string {
+	if len(comments) == 0 {
+		return "# Review feedback\n\nNo comments.\n"
+	}
+	sorted := append([]Comment(nil), comments...)
+	sortComments(sorted)
+
+	var b strings.Builder
+	b.WriteString("# Review feedback\n\n")
+	b.WriteString("Please address the following review comments.\n")
+	currentPath := ""
+	for _, comment := range sorted {
+		if comment.Path != currentPath {
+			currentPath = comment.Path
+			fmt.Fprintf(&b, "\n## %s\n", currentPath)
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n", comment.Location())
+		if len(comment.Snippet) > 0 {
+			b.WriteString("```diff\n")
+			b.WriteString(strings.Join(comment.Snippet, "\n"))
+			b.WriteString("\n```\n\n")
+		}
+		b.WriteString(strings.TrimSpace(comment.Body))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,111 @@
+package review
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "-C", repo, "init").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v (%s)", err, out)
+	}
+
+	store, err := Load(repo)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if len(store.Comments) != 0 {
+		t.Fatalf("expected empty store, got %+v", store.Comments)
+	}
+	first := store.AddComment(Comment{Path: "b.go", Line: 3, Snippet: []string{"+x := 1"}, Body: "rename x"})
+	second := store.AddComment(Comment{Path: "a.go", OldLine: 7, Snippet: []string{"-old()"}, Body: "why remove?"})
+	if first.ID == second.ID {
+		t.Fatalf("expected distinct ids")
+	}
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+
+	loaded, err := Load(repo)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if len(loaded.Comments) != 2 || loaded.NextID != 2 {
+		t.Fatalf("expected saved comments, got %+v", loaded)
+	}
+	if !loaded.DeleteComment(first.ID) || loaded.DeleteComment(first.ID) {
+		t.Fatalf("expected delete to report existence")
+	}
+	if got := loaded.CommentsFor("b.go"); len(got) != 0 {
+		t.Fatalf("expected b.go comment deleted, got %+v", got)
+	}
+}
+
+func TestExportMarkdown(t *testing.T) {
+	doc := ExportMarkdown([]Comment{
+		{Path: "b.go", Line: 3, Snippet: []string{"+x := 1"}, Body: "rename x"},
+		{Path: "a.go", Line: 10, Hunk: true, Snippet: []string{" a", "-b", "+c", " d"}, Body: "simplify"},
+		{Path: "a.go", OldLine: 2, Snippet: []string{"-gone"}, Body: "keep this"},
+	})
+	for _, want := range []string{
+		"## a.go\n\n### old line 2\n\n```diff\n-gone\n```\n\nkeep this\n",
+		"### lines 10-12\n",
+		"## b.go\n\n### line 3\n\n```diff\n+x := 1\n```\n\nrename x\n",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Fatalf("expected %q in export:\n%s", want, doc)
+		}
+	}
+	if strings.Index(doc, "## a.go") > strings.Index(doc, "## b.go") {
+		t.Fatalf("expected files in path order")
+	}
+}
+
+func TestViewedFollowsContentHash(t *testing.T) {
+	var store Store
+	store.SetViewed("a.go", "h1", true)
+	if !store.IsViewed("a.go", "h1") {
+		t.Fatalf("expected a.go viewed at h1")
+	}
+	if store.IsViewed("a.go", "h2") {
+		t.Fatalf("expected viewed mark to lapse when the content changes")
+	}
+	if store.IsViewed("b.go", "") {
+		t.Fatalf("expected unknown hash to never count as viewed")
+	}
+	before := store.Viewed
+	store.SetViewed("a.go", "h1", false)
+	if store.IsViewed("a.go", "h1") || len(before) != 1 {
+		t.Fatalf("expected unmark without touching earlier copies")
+	}
+}
+
+func TestCorruptStoreIsNotOverwritten(t *testing.T) {
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "-C", repo, "init").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v (%s)", err, out)
+	}
+	path := filepath.Join(repo, ".git", "wing", "review.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`{"comments": [`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := Load(repo)
+	if err == nil {
+		t.Fatalf("expected an error for a corrupt store")
+	}
+	store.AddComment(Comment{Path: "a.go", Line: 1, Body: "lost?"})
+	if err := store.Save(); err == nil {
+		t.Fatalf("expected a store that failed to load to refuse saving")
+	}
+	if data, _ := os.ReadFile(path); string(data) != `{"comments": [` {
+		t.Fatalf("expected the corrupt file to be left alone, got %q", data)
+	}
+}
@@ -0,0 +1,69 @@
+// Package settings keeps the view preferences wing remembers between runs,
+// in a per-user config file.
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Settings are the remembered preferences. The zero value is the default
+// view.
+type Settings struct {
+	path string
+	// Sort names the Files ordering: "" for by path, or "status", "size"
+	// or "modified".
+	Sort string `json:"sort,omitempty"`
+	// Flat lists files without folders.
+	Flat bool `json:"flat,omitempty"`
+}
+
+// DefaultPath returns where the settings of the current user are kept.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "wing", "config.json"), nil
+}
+
+// Load reads the settings at path. A missing file returns the defaults,
+// which save back to path. A file that cannot be read returns defaults that
+// are never saved, so the user's file is left for them to fix.
+func Load(path string) (Settings, error) {
+	settings := Settings{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return settings, nil
+	}
+	if err != nil {
+		return Settings{}, err
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return Settings{}, fmt.Errorf("read %s: %w", path, err)
+	}
+	return settings, nil
+}
+
+// Save writes the settings back to the file they were loaded from. Settings
+// that were never loaded have nowhere to go and are not saved.
+func (s Settings) Save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,62 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadMissingReturnsDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wing", "config.json")
+	settings, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if settings.Sort != "" || settings.Flat {
+		t.Fatalf("expected defaults, got %+v", settings)
+	}
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wing", "config.json")
+	settings, _ := Load(path)
+	settings.Sort = "size"
+	settings.Flat = true
+	if err := settings.Save(); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if loaded.Sort != "size" || !loaded.Flat {
+		t.Fatalf("expected the saved settings, got %+v", loaded)
+	}
+}
+
+func TestLoadRejectsCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	settings, err := Load(path)
+	if err == nil {
+		t.Fatalf("expected an error for a corrupt file")
+	}
+	if settings.Sort != "" {
+		t.Fatalf("expected defaults alongside the error, got %+v", settings)
+	}
+	settings.Sort = "size"
+	if err := settings.Save(); err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "{" {
+		t.Fatalf("expected the corrupt file to be left alone, got %q", data)
+	}
+}
+
+func TestUnloadedSettingsAreNotSaved(t *testing.T) {
+	if err := (Settings{Sort: "size"}).Save(); err != nil {
+		t.Fatalf("expected saving without a path to do nothing, got %v", err)
+	}
+}
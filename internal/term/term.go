@@ -0,0 +1,228 @@
+// Package term runs a command on a pseudo-terminal and keeps an emulated
+// screen of its output, so an agent or shell can live in a wing pane.
+package term
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/creack/pty"
+	"github.com/hinshun/vt10x"
+)
+
+// Glyph attribute bits as set by vt10x.
+const (
+	attrReverse   = 1 << 0
+	attrUnderline = 1 << 1
+	attrBold      = 1 << 2
+	attrItalic    = 1 << 4
+)
+
+// Session is a running command attached to a pseudo-terminal.
+type Session struct {
+	cmd     *exec.Cmd
+	pty     *os.File
+	vt      vt10x.Terminal
+	updates chan struct{}
+	done    chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+// Start runs command through sh in dir on a cols×rows pseudo-terminal.
+func Start(dir, command string, cols, rows int) (*Session, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, fmt.Errorf("no command to run")
+	}
+	cols, rows = clampSize(cols, rows)
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+	file, err := pty.StartWithSize(cmd, &pty.Winsize{Cols: uint16(cols), Rows: uint16(rows)})
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{
+		cmd:     cmd,
+		pty:     file,
+		vt:      vt10x.New(vt10x.WithWriter(file), vt10x.WithSize(cols, rows)),
+		updates: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go s.read()
+	return s, nil
+}
+
+// read feeds the command's output to the emulator until it exits.
+func (s *Session) read() {
+	buf := make([]byte, 32*1024)
+	var pending []byte
+	for {
+		n, err := s.pty.Read(buf)
+		if n > 0 {
+			pending = append(pending, buf[:n]...)
+			// The emulator leaves a trailing partial UTF-8 sequence unread.
+			written, _ := s.vt.Write(pending)
+			pending = append(pending[:0], pending[written:]...)
+			s.notify()
+		}
+		if err != nil {
+			break
+		}
+	}
+	waitErr := s.cmd.Wait()
+	s.mu.Lock()
+	s.err = waitErr
+	s.mu.Unlock()
+	s.pty.Close()
+	close(s.done)
+}
+
+func (s *Session) notify() {
+	select {
+	case s.updates <- struct{}{}:
+	default:
+	}
+}
+
+// Updates receives a value whenever the screen changed since the last
+// receive; bursts of output are coalesced.
+func (s *Session) Updates() <-chan struct{} {
+	return s.updates
+}
+
+// Done is closed once the command has exited.
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Exited reports whether the command has exited.
+func (s *Session) Exited() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Err returns the command's exit error once it has exited.
+func (s *Session) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Write sends input to the command as if typed.
+func (s *Session) Write(p []byte) (int, error) {
+	if s.Exited() {
+		return 0, os.ErrClosed
+	}
+	return s.pty.Write(p)
+}
+
+// Resize changes the terminal size seen by the command.
+func (s *Session) Resize(cols, rows int) error {
+	cols, rows = clampSize(cols, rows)
+	s.vt.Lock()
+	currentCols, currentRows := s.vt.Size()
+	s.vt.Unlock()
+	if currentCols == cols && currentRows == rows {
+		return nil
+	}
+	s.vt.Resize(cols, rows)
+	if s.Exited() {
+		return nil
+	}
+	return pty.Setsize(s.pty, &pty.Winsize{Cols: uint16(cols), Rows: uint16(rows)})
+}
+
+// Close kills the command if it is still running.
+func (s *Session) Close() error {
+	if s.Exited() || s.cmd.Process == nil {
+		return nil
+	}
+	return s.cmd.Process.Kill()
+}
+
+// Lines renders the screen as one string per row, with SGR escapes for the
+// colors and attributes, and the cursor in reverse video when showCursor is
+// set.
+func (s *Session) Lines(showCursor bool) []string {
+	s.vt.Lock()
+	defer s.vt.Unlock()
+	cols, rows := s.vt.Size()
+	cursor := s.vt.Cursor()
+	showCursor = showCursor && s.vt.CursorVisible() && !s.Exited()
+	lines := make([]string, rows)
+	for y := 0; y < rows; y++ {
+		var b strings.Builder
+		current := ""
+		for x := 0; x < cols; x++ {
+			glyph := s.vt.Cell(x, y)
+			if showCursor && x == cursor.X && y == cursor.Y {
+				glyph.Mode ^= attrReverse
+			}
+			if sgr := glyphSGR(glyph); sgr != current {
+				if sgr == "" {
+					b.WriteString("\x1b[0m")
+				} else {
+					b.WriteString(sgr)
+				}
+				current = sgr
+			}
+			char := glyph.Char
+			if char == 0 {
+				char = ' '
+			}
+			b.WriteRune(char)
+		}
+		if current != "" {
+			b.WriteString("\x1b[0m")
+		}
+		lines[y] = b.String()
+	}
+	return lines
+}
+
+// glyphSGR returns the escape sequence selecting the glyph's style, or ""
+// for the default style.
+func glyphSGR(glyph vt10x.Glyph) string {
+	codes := []string{}
+	if glyph.Mode&attrBold != 0 {
+		codes = append(codes, "1")
+	}
+	if glyph.Mode&attrItalic != 0 {
+		codes = append(codes, "3")
+	}
+	if glyph.Mode&attrUnderline != 0 {
+		codes = append(codes, "4")
+	}
+	if glyph.Mode&attrReverse != 0 {
+		codes = append(codes, "7")
+	}
+	if glyph.FG < 256 {
+		codes = append(codes, fmt.Sprintf("38;5;%d", glyph.FG))
+	}
+	if glyph.BG < 256 {
+		codes = append(codes, fmt.Sprintf("48;5;%d", glyph.BG))
+	}
+	if len(codes) == 0 {
+		return ""
+	}
+	return "\x1b[0;" + strings.Join(codes, ";") + "m"
+}
+
+func clampSize(cols, rows int) (int, int) {
+	if cols < 10 {
+		cols = 10
+	}
+	if rows < 2 {
+		rows = 2
+	}
+	return cols, rows
+}
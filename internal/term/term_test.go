@@ -0,0 +1,64 @@
+package term
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionRendersOutput(t *testing.T) {
+	session, err := Start(t.TempDir(), "printf 'hello\\n\\033[31mred\\033[0m'", 20, 4)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	select {
+	case <-session.Done():
+	case <-time.After(5 * time.Second):
+		session.Close()
+		t.Fatalf("command did not exit")
+	}
+	if !session.Exited() || session.Err() != nil {
+		t.Fatalf("expected clean exit, got %v", session.Err())
+	}
+	lines := session.Lines(false)
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 rows, got %d", len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "hello") {
+		t.Fatalf("unexpected first row %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "\x1b[0;38;5;1mred\x1b[0m") {
+		t.Fatalf("expected red text on second row, got %q", lines[1])
+	}
+	if err := session.Resize(30, 6); err != nil {
+		t.Fatalf("resize: %v", err)
+	}
+	if got := session.Lines(false); len(got) != 6 {
+		t.Fatalf("expected 6 rows after resize, got %d", len(got))
+	}
+}
+
+func TestSessionForwardsInput(t *testing.T) {
+	session, err := Start(t.TempDir(), "read line; echo got:$line", 30, 4)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer session.Close()
+	if _, err := session.Write([]byte("ping\r")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	select {
+	case <-session.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("command did not exit")
+	}
+	if screen := strings.Join(session.Lines(false), "\n"); !strings.Contains(screen, "got:ping") {
+		t.Fatalf("expected echoed input, got %q", screen)
+	}
+}
+
+func TestStartRequiresCommand(t *testing.T) {
+	if _, err := Start(t.TempDir(), " ", 20, 4); err == nil {
+		t.Fatalf("expected an error for an empty command")
+	}
+}